@@ -7,6 +7,12 @@ const (
 	// See: https://github.com/distribution/reference/blob/v0.5.0/regexp.go#L91
 	IPv6 = `\[(?:[a-fA-F0-9:]+)\]`
 
+	// IPv6Address matches an IPv6 address without brackets, as used in Subject Alternative Names and API endpoints.
+	IPv6Address = `(?:[a-fA-F0-9]{0,4}:){2,7}[a-fA-F0-9]{0,4}`
+
+	// Address matches a DNS name, an IPv4 address or an IPv6 address without brackets.
+	Address = `(?:` + DNS1123Subdomain + `|` + IPv6Address + `)`
+
 	Port = `:[0-9]+`
 
 	// See https://github.com/distribution/reference/blob/v0.5.0/regexp.go#L65
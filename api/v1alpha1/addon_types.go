@@ -6,6 +6,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
@@ -21,6 +22,24 @@ const (
 	VolumeReclaimRecycle = corev1.PersistentVolumeReclaimRecycle
 	VolumeReclaimDelete  = corev1.PersistentVolumeReclaimDelete
 	VolumeReclaimRetain  = corev1.PersistentVolumeReclaimRetain
+
+	CalicoEncapsulationIPIP             CalicoEncapsulation = "IPIP"
+	CalicoEncapsulationIPIPCrossSubnet  CalicoEncapsulation = "IPIPCrossSubnet"
+	CalicoEncapsulationVXLAN            CalicoEncapsulation = "VXLAN"
+	CalicoEncapsulationVXLANCrossSubnet CalicoEncapsulation = "VXLANCrossSubnet"
+	CalicoEncapsulationNone             CalicoEncapsulation = "None"
+
+	CiliumRoutingModeTunnel CiliumRoutingMode = "tunnel"
+	CiliumRoutingModeNative CiliumRoutingMode = "native"
+
+	CiliumTunnelProtocolVXLAN  CiliumTunnelProtocol = "vxlan"
+	CiliumTunnelProtocolGeneve CiliumTunnelProtocol = "geneve"
+
+	CiliumEncryptionTypeWireGuard CiliumEncryptionType = "wireguard"
+	CiliumEncryptionTypeIPsec     CiliumEncryptionType = "ipsec"
+
+	CustomCNIResourceKindConfigMap CustomCNIResourceKind = "ConfigMap"
+	CustomCNIResourceKindSecret    CustomCNIResourceKind = "Secret"
 )
 
 type Addons struct {
@@ -38,6 +57,9 @@ type Addons struct {
 
 	// +optional
 	CSIProviders *CSI `json:"csi,omitempty"`
+
+	// +optional
+	HelmCharts []HelmChart `json:"helmCharts,omitempty"`
 }
 
 func (Addons) VariableSchema() clusterv1.VariableSchema {
@@ -51,6 +73,11 @@ func (Addons) VariableSchema() clusterv1.VariableSchema {
 				"clusterAutoscaler": ClusterAutoscaler{}.VariableSchema().OpenAPIV3Schema,
 				"csi":               CSI{}.VariableSchema().OpenAPIV3Schema,
 				"ccm":               CCM{}.VariableSchema().OpenAPIV3Schema,
+				"helmCharts": {
+					Description: "Additional Helm charts to install on the workload cluster",
+					Type:        "array",
+					Items:       ptr.To(HelmChart{}.VariableSchema().OpenAPIV3Schema),
+				},
 			},
 		},
 	}
@@ -58,16 +85,65 @@ func (Addons) VariableSchema() clusterv1.VariableSchema {
 
 type AddonStrategy string
 
+// AddonValues are Helm values that are deep-merged over the default values of an addon deployed with the HelmAddon
+// strategy. Exactly one of Inline or ConfigMapRef must be set.
+type AddonValues struct {
+	// Inline Helm values.
+	// +optional
+	Inline *apiextensionsv1.JSON `json:"inline,omitempty"`
+
+	// A reference to a ConfigMap in the cluster namespace with the Helm values in its values.yaml key.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+func (AddonValues) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Helm values deep-merged over the default values of the addon, " +
+				"given either inline or as a reference to a ConfigMap in the cluster namespace",
+			Type: "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"inline": {
+					Description:            "Inline Helm values",
+					Type:                   "object",
+					XPreserveUnknownFields: true,
+				},
+				"configMapRef": {
+					Description: "A reference to a ConfigMap in the cluster namespace " +
+						"with the Helm values in its values.yaml key",
+					Type: "object",
+					Properties: map[string]clusterv1.JSONSchemaProps{
+						"name": {
+							Description: "The name of the ConfigMap",
+							Type:        "string",
+						},
+					},
+					Required: []string{"name"},
+				},
+			},
+		},
+	}
+}
+
 // CNI required for providing CNI configuration.
 type CNI struct {
 	// +optional
 	Provider string `json:"provider,omitempty"`
 	// +optional
 	Strategy AddonStrategy `json:"strategy,omitempty"`
+	// +optional
+	Values *AddonValues `json:"values,omitempty"`
+	// +optional
+	Calico *CalicoNetwork `json:"calico,omitempty"`
+	// +optional
+	Cilium *CiliumConfig `json:"cilium,omitempty"`
+	// +optional
+	Custom *CustomCNI `json:"custom,omitempty"`
 }
 
 func (CNI) VariableSchema() clusterv1.VariableSchema {
-	supportedCNIProviders := []string{CNIProviderCalico, CNIProviderCilium}
+	supportedCNIProviders := []string{CNIProviderCalico, CNIProviderCilium, CNIProviderCustom}
 
 	return clusterv1.VariableSchema{
 		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
@@ -86,16 +162,279 @@ func (CNI) VariableSchema() clusterv1.VariableSchema {
 						AddonStrategyHelmAddon,
 					),
 				},
+				"values": AddonValues{}.VariableSchema().OpenAPIV3Schema,
+				"calico": CalicoNetwork{}.VariableSchema().OpenAPIV3Schema,
+				"cilium": CiliumConfig{}.VariableSchema().OpenAPIV3Schema,
+				"custom": CustomCNI{}.VariableSchema().OpenAPIV3Schema,
 			},
 			Required: []string{"provider", "strategy"},
 		},
 	}
 }
 
+type CalicoEncapsulation string
+
+// CalicoNetwork configures the Calico network. Settings that are not set keep the defaults of the Calico installation.
+type CalicoNetwork struct {
+	// Encapsulation of the traffic between pods on different nodes.
+	// +optional
+	Encapsulation CalicoEncapsulation `json:"encapsulation,omitempty"`
+	// MTU of the pod network interfaces.
+	// +optional
+	MTU *int32 `json:"mtu,omitempty"`
+	// BGP enables routing with BGP.
+	// +optional
+	BGP *bool `json:"bgp,omitempty"`
+	// NATOutgoing enables NAT for the traffic from pods to destinations outside of the pod network.
+	// +optional
+	NATOutgoing *bool `json:"natOutgoing,omitempty"`
+}
+
+func (CalicoNetwork) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Calico network configuration, only valid if the CNI provider is Calico",
+			Type:        "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"encapsulation": {
+					Description: "Encapsulation of the traffic between pods on different nodes",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						CalicoEncapsulationIPIP,
+						CalicoEncapsulationIPIPCrossSubnet,
+						CalicoEncapsulationVXLAN,
+						CalicoEncapsulationVXLANCrossSubnet,
+						CalicoEncapsulationNone,
+					),
+				},
+				"mtu": {
+					Description: "MTU of the pod network interfaces, detected automatically if not set",
+					Type:        "integer",
+					Minimum:     ptr.To[int64](1000),
+					Maximum:     ptr.To[int64](9000),
+				},
+				"bgp": {
+					Description: "Enable routing with BGP",
+					Type:        "boolean",
+				},
+				"natOutgoing": {
+					Description: "Enable NAT for the traffic from pods to destinations outside of the pod network",
+					Type:        "boolean",
+				},
+			},
+		},
+	}
+}
+
+type CiliumRoutingMode string
+
+type CiliumTunnelProtocol string
+
+// CiliumConfig configures Cilium. Settings that are not set keep the defaults of the Cilium installation.
+type CiliumConfig struct {
+	// RoutingMode of the traffic between pods on different nodes, either encapsulated in a tunnel or natively routed.
+	// +optional
+	RoutingMode CiliumRoutingMode `json:"routingMode,omitempty"`
+	// TunnelProtocol encapsulating the traffic between pods on different nodes in the tunnel routing mode.
+	// +optional
+	TunnelProtocol CiliumTunnelProtocol `json:"tunnelProtocol,omitempty"`
+	// KubeProxyReplacement replaces kube-proxy with Cilium, which is then not installed by kubeadm.
+	// +optional
+	KubeProxyReplacement bool `json:"kubeProxyReplacement,omitempty"`
+	// Hubble network observability.
+	// +optional
+	Hubble *CiliumHubble `json:"hubble,omitempty"`
+	// Encryption of the traffic between pods on different nodes.
+	// +optional
+	Encryption *CiliumEncryption `json:"encryption,omitempty"`
+}
+
+func (CiliumConfig) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Cilium configuration, only valid if the CNI provider is Cilium",
+			Type:        "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"routingMode": {
+					Description: "Routing mode of the traffic between pods on different nodes, defaults to tunnel",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						CiliumRoutingModeTunnel,
+						CiliumRoutingModeNative,
+					),
+				},
+				"tunnelProtocol": {
+					Description: "Protocol encapsulating the traffic between pods in the tunnel routing mode, defaults to vxlan",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						CiliumTunnelProtocolVXLAN,
+						CiliumTunnelProtocolGeneve,
+					),
+				},
+				"kubeProxyReplacement": {
+					Description: "Replace kube-proxy with Cilium, skipping the installation of kube-proxy by kubeadm",
+					Type:        "boolean",
+				},
+				"hubble":     CiliumHubble{}.VariableSchema().OpenAPIV3Schema,
+				"encryption": CiliumEncryption{}.VariableSchema().OpenAPIV3Schema,
+			},
+		},
+	}
+}
+
+// CiliumHubble configures Hubble, the network observability layer of Cilium.
+type CiliumHubble struct {
+	// Enabled enables Hubble in the Cilium agents.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Relay deploys Hubble Relay, providing the flows of the whole cluster. Requires Hubble to be enabled.
+	// +optional
+	Relay bool `json:"relay,omitempty"`
+	// UI deploys the Hubble UI. Requires Hubble Relay to be deployed.
+	// +optional
+	UI bool `json:"ui,omitempty"`
+}
+
+func (CiliumHubble) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Hubble network observability configuration",
+			Type:        "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"enabled": {
+					Description: "Enable Hubble in the Cilium agents",
+					Type:        "boolean",
+				},
+				"relay": {
+					Description: "Deploy Hubble Relay, requires Hubble to be enabled",
+					Type:        "boolean",
+				},
+				"ui": {
+					Description: "Deploy the Hubble UI, requires Hubble Relay to be deployed",
+					Type:        "boolean",
+				},
+			},
+		},
+	}
+}
+
+type CiliumEncryptionType string
+
+// CiliumEncryption configures the transparent encryption of the traffic between pods on different nodes.
+type CiliumEncryption struct {
+	// Type of the encryption, either WireGuard or IPsec.
+	Type CiliumEncryptionType `json:"type"`
+	// IPsecKeySecretRef is a reference to the Secret containing the IPsec key under the `keys` key. The Secret is
+	// copied to the workload cluster. Required with the IPsec encryption type.
+	// +optional
+	IPsecKeySecretRef *corev1.LocalObjectReference `json:"ipsecKeySecretRef,omitempty"`
+}
+
+func (CiliumEncryption) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Transparent encryption of the traffic between pods on different nodes",
+			Type:        "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"type": {
+					Description: "Type of the encryption",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						CiliumEncryptionTypeWireGuard,
+						CiliumEncryptionTypeIPsec,
+					),
+				},
+				"ipsecKeySecretRef": {
+					Description: "A reference to the Secret containing the IPsec key under the 'keys' key, " +
+						"required with the ipsec encryption type",
+					Type: "object",
+					Properties: map[string]clusterv1.JSONSchemaProps{
+						"name": {
+							Description: "The name of the Secret containing the IPsec key. This Secret must exist in " +
+								"the same namespace as the Cluster.",
+							Type: "string",
+						},
+					},
+					Required: []string{"name"},
+				},
+			},
+			Required: []string{"type"},
+		},
+	}
+}
+
+// CustomCNI is a CNI provided by the user, installed either by a ClusterResourceSet applying the manifests held by
+// ConfigMaps and Secrets in the cluster namespace, or by a HelmChartProxy installing a Helm chart.
+type CustomCNI struct {
+	// References to the ConfigMaps and Secrets in the cluster namespace holding the manifests of the CNI, applied by a
+	// ClusterResourceSet. Required with the ClusterResourceSet strategy.
+	// +optional
+	Resources []CustomCNIResourceRef `json:"resources,omitempty"`
+
+	// Helm chart of the CNI, installed by a HelmChartProxy. Required with the HelmAddon strategy.
+	// +optional
+	HelmChart *HelmChart `json:"helmChart,omitempty"`
+}
+
+func (CustomCNI) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Custom CNI configuration, only valid if the CNI provider is Custom",
+			Type:        "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"resources": {
+					Description: "References to the ConfigMaps and Secrets in the cluster namespace holding the " +
+						"manifests of the CNI, required with the ClusterResourceSet strategy",
+					Type:  "array",
+					Items: ptr.To(CustomCNIResourceRef{}.VariableSchema().OpenAPIV3Schema),
+				},
+				"helmChart": HelmChart{}.VariableSchema().OpenAPIV3Schema,
+			},
+		},
+	}
+}
+
+type CustomCNIResourceKind string
+
+// CustomCNIResourceRef is a reference to a ConfigMap or a Secret in the cluster namespace holding manifests of a custom
+// CNI.
+type CustomCNIResourceRef struct {
+	// Kind of the resource, either ConfigMap or Secret.
+	Kind CustomCNIResourceKind `json:"kind"`
+	// Name of the resource.
+	Name string `json:"name"`
+}
+
+func (CustomCNIResourceRef) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"kind": {
+					Description: "Kind of the resource",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						CustomCNIResourceKindConfigMap,
+						CustomCNIResourceKindSecret,
+					),
+				},
+				"name": {
+					Description: "Name of the resource, which must exist in the same namespace as the Cluster",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+				},
+			},
+			Required: []string{"kind", "name"},
+		},
+	}
+}
+
 // NFD tells us to enable or disable the node feature discovery addon.
 type NFD struct {
 	// +optional
 	Strategy AddonStrategy `json:"strategy,omitempty"`
+	// +optional
+	Values *AddonValues `json:"values,omitempty"`
 }
 
 func (NFD) VariableSchema() clusterv1.VariableSchema {
@@ -111,6 +450,7 @@ func (NFD) VariableSchema() clusterv1.VariableSchema {
 						AddonStrategyHelmAddon,
 					),
 				},
+				"values": AddonValues{}.VariableSchema().OpenAPIV3Schema,
 			},
 			Required: []string{"strategy"},
 		},
@@ -121,6 +461,8 @@ func (NFD) VariableSchema() clusterv1.VariableSchema {
 type ClusterAutoscaler struct {
 	// +optional
 	Strategy AddonStrategy `json:"strategy,omitempty"`
+	// +optional
+	Values *AddonValues `json:"values,omitempty"`
 }
 
 func (ClusterAutoscaler) VariableSchema() clusterv1.VariableSchema {
@@ -137,6 +479,7 @@ func (ClusterAutoscaler) VariableSchema() clusterv1.VariableSchema {
 						AddonStrategyHelmAddon,
 					),
 				},
+				"values": AddonValues{}.VariableSchema().OpenAPIV3Schema,
 			},
 			Required: []string{"strategy"},
 		},
@@ -165,6 +508,9 @@ type CSIProvider struct {
 
 	// +optional
 	Credentials *corev1.LocalObjectReference `json:"credentials,omitempty"`
+
+	// +optional
+	Values *AddonValues `json:"values,omitempty"`
 }
 
 type StorageClassConfig struct {
@@ -263,6 +609,7 @@ func (CSIProvider) VariableSchema() clusterv1.VariableSchema {
 					Type:  "array",
 					Items: ptr.To(StorageClassConfig{}.VariableSchema().OpenAPIV3Schema),
 				},
+				"values": AddonValues{}.VariableSchema().OpenAPIV3Schema,
 			},
 		},
 	}
@@ -312,6 +659,14 @@ type CCM struct {
 	// A reference to the Secret for credential information for the target Prism Central instance
 	// +optional
 	Credentials *corev1.LocalObjectReference `json:"credentials"`
+
+	// Addon strategy used to deploy the CCM. The AWS CCM defaults to ClusterResourceSet, the Nutanix CCM only supports
+	// HelmAddon.
+	// +optional
+	Strategy AddonStrategy `json:"strategy,omitempty"`
+
+	// +optional
+	Values *AddonValues `json:"values,omitempty"`
 }
 
 func (CCM) VariableSchema() clusterv1.VariableSchema {
@@ -335,6 +690,115 @@ func (CCM) VariableSchema() clusterv1.VariableSchema {
 					},
 					Required: []string{"name"},
 				},
+				"strategy": {
+					Description: "Addon strategy used to deploy the CCM to the workload cluster",
+					Type:        "string",
+					Enum: variables.MustMarshalValuesToEnumJSON(
+						AddonStrategyClusterResourceSet,
+						AddonStrategyHelmAddon,
+					),
+				},
+				"values": AddonValues{}.VariableSchema().OpenAPIV3Schema,
+			},
+		},
+	}
+}
+
+// HelmChart is an additional Helm chart installed on the cluster with a HelmChartProxy.
+type HelmChart struct {
+	// Name of the Helm release, unique among the Helm charts of the cluster.
+	Name string `json:"name"`
+
+	// URL of the Helm chart repository.
+	RepoURL string `json:"repoURL"`
+
+	// Name of the Helm chart in the repository.
+	ChartName string `json:"chartName"`
+
+	// Version of the Helm chart.
+	Version string `json:"version"`
+
+	// Namespace the Helm release is installed in.
+	ReleaseNamespace string `json:"releaseNamespace"`
+
+	// +optional
+	ValuesTemplate *HelmChartValuesTemplate `json:"valuesTemplate,omitempty"`
+}
+
+func (HelmChart) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Type:     "object",
+			Required: []string{"name", "repoURL", "chartName", "version", "releaseNamespace"},
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"name": {
+					Description: "Name of the Helm release, unique among the Helm charts of the cluster",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+					MaxLength:   ptr.To[int64](53),
+					Pattern:     "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$",
+				},
+				"repoURL": {
+					Description: "URL of the Helm chart repository",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+				},
+				"chartName": {
+					Description: "Name of the Helm chart in the repository",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+				},
+				"version": {
+					Description: "Version of the Helm chart",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+				},
+				"releaseNamespace": {
+					Description: "Namespace the Helm release is installed in",
+					Type:        "string",
+					MinLength:   ptr.To[int64](1),
+				},
+				"valuesTemplate": HelmChartValuesTemplate{}.VariableSchema().OpenAPIV3Schema,
+			},
+		},
+	}
+}
+
+// HelmChartValuesTemplate is a Go template of the Helm values of a Helm chart, rendered for the cluster by the Cluster
+// API Addon Provider for Helm. Exactly one of Inline or ConfigMapRef must be set.
+type HelmChartValuesTemplate struct {
+	// Inline values template.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// A reference to a ConfigMap in the cluster namespace with the values template in its values.yaml key.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+func (HelmChartValuesTemplate) VariableSchema() clusterv1.VariableSchema {
+	return clusterv1.VariableSchema{
+		OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+			Description: "Go template of the Helm values, rendered for the cluster, " +
+				"given either inline or as a reference to a ConfigMap in the cluster namespace",
+			Type: "object",
+			Properties: map[string]clusterv1.JSONSchemaProps{
+				"inline": {
+					Description: "Inline values template",
+					Type:        "string",
+				},
+				"configMapRef": {
+					Description: "A reference to a ConfigMap in the cluster namespace " +
+						"with the values template in its values.yaml key",
+					Type: "object",
+					Properties: map[string]clusterv1.JSONSchemaProps{
+						"name": {
+							Description: "The name of the ConfigMap",
+							Type:        "string",
+						},
+					},
+					Required: []string{"name"},
+				},
 			},
 		},
 	}
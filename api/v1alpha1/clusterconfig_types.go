@@ -19,6 +19,7 @@ type StorageProvisioner string
 const (
 	CNIProviderCalico                     = "Calico"
 	CNIProviderCilium                     = "Cilium"
+	CNIProviderCustom                     = "Custom"
 	AWSEBSProvisioner  StorageProvisioner = "ebs.csi.aws.com"
 	NutanixProvisioner StorageProvisioner = "csi.nutanix.com"
 
@@ -261,7 +262,7 @@ func (ExtraAPIServerCertSANs) VariableSchema() clusterv1.VariableSchema {
 			UniqueItems: true,
 			Items: &clusterv1.JSONSchemaProps{
 				Type:    "string",
-				Pattern: patterns.Anchored(patterns.DNS1123Subdomain),
+				Pattern: patterns.Anchored(patterns.Address),
 			},
 		},
 	}
@@ -307,6 +308,16 @@ type GlobalImageRegistryMirror struct {
 	// Credentials and CA certificate for the image registry mirror
 	// +optional
 	Credentials *RegistryCredentials `json:"credentials,omitempty"`
+
+	// RewriteAddonReferences rewrites the image references of the addons to the mirror, together with the
+	// repositories of the addon Helm charts, for environments without access to the public registries.
+	// +optional
+	RewriteAddonReferences bool `json:"rewriteAddonReferences,omitempty"`
+
+	// AddonChartRepository is the OCI repository the addon Helm charts are pulled from when rewriting addon
+	// references. Defaults to the mirror URL with the oci scheme.
+	// +optional
+	AddonChartRepository string `json:"addonChartRepository,omitempty"`
 }
 
 func (GlobalImageRegistryMirror) VariableSchema() clusterv1.VariableSchema {
@@ -321,6 +332,17 @@ func (GlobalImageRegistryMirror) VariableSchema() clusterv1.VariableSchema {
 					Pattern:     "^https?://",
 				},
 				"credentials": RegistryCredentials{}.VariableSchema().OpenAPIV3Schema,
+				"rewriteAddonReferences": {
+					Description: "Rewrite the image and Helm chart references of the addons to the mirror.",
+					Type:        "boolean",
+				},
+				"addonChartRepository": {
+					Description: "OCI repository the addon Helm charts are pulled from when rewriting addon " +
+						"references. Defaults to the mirror URL with the oci scheme.",
+					Type:    "string",
+					Format:  "uri",
+					Pattern: "^oci://",
+				},
 			},
 			Required: []string{"url"},
 		},
@@ -7,6 +7,7 @@ import (
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/openapi/patterns"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/variables"
 )
 
@@ -47,6 +48,7 @@ func (ControlPlaneEndpointSpec) VariableSchema() clusterv1.VariableSchema {
 					Description: "host ip/fqdn for control plane API Server",
 					Type:        "string",
 					MinLength:   ptr.To[int64](1),
+					Pattern:     patterns.Anchored(patterns.Address),
 				},
 				"port": {
 					Description: "port for control plane API Server",
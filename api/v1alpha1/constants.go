@@ -10,6 +10,8 @@ const (
 	NFDVariableName = "nfd"
 	// ClusterAutoscalerVariableName is the cluster-autoscaler external patch variable name.
 	ClusterAutoscalerVariableName = "clusterAutoscaler"
+	// HelmChartsVariableName is the additional Helm charts external patch variable name.
+	HelmChartsVariableName = "helmCharts"
 	// AWSVariableName is the AWS config patch variable name.
 	AWSVariableName = "aws"
 	// NutanixVariableName is the Nutanix config patch variable name.
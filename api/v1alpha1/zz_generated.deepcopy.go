@@ -10,6 +10,7 @@ package v1alpha1
 import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
 	"k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -181,23 +182,48 @@ func (in AdditionalSecurityGroup) DeepCopy() AdditionalSecurityGroup {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonValues) DeepCopyInto(out *AddonValues) {
+	*out = *in
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonValues.
+func (in *AddonValues) DeepCopy() *AddonValues {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Addons) DeepCopyInto(out *Addons) {
 	*out = *in
 	if in.CNI != nil {
 		in, out := &in.CNI, &out.CNI
 		*out = new(CNI)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.NFD != nil {
 		in, out := &in.NFD, &out.NFD
 		*out = new(NFD)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ClusterAutoscaler != nil {
 		in, out := &in.ClusterAutoscaler, &out.ClusterAutoscaler
 		*out = new(ClusterAutoscaler)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.CCM != nil {
 		in, out := &in.CCM, &out.CCM
@@ -209,6 +235,13 @@ func (in *Addons) DeepCopyInto(out *Addons) {
 		*out = new(CSI)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HelmCharts != nil {
+		in, out := &in.HelmCharts, &out.HelmCharts
+		*out = make([]HelmChart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Addons.
@@ -229,6 +262,11 @@ func (in *CCM) DeepCopyInto(out *CCM) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(AddonValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CCM.
@@ -244,6 +282,26 @@ func (in *CCM) DeepCopy() *CCM {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CNI) DeepCopyInto(out *CNI) {
 	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(AddonValues)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Calico != nil {
+		in, out := &in.Calico, &out.Calico
+		*out = new(CalicoNetwork)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cilium != nil {
+		in, out := &in.Cilium, &out.Cilium
+		*out = new(CiliumConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Custom != nil {
+		in, out := &in.Custom, &out.Custom
+		*out = new(CustomCNI)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNI.
@@ -298,6 +356,11 @@ func (in *CSIProvider) DeepCopyInto(out *CSIProvider) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(AddonValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSIProvider.
@@ -310,9 +373,104 @@ func (in *CSIProvider) DeepCopy() *CSIProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalicoNetwork) DeepCopyInto(out *CalicoNetwork) {
+	*out = *in
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BGP != nil {
+		in, out := &in.BGP, &out.BGP
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NATOutgoing != nil {
+		in, out := &in.NATOutgoing, &out.NATOutgoing
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalicoNetwork.
+func (in *CalicoNetwork) DeepCopy() *CalicoNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(CalicoNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumConfig) DeepCopyInto(out *CiliumConfig) {
+	*out = *in
+	if in.Hubble != nil {
+		in, out := &in.Hubble, &out.Hubble
+		*out = new(CiliumHubble)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(CiliumEncryption)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumConfig.
+func (in *CiliumConfig) DeepCopy() *CiliumConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumEncryption) DeepCopyInto(out *CiliumEncryption) {
+	*out = *in
+	if in.IPsecKeySecretRef != nil {
+		in, out := &in.IPsecKeySecretRef, &out.IPsecKeySecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumEncryption.
+func (in *CiliumEncryption) DeepCopy() *CiliumEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumHubble) DeepCopyInto(out *CiliumHubble) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumHubble.
+func (in *CiliumHubble) DeepCopy() *CiliumHubble {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumHubble)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterAutoscaler) DeepCopyInto(out *ClusterAutoscaler) {
 	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(AddonValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterAutoscaler.
@@ -402,6 +560,46 @@ func (in *ControlPlaneEndpointSpec) DeepCopy() *ControlPlaneEndpointSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomCNI) DeepCopyInto(out *CustomCNI) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]CustomCNIResourceRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.HelmChart != nil {
+		in, out := &in.HelmChart, &out.HelmChart
+		*out = new(HelmChart)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomCNI.
+func (in *CustomCNI) DeepCopy() *CustomCNI {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomCNI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomCNIResourceRef) DeepCopyInto(out *CustomCNIResourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomCNIResourceRef.
+func (in *CustomCNIResourceRef) DeepCopy() *CustomCNIResourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomCNIResourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DefaultStorage) DeepCopyInto(out *DefaultStorage) {
 	*out = *in
@@ -605,6 +803,46 @@ func (in *HTTPProxy) DeepCopy() *HTTPProxy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChart) DeepCopyInto(out *HelmChart) {
+	*out = *in
+	if in.ValuesTemplate != nil {
+		in, out := &in.ValuesTemplate, &out.ValuesTemplate
+		*out = new(HelmChartValuesTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChart.
+func (in *HelmChart) DeepCopy() *HelmChart {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmChartValuesTemplate) DeepCopyInto(out *HelmChartValuesTemplate) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmChartValuesTemplate.
+func (in *HelmChartValuesTemplate) DeepCopy() *HelmChartValuesTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmChartValuesTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
@@ -664,6 +902,11 @@ func (in *ImageRegistry) DeepCopy() *ImageRegistry {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NFD) DeepCopyInto(out *NFD) {
 	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = new(AddonValues)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFD.
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -27,6 +28,9 @@ import (
 	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/server"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/controllers/addonremoval"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/controllers/addonstatus"
 	awsclusterconfig "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/aws/clusterconfig"
 	awsmutation "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/aws/mutation"
 	awsworkerconfig "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/aws/workerconfig"
@@ -34,6 +38,8 @@ import (
 	dockermutation "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/docker/mutation"
 	dockerworkerconfig "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/docker/workerconfig"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/validation"
 	nutanixclusterconfig "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/nutanix/clusterconfig"
 	nutanixmutation "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/nutanix/mutation"
 	nutanixworkerconfig "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/nutanix/workerconfig"
@@ -80,6 +86,8 @@ func main() {
 
 	runtimeWebhookServerOpts := server.NewServerOptions()
 
+	tracingOpts := tracing.NewOptions()
+
 	globalOptions := options.NewGlobalOptions()
 
 	genericLifecycleHandlers := lifecycle.New(globalOptions)
@@ -89,6 +97,7 @@ func main() {
 	logsv1.AddFlags(logOptions, pflag.CommandLine)
 	globalOptions.AddFlags(pflag.CommandLine)
 	runtimeWebhookServerOpts.AddFlags(pflag.CommandLine)
+	tracingOpts.AddFlags(pflag.CommandLine)
 	genericLifecycleHandlers.AddFlags(pflag.CommandLine)
 	pflag.CommandLine.SetNormalizeFunc(cliflag.WordSepNormalizeFunc)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
@@ -107,6 +116,12 @@ func main() {
 
 	signalCtx := ctrl.SetupSignalHandler()
 
+	shutdownTracing, err := tracingOpts.Setup(signalCtx)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	mgr, err := newManager(mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "failed to create a new controller manager")
@@ -141,14 +156,32 @@ func main() {
 		nutanixmutation.MetaWorkerPatchHandler(),
 	}
 
+	lifecycleHandlers, err := genericLifecycleHandlers.AllHandlers(mgr)
+	if err != nil {
+		setupLog.Error(err, "failed to create lifecycle handlers")
+		os.Exit(1)
+	}
+
 	var allHandlers []handlers.Named
-	allHandlers = append(allHandlers, genericLifecycleHandlers.AllHandlers(mgr)...)
+	allHandlers = append(allHandlers, lifecycleHandlers...)
 	allHandlers = append(allHandlers, awsMetaHandlers...)
 	allHandlers = append(allHandlers, dockerMetaHandlers...)
 	allHandlers = append(allHandlers, nutanixMetaHandlers...)
+	allHandlers = append(allHandlers, validation.New())
 
 	runtimeWebhookServer := server.NewServer(runtimeWebhookServerOpts, allHandlers...)
 
+	if err := addonstatus.New(mgr.GetClient()).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create addon status controller")
+		os.Exit(1)
+	}
+
+	addonRemovalReconciler := addonremoval.New(mgr.GetClient(), mgr.GetEventRecorderFor(events.RecorderName))
+	if err := addonRemovalReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create addon removal controller")
+		os.Exit(1)
+	}
+
 	if err := mgr.Add(runtimeWebhookServer); err != nil {
 		setupLog.Error(err, "unable to add runtime webhook server runnable to controller manager")
 		os.Exit(1)
@@ -158,6 +191,11 @@ func main() {
 		setupLog.Error(err, "unable to start controller manager")
 		os.Exit(1)
 	}
+
+	// Flush any remaining spans. The signal context is already done at this point.
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to shut down tracing")
+	}
 }
 
 func newManager(opts *manager.Options) (ctrl.Manager, error) {
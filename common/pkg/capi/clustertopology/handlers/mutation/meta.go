@@ -5,7 +5,10 @@ package mutation
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"go.opentelemetry.io/otel/attribute"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -14,6 +17,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/metrics"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
 )
 
 type MutateFunc func(
@@ -72,7 +77,18 @@ func (mgp metaGeneratePatches) GeneratePatches(
 			holderRef runtimehooksv1.HolderReference,
 		) error {
 			for _, h := range mgp.mutators {
-				if err := h.Mutate(ctx, obj.(*unstructured.Unstructured), vars, holderRef, clusterKey); err != nil {
+				name := mutatorName(h)
+				mutateCtx, span := tracing.Start(
+					ctx,
+					fmt.Sprintf("Mutate %s", name),
+					clusterKey,
+					attribute.String("handler", mgp.name),
+					attribute.String("holderRef", holderRef.Kind+"/"+holderRef.Name),
+				)
+				err := h.Mutate(mutateCtx, obj.(*unstructured.Unstructured), vars, holderRef, clusterKey)
+				tracing.End(span, err)
+				metrics.ObserveMutator(mgp.name, name, err)
+				if err != nil {
 					return err
 				}
 			}
@@ -81,3 +97,12 @@ func (mgp metaGeneratePatches) GeneratePatches(
 		},
 	)
 }
+
+// mutatorName returns the name used to label the mutator metrics, using the name of mutators implementing
+// handlers.Named and the type name of all other mutators.
+func mutatorName(m MetaMutator) string {
+	if named, ok := m.(handlers.Named); ok {
+		return named.Name()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", m), "*")
+}
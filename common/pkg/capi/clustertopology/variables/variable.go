@@ -9,6 +9,7 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	"sigs.k8s.io/cluster-api/exp/runtime/topologymutation"
 )
 
@@ -64,3 +65,18 @@ func ClusterVariablesToVariablesMap(
 	}
 	return variablesMap
 }
+
+// HookVariablesToVariablesMap converts lists of runtime hook Variables to a map of JSON (name is the map key).
+// Variables in later lists take precedence over variables with the same name in earlier lists, which allows
+// merging global request variables with template specific variables, e.g. MachineDeployment overrides.
+func HookVariablesToVariablesMap(
+	variables ...[]runtimehooksv1.Variable,
+) map[string]apiextensionsv1.JSON {
+	variablesMap := map[string]apiextensionsv1.JSON{}
+	for _, vars := range variables {
+		for i := range vars {
+			variablesMap[vars[i].Name] = vars[i].Value
+		}
+	}
+	return variablesMap
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/onsi/gomega"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 )
 
 func TestGet(t *testing.T) {
@@ -156,3 +157,54 @@ func TestClusterVariablesToVariablesMap(t *testing.T) {
 		})
 	}
 }
+
+func TestHookVariablesToVariablesMap(t *testing.T) {
+	t.Parallel()
+
+	g := gomega.NewWithT(t)
+
+	testCases := []struct {
+		name        string
+		variables   [][]runtimehooksv1.Variable
+		expectedMap map[string]apiextensionsv1.JSON
+	}{{
+		name:        "No variables",
+		variables:   nil,
+		expectedMap: map[string]apiextensionsv1.JSON{},
+	}, {
+		name: "Single list",
+		variables: [][]runtimehooksv1.Variable{{{
+			Name:  "variable1",
+			Value: apiextensionsv1.JSON{Raw: []byte(`{"key": "value1"}`)},
+		}}},
+		expectedMap: map[string]apiextensionsv1.JSON{
+			"variable1": {Raw: []byte(`{"key": "value1"}`)},
+		},
+	}, {
+		name: "Later lists take precedence",
+		variables: [][]runtimehooksv1.Variable{{{
+			Name:  "variable1",
+			Value: apiextensionsv1.JSON{Raw: []byte(`{"key1": "value1"}`)},
+		}, {
+			Name:  "variable2",
+			Value: apiextensionsv1.JSON{Raw: []byte(`{"key2": "value2"}`)},
+		}}, {{
+			Name:  "variable2",
+			Value: apiextensionsv1.JSON{Raw: []byte(`{"key2": "override"}`)},
+		}}},
+		expectedMap: map[string]apiextensionsv1.JSON{
+			"variable1": {Raw: []byte(`{"key1": "value1"}`)},
+			"variable2": {Raw: []byte(`{"key2": "override"}`)},
+		},
+	}}
+
+	for i := range testCases {
+		tt := testCases[i]
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g.Expect(HookVariablesToVariablesMap(tt.variables...)).To(gomega.Equal(tt.expectedMap))
+		})
+	}
+}
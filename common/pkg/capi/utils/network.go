@@ -0,0 +1,72 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+
+	netutils "k8s.io/utils/net"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// ClusterIPFamilies returns the IP families of the cluster, with the primary IP family first. The IP families are
+// those of the Pods network CIDRs of the cluster, falling back to the Services network CIDRs if the Pods network is not
+// set. A cluster without any network CIDRs is IPv4 only.
+func ClusterIPFamilies(cluster *clusterv1.Cluster) ([]netutils.IPFamily, error) {
+	var cidrs []string
+	if cluster.Spec.ClusterNetwork != nil {
+		switch {
+		case cluster.Spec.ClusterNetwork.Pods != nil && len(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks) > 0:
+			cidrs = cluster.Spec.ClusterNetwork.Pods.CIDRBlocks
+		case cluster.Spec.ClusterNetwork.Services != nil:
+			cidrs = cluster.Spec.ClusterNetwork.Services.CIDRBlocks
+		}
+	}
+
+	switch len(cidrs) {
+	case 0:
+		return []netutils.IPFamily{netutils.IPv4}, nil
+	case 1, 2:
+	default:
+		return nil, fmt.Errorf("too many CIDRs specified: %v", cidrs)
+	}
+
+	families := make([]netutils.IPFamily, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		family := netutils.IPFamilyOfCIDRString(cidr)
+		if family == netutils.IPFamilyUnknown {
+			return nil, fmt.Errorf("could not parse CIDR %q", cidr)
+		}
+		families = append(families, family)
+	}
+	if len(families) == 2 && families[0] == families[1] {
+		return nil, fmt.Errorf("CIDRs must be of different IP families in a dual-stack cluster: %v", cidrs)
+	}
+
+	return families, nil
+}
+
+// IsIPv6Primary returns true if the primary IP family of the cluster is IPv6, which is the case of IPv6 only clusters
+// and of dual-stack clusters with the IPv6 CIDR first.
+func IsIPv6Primary(cluster *clusterv1.Cluster) (bool, error) {
+	families, err := ClusterIPFamilies(cluster)
+	if err != nil {
+		return false, err
+	}
+	return families[0] == netutils.IPv6, nil
+}
+
+// HasIPv6 returns true if the cluster is IPv6 only or dual-stack.
+func HasIPv6(cluster *clusterv1.Cluster) (bool, error) {
+	families, err := ClusterIPFamilies(cluster)
+	if err != nil {
+		return false, err
+	}
+	for _, family := range families {
+		if family == netutils.IPv6 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
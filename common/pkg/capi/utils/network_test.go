@@ -0,0 +1,108 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	netutils "k8s.io/utils/net"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func testCluster(podCIDRs, serviceCIDRs []string) *clusterv1.Cluster {
+	cluster := &clusterv1.Cluster{}
+	if podCIDRs != nil || serviceCIDRs != nil {
+		cluster.Spec.ClusterNetwork = &clusterv1.ClusterNetwork{}
+	}
+	if podCIDRs != nil {
+		cluster.Spec.ClusterNetwork.Pods = &clusterv1.NetworkRanges{CIDRBlocks: podCIDRs}
+	}
+	if serviceCIDRs != nil {
+		cluster.Spec.ClusterNetwork.Services = &clusterv1.NetworkRanges{CIDRBlocks: serviceCIDRs}
+	}
+	return cluster
+}
+
+func TestClusterIPFamilies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		cluster          *clusterv1.Cluster
+		expectedFamilies []netutils.IPFamily
+		expectedErr      string
+	}{{
+		name:             "no cluster network",
+		cluster:          testCluster(nil, nil),
+		expectedFamilies: []netutils.IPFamily{netutils.IPv4},
+	}, {
+		name:             "IPv4",
+		cluster:          testCluster([]string{"192.168.0.0/16"}, []string{"10.128.0.0/12"}),
+		expectedFamilies: []netutils.IPFamily{netutils.IPv4},
+	}, {
+		name:             "IPv6",
+		cluster:          testCluster([]string{"fd00:100:96::/48"}, []string{"fd00:100:64::/108"}),
+		expectedFamilies: []netutils.IPFamily{netutils.IPv6},
+	}, {
+		name: "dual-stack with IPv6 first",
+		cluster: testCluster(
+			[]string{"fd00:100:96::/48", "192.168.0.0/16"},
+			[]string{"fd00:100:64::/108", "10.128.0.0/12"},
+		),
+		expectedFamilies: []netutils.IPFamily{netutils.IPv6, netutils.IPv4},
+	}, {
+		name:             "IPv6 Services network only",
+		cluster:          testCluster(nil, []string{"fd00:100:64::/108"}),
+		expectedFamilies: []netutils.IPFamily{netutils.IPv6},
+	}, {
+		name:        "invalid CIDR",
+		cluster:     testCluster([]string{"192.168.0.0"}, nil),
+		expectedErr: `could not parse CIDR "192.168.0.0"`,
+	}, {
+		name:        "two IPv4 CIDRs",
+		cluster:     testCluster([]string{"192.168.0.0/16", "10.0.0.0/16"}, nil),
+		expectedErr: "CIDRs must be of different IP families in a dual-stack cluster: [192.168.0.0/16 10.0.0.0/16]",
+	}, {
+		name:        "too many CIDRs",
+		cluster:     testCluster([]string{"192.168.0.0/16", "fd00:100:96::/48", "10.0.0.0/16"}, nil),
+		expectedErr: "too many CIDRs specified: [192.168.0.0/16 fd00:100:96::/48 10.0.0.0/16]",
+	}}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			families, err := ClusterIPFamilies(tt.cluster)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFamilies, families)
+		})
+	}
+}
+
+func TestIsIPv6PrimaryAndHasIPv6(t *testing.T) {
+	t.Parallel()
+
+	dualStackIPv4First := testCluster([]string{"192.168.0.0/16", "fd00:100:96::/48"}, nil)
+	ipv6Primary, err := IsIPv6Primary(dualStackIPv4First)
+	assert.NoError(t, err)
+	assert.False(t, ipv6Primary)
+	hasIPv6, err := HasIPv6(dualStackIPv4First)
+	assert.NoError(t, err)
+	assert.True(t, hasIPv6)
+
+	ipv6 := testCluster([]string{"fd00:100:96::/48"}, nil)
+	ipv6Primary, err = IsIPv6Primary(ipv6)
+	assert.NoError(t, err)
+	assert.True(t, ipv6Primary)
+
+	hasIPv6, err = HasIPv6(testCluster([]string{"192.168.0.0/16"}, nil))
+	assert.NoError(t, err)
+	assert.False(t, hasIPv6)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics provides Prometheus metrics for runtime hook invocations. The metrics are registered with the
+// controller-runtime metrics registry and are therefore exported on the manager metrics endpoint.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "capiext"
+	subsystem = "runtime_hook"
+
+	hookLabel    = "hook"
+	handlerLabel = "handler"
+	mutatorLabel = "mutator"
+)
+
+var (
+	// HookRequests counts the calls of every runtime hook handler.
+	HookRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Total number of runtime hook handler calls.",
+		},
+		[]string{hookLabel, handlerLabel},
+	)
+
+	// HookFailures counts the calls of every runtime hook handler that returned a failure response.
+	HookFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "failures_total",
+			Help:      "Total number of runtime hook handler calls that returned a failure response.",
+		},
+		[]string{hookLabel, handlerLabel},
+	)
+
+	// HookDuration observes the latency of every runtime hook handler.
+	HookDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "duration_seconds",
+			Help:      "Latency of runtime hook handler calls in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{hookLabel, handlerLabel},
+	)
+
+	// MutatorRequests counts the calls of every mutator of a GeneratePatches handler.
+	MutatorRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "mutator_requests_total",
+			Help:      "Total number of GeneratePatches mutator calls.",
+		},
+		[]string{handlerLabel, mutatorLabel},
+	)
+
+	// MutatorFailures counts the calls of every mutator of a GeneratePatches handler that returned an error.
+	MutatorFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "mutator_failures_total",
+			Help:      "Total number of GeneratePatches mutator calls that returned an error.",
+		},
+		[]string{handlerLabel, mutatorLabel},
+	)
+)
+
+func init() { //nolint:gochecknoinits // Idiomatically used to register metrics.
+	ctrlmetrics.Registry.MustRegister(
+		HookRequests,
+		HookFailures,
+		HookDuration,
+		MutatorRequests,
+		MutatorFailures,
+	)
+}
+
+// ObserveHook records a single call of a runtime hook handler.
+func ObserveHook(hook, handler string, status runtimehooksv1.ResponseStatus, duration time.Duration) {
+	HookRequests.WithLabelValues(hook, handler).Inc()
+	HookDuration.WithLabelValues(hook, handler).Observe(duration.Seconds())
+	if status == runtimehooksv1.ResponseStatusFailure {
+		HookFailures.WithLabelValues(hook, handler).Inc()
+	}
+}
+
+// ObserveMutator records a single call of a GeneratePatches mutator.
+func ObserveMutator(handler, mutator string, err error) {
+	MutatorRequests.WithLabelValues(handler, mutator).Inc()
+	if err != nil {
+		MutatorFailures.WithLabelValues(handler, mutator).Inc()
+	}
+}
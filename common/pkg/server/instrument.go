@@ -0,0 +1,68 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	runtimecatalog "sigs.k8s.io/cluster-api/exp/runtime/catalog"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/metrics"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+)
+
+// instrument wraps a runtime hook handler function to create a span per request and to record call counts, latency
+// and failures, labeled by hook type and handler name.
+func instrument[Req any, Resp runtimehooksv1.ResponseObject](
+	hook runtimecatalog.Hook,
+	handlerName string,
+	handlerFunc func(context.Context, Req, Resp),
+) func(context.Context, Req, Resp) {
+	hookName := runtimecatalog.HookName(hook)
+	return func(ctx context.Context, req Req, resp Resp) {
+		ctx, span := tracing.Start(
+			ctx,
+			fmt.Sprintf("%s/%s", hookName, handlerName),
+			clusterKeyFromRequest(req),
+			attribute.String("hook", hookName),
+			attribute.String("handler", handlerName),
+		)
+		start := time.Now()
+		handlerFunc(ctx, req, resp)
+		metrics.ObserveHook(hookName, handlerName, resp.GetStatus(), time.Since(start))
+
+		var err error
+		if resp.GetStatus() == runtimehooksv1.ResponseStatusFailure {
+			err = fmt.Errorf("%s", resp.GetMessage())
+		}
+		tracing.End(span, err)
+	}
+}
+
+// clusterKeyFromRequest returns the key of the Cluster the request is for, or an empty key if the request is not for
+// a single Cluster.
+func clusterKeyFromRequest(req any) ctrlclient.ObjectKey {
+	switch r := req.(type) {
+	case *runtimehooksv1.BeforeClusterCreateRequest:
+		return ctrlclient.ObjectKeyFromObject(&r.Cluster)
+	case *runtimehooksv1.AfterControlPlaneInitializedRequest:
+		return ctrlclient.ObjectKeyFromObject(&r.Cluster)
+	case *runtimehooksv1.BeforeClusterUpgradeRequest:
+		return ctrlclient.ObjectKeyFromObject(&r.Cluster)
+	case *runtimehooksv1.AfterControlPlaneUpgradeRequest:
+		return ctrlclient.ObjectKeyFromObject(&r.Cluster)
+	case *runtimehooksv1.BeforeClusterDeleteRequest:
+		return ctrlclient.ObjectKeyFromObject(&r.Cluster)
+	case *runtimehooksv1.GeneratePatchesRequest:
+		return handlers.ClusterKeyFromReq(r)
+	default:
+		return ctrlclient.ObjectKey{}
+	}
+}
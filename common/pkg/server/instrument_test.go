@@ -0,0 +1,52 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	t.Parallel()
+
+	handlerFunc := func(
+		_ context.Context,
+		req *runtimehooksv1.BeforeClusterDeleteRequest,
+		resp *runtimehooksv1.BeforeClusterDeleteResponse,
+	) {
+		if req.Cluster.Name == "fail" {
+			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+			return
+		}
+		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	}
+
+	instrumented := instrument(runtimehooksv1.BeforeClusterDelete, "TestInstrument", handlerFunc)
+
+	for _, name := range []string{"succeed", "succeed", "fail"} {
+		req := &runtimehooksv1.BeforeClusterDeleteRequest{}
+		req.Cluster.Name = name
+		instrumented(context.Background(), req, &runtimehooksv1.BeforeClusterDeleteResponse{})
+	}
+
+	assert.InDelta(
+		t,
+		3,
+		testutil.ToFloat64(metrics.HookRequests.WithLabelValues("BeforeClusterDelete", "TestInstrument")),
+		0,
+	)
+	assert.InDelta(
+		t,
+		1,
+		testutil.ToFloat64(metrics.HookFailures.WithLabelValues("BeforeClusterDelete", "TestInstrument")),
+		0,
+	)
+}
@@ -86,7 +86,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.BeforeClusterCreate,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.BeforeClusterCreate,
+				HandlerFunc: instrument(runtimehooksv1.BeforeClusterCreate, h.Name(), t.BeforeClusterCreate),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -97,7 +97,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.AfterControlPlaneInitialized,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.AfterControlPlaneInitialized,
+				HandlerFunc: instrument(runtimehooksv1.AfterControlPlaneInitialized, h.Name(), t.AfterControlPlaneInitialized),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -108,7 +108,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.BeforeClusterUpgrade,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.BeforeClusterUpgrade,
+				HandlerFunc: instrument(runtimehooksv1.BeforeClusterUpgrade, h.Name(), t.BeforeClusterUpgrade),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -118,8 +118,8 @@ func (s *Server) Start(ctx context.Context) error {
 		if t, ok := h.(lifecycle.AfterControlPlaneUpgrade); ok {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.AfterControlPlaneUpgrade,
-				Name:        h.Name(),
-				HandlerFunc: t.AfterControlPlaneUpgrade,
+				Name:        strings.ToLower(h.Name()),
+				HandlerFunc: instrument(runtimehooksv1.AfterControlPlaneUpgrade, h.Name(), t.AfterControlPlaneUpgrade),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -130,7 +130,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.BeforeClusterDelete,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.BeforeClusterDelete,
+				HandlerFunc: instrument(runtimehooksv1.BeforeClusterDelete, h.Name(), t.BeforeClusterDelete),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -141,7 +141,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.DiscoverVariables,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.DiscoverVariables,
+				HandlerFunc: instrument(runtimehooksv1.DiscoverVariables, h.Name(), t.DiscoverVariables),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -152,7 +152,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.GeneratePatches,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.GeneratePatches,
+				HandlerFunc: instrument(runtimehooksv1.GeneratePatches, h.Name(), t.GeneratePatches),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
@@ -163,7 +163,7 @@ func (s *Server) Start(ctx context.Context) error {
 			if err := webhookServer.AddExtensionHandler(server.ExtensionHandler{
 				Hook:        runtimehooksv1.ValidateTopology,
 				Name:        strings.ToLower(h.Name()),
-				HandlerFunc: t.ValidateTopology,
+				HandlerFunc: instrument(runtimehooksv1.ValidateTopology, h.Name(), t.ValidateTopology),
 			}); err != nil {
 				setupLog.Error(err, "error adding handler")
 				return err
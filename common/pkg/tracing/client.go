@@ -0,0 +1,145 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// WrapClient returns a client that creates a span for every Kubernetes API call.
+func WrapClient(c ctrlclient.Client) ctrlclient.Client {
+	if _, ok := c.(*tracingClient); ok {
+		return c
+	}
+	return &tracingClient{Client: c}
+}
+
+// NewClusterClient creates a client for the workload cluster, as remote.NewClusterClient does, creating a span for
+// the creation of the client and for every Kubernetes API call made with the returned client.
+func NewClusterClient(
+	ctx context.Context,
+	sourceName string,
+	c ctrlclient.Client,
+	clusterKey ctrlclient.ObjectKey,
+) (ctrlclient.Client, error) {
+	ctx, span := Start(ctx, "NewClusterClient", clusterKey)
+	remoteClient, err := remote.NewClusterClient(ctx, sourceName, c, clusterKey)
+	End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return WrapClient(remoteClient), nil
+}
+
+type tracingClient struct {
+	ctrlclient.Client
+}
+
+// start creates a span for an API call on the object, or list of objects, with the given key.
+func (c *tracingClient) start(
+	ctx context.Context,
+	verb string,
+	obj runtime.Object,
+	key ctrlclient.ObjectKey,
+) (context.Context, func(error)) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+			kind = gvk.Kind
+		}
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("k8s.kind", kind)}
+	if key.Namespace != "" {
+		attrs = append(attrs, attribute.String("k8s.namespace", key.Namespace))
+	}
+	if key.Name != "" {
+		attrs = append(attrs, attribute.String("k8s.name", key.Name))
+	}
+
+	ctx, span := Start(ctx, fmt.Sprintf("%s %s", verb, kind), ctrlclient.ObjectKey{}, attrs...)
+	return ctx, func(err error) { End(span, err) }
+}
+
+func (c *tracingClient) Get(
+	ctx context.Context,
+	key ctrlclient.ObjectKey,
+	obj ctrlclient.Object,
+	opts ...ctrlclient.GetOption,
+) error {
+	ctx, end := c.start(ctx, "Get", obj, key)
+	err := c.Client.Get(ctx, key, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) List(ctx context.Context, list ctrlclient.ObjectList, opts ...ctrlclient.ListOption) error {
+	ctx, end := c.start(ctx, "List", list, ctrlclient.ObjectKey{})
+	err := c.Client.List(ctx, list, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Create(
+	ctx context.Context,
+	obj ctrlclient.Object,
+	opts ...ctrlclient.CreateOption,
+) error {
+	ctx, end := c.start(ctx, "Create", obj, ctrlclient.ObjectKeyFromObject(obj))
+	err := c.Client.Create(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Delete(
+	ctx context.Context,
+	obj ctrlclient.Object,
+	opts ...ctrlclient.DeleteOption,
+) error {
+	ctx, end := c.start(ctx, "Delete", obj, ctrlclient.ObjectKeyFromObject(obj))
+	err := c.Client.Delete(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Update(
+	ctx context.Context,
+	obj ctrlclient.Object,
+	opts ...ctrlclient.UpdateOption,
+) error {
+	ctx, end := c.start(ctx, "Update", obj, ctrlclient.ObjectKeyFromObject(obj))
+	err := c.Client.Update(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Patch(
+	ctx context.Context,
+	obj ctrlclient.Object,
+	patch ctrlclient.Patch,
+	opts ...ctrlclient.PatchOption,
+) error {
+	ctx, end := c.start(ctx, "Patch", obj, ctrlclient.ObjectKeyFromObject(obj))
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) DeleteAllOf(
+	ctx context.Context,
+	obj ctrlclient.Object,
+	opts ...ctrlclient.DeleteAllOfOption,
+) error {
+	ctx, end := c.start(ctx, "DeleteAllOf", obj, ctrlclient.ObjectKeyFromObject(obj))
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	end(err)
+	return err
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWrapClient(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"},
+	}
+	c := WrapClient(fake.NewClientBuilder().WithObjects(cm).Build())
+
+	clusterKey := ctrlclient.ObjectKey{Namespace: "default", Name: "my-cluster"}
+	ctx, span := Start(context.Background(), "parent", clusterKey)
+	require.NoError(t, c.Get(ctx, ctrlclient.ObjectKeyFromObject(cm), &corev1.ConfigMap{}))
+	require.Error(t, c.Get(ctx, ctrlclient.ObjectKey{Namespace: "ns", Name: "missing"}, &corev1.ConfigMap{}))
+	End(span, nil)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "Get ConfigMap", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String("k8s.name", "cm"))
+	assert.Contains(t, spans[0].Attributes, ClusterKeyAttribute.String("default/my-cluster"))
+	assert.Equal(t, spans[2].SpanContext.SpanID(), spans[0].Parent.SpanID())
+
+	assert.Equal(t, "Get ConfigMap", spans[1].Name)
+	assert.NotEmpty(t, spans[1].Events, "expected error to be recorded")
+
+	assert.Equal(t, "parent", spans[2].Name)
+	assert.Contains(t, spans[2].Attributes, ClusterKeyAttribute.String("default/my-cluster"))
+}
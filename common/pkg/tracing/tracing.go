@@ -0,0 +1,139 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing provides optional OpenTelemetry tracing of runtime hooks, mutators and Kubernetes API calls.
+// Spans are exported via OTLP when an OTLP endpoint is configured, and are discarded otherwise.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	tracerName = "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix"
+
+	// ClusterKeyAttribute is the span attribute holding the namespaced name of the Cluster.
+	ClusterKeyAttribute = attribute.Key("cluster")
+)
+
+type Options struct {
+	otlpEndpoint  string
+	otlpInsecure  bool
+	samplingRatio float64
+	serviceName   string
+}
+
+func NewOptions() *Options {
+	return &Options{
+		samplingRatio: 1,
+		serviceName:   "cluster-api-runtime-extensions-nutanix",
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(
+		&o.otlpEndpoint,
+		"tracing-otlp-endpoint",
+		o.otlpEndpoint,
+		"OTLP gRPC endpoint (host:port) to export traces to. Tracing is disabled if empty.",
+	)
+	fs.BoolVar(
+		&o.otlpInsecure,
+		"tracing-otlp-insecure",
+		o.otlpInsecure,
+		"Disable TLS when connecting to the OTLP endpoint.",
+	)
+	fs.Float64Var(
+		&o.samplingRatio,
+		"tracing-sampling-ratio",
+		o.samplingRatio,
+		"Ratio of traces to sample, between 0 and 1.",
+	)
+	fs.StringVar(
+		&o.serviceName,
+		"tracing-service-name",
+		o.serviceName,
+		"Service name reported in exported traces.",
+	)
+}
+
+// Setup configures the global OpenTelemetry tracer provider to export spans to the configured OTLP endpoint. The
+// returned function flushes and stops the exporter. If no OTLP endpoint is configured, tracing is left disabled.
+func (o *Options) Setup(ctx context.Context) (func(context.Context) error, error) {
+	if o.otlpEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.otlpEndpoint)}
+	if o.otlpInsecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(o.serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.samplingRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(
+		propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	)
+
+	return tp.Shutdown, nil
+}
+
+type clusterKeyContextKey struct{}
+
+// Start creates a span and a context containing it. The cluster key is stored in the returned context so that all
+// child spans created via this package carry it as an attribute.
+func Start(
+	ctx context.Context,
+	spanName string,
+	clusterKey ctrlclient.ObjectKey,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	if clusterKey.Name != "" {
+		ctx = context.WithValue(ctx, clusterKeyContextKey{}, clusterKey)
+	} else {
+		clusterKey, _ = ctx.Value(clusterKeyContextKey{}).(ctrlclient.ObjectKey)
+	}
+	if clusterKey.Name != "" {
+		attrs = append(attrs, ClusterKeyAttribute.String(clusterKey.String()))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, spanName, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -5,7 +5,7 @@ module github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/external/caap
 
 go 1.21
 
-require sigs.k8s.io/cluster-api-addon-provider-helm v0.1.1-alpha.1
+require sigs.k8s.io/cluster-api-addon-provider-helm v0.2.0
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
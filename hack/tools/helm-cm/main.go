@@ -6,6 +6,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -22,6 +23,10 @@ import (
 
 const (
 	createHelmAddonsConfigMap = "helm-addons"
+
+	// kubernetesVersionsFile optionally configures a list of chart versions for an addon, one per range of Kubernetes
+	// versions, next to its kustomization.yaml.tmpl.
+	kubernetesVersionsFile = "kubernetes-versions.yaml.tmpl"
 )
 
 var log = ctrl.LoggerFrom(context.Background())
@@ -70,10 +75,34 @@ func main() {
 }
 
 type configMapInfo struct {
-	configMapFieldName string
-	RepositoryURL      string `json:"RepositoryURL"`
-	ChartVersion       string `json:"ChartVersion"`
-	ChartName          string `json:"ChartName"`
+	RepositoryURL          string `json:"RepositoryURL"`
+	ChartVersion           string `json:"ChartVersion"`
+	ChartName              string `json:"ChartName"`
+	KubernetesVersionRange string `json:"KubernetesVersionRange,omitempty"`
+	ImageTag               string `json:"ImageTag,omitempty"`
+}
+
+type kubernetesVersionInfo struct {
+	KubernetesVersionRange string `json:"kubernetesVersionRange"`
+	Version                string `json:"version"`
+	ImageTag               string `json:"imageTag"`
+}
+
+// readKubernetesVersions returns the chart versions configured per range of Kubernetes versions in the
+// kubernetes-versions.yaml.tmpl file of the addon directory, or nil if the file does not exist.
+func readKubernetesVersions(addonDir string) ([]kubernetesVersionInfo, error) {
+	b, err := os.ReadFile(path.Join(addonDir, kubernetesVersionsFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	var versions []kubernetesVersionInfo
+	if err := yamlMarshal.Unmarshal([]byte(os.ExpandEnv(string(b))), &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", kubernetesVersionsFile, err)
+	}
+	return versions, nil
 }
 
 func createConfigMapFromDir(kustomizeDir string) (*corev1.ConfigMap, error) {
@@ -86,7 +115,7 @@ func createConfigMapFromDir(kustomizeDir string) (*corev1.ConfigMap, error) {
 		fullPath = path.Join(wd, kustomizeDir)
 	}
 	configDirFS := os.DirFS(fullPath)
-	results := []configMapInfo{}
+	results := map[string][]configMapInfo{}
 	err := fs.WalkDir(configDirFS, ".", func(filepath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -119,18 +148,33 @@ func createConfigMapFromDir(kustomizeDir string) (*corev1.ConfigMap, error) {
 			name := info["name"].(string)
 			dirName := strings.Split(filepath, "/")[0]
 			i := configMapInfo{
-				configMapFieldName: dirName,
-				RepositoryURL:      repo,
-				ChartName:          name,
+				RepositoryURL: repo,
+				ChartName:     name,
+			}
+			versions, err := readKubernetesVersions(path.Join(fullPath, dirName))
+			if err != nil {
+				return err
+			}
+			if versions == nil {
+				versionEnvVar := info["version"].(string)
+				version := os.ExpandEnv(versionEnvVar)
+				i.ChartVersion = version
+				results[dirName] = append(results[dirName], i)
+				return nil
+			}
+			for _, v := range versions {
+				i.ChartVersion = v.Version
+				i.KubernetesVersionRange = v.KubernetesVersionRange
+				i.ImageTag = v.ImageTag
+				results[dirName] = append(results[dirName], i)
 			}
-			versionEnvVar := info["version"].(string)
-			version := os.ExpandEnv(versionEnvVar)
-			i.ChartVersion = version
-			results = append(results, i)
 			return nil
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	finalCM := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -142,20 +186,26 @@ func createConfigMapFromDir(kustomizeDir string) (*corev1.ConfigMap, error) {
 		},
 		Data: make(map[string]string),
 	}
-	for _, res := range results {
-		d, err := yamlMarshal.Marshal(res)
+	for fieldName, res := range results {
+		// An addon with a single chart is configured as a single chart, rather than as a list.
+		var (
+			d   []byte
+			err error
+		)
+		if len(res) == 1 && res[0].KubernetesVersionRange == "" {
+			d, err = yamlMarshal.Marshal(res[0])
+		} else {
+			d, err = yamlMarshal.Marshal(res)
+		}
 		if err != nil {
 			return &finalCM, err
 		}
-		finalCM.Data[res.configMapFieldName] = string(d)
+		finalCM.Data[fieldName] = string(d)
 	}
-	return &finalCM, err
+	return &finalCM, nil
 }
 
-var ignored = []string{
-	"aws-ccm",
-	"aws-ebs-csi",
-}
+var ignored = []string{}
 
 func isIgnored(filepath string) bool {
 	for _, i := range ignored {
@@ -18,7 +18,7 @@ export AWS_CCM_CHART_VERSION_128 := 0.0.8
 export NUTANIX_CCM_CHART_VERSION := 0.3.3
 
 .PHONY: addons.sync
-addons.sync: $(addprefix update-addon.,calico cilium nfd cluster-autoscaler aws-ebs-csi aws-ccm.127 nutanix-storage-csi aws-ccm.128)
+addons.sync: $(addprefix update-addon.,calico cilium nfd cluster-autoscaler aws-ebs-csi aws-ccm.127 nutanix-storage-csi nutanix-snapshot-csi aws-ccm.128)
 
 .PHONY: update-addon.calico
 update-addon.calico: ; $(info $(M) updating calico manifests)
@@ -48,6 +48,10 @@ update-addon.aws-ccm.%: ; $(info $(M) updating aws ccm $* manifests)
 update-addon.nutanix-storage-csi: ; $(info $(M) updating nutanix-storage csi manifests)
 	./hack/addons/update-nutanix-csi.sh
 
+.PHONY: update-addon.nutanix-snapshot-csi
+update-addon.nutanix-snapshot-csi: ; $(info $(M) updating nutanix-snapshot csi manifests)
+	./hack/addons/update-nutanix-snapshot-csi.sh
+
 .PHONY: generate-helm-configmap
 generate-helm-configmap:
 	go run hack/tools/helm-cm/main.go -kustomize-directory="./hack/addons/kustomize" -output-file="./charts/cluster-api-runtime-extensions-nutanix/templates/helm-config.yaml"
@@ -0,0 +1,237 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonremoval
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+// remoteClientFunc returns a client for the cluster with the given key.
+type remoteClientFunc func(ctx context.Context, c ctrlclient.Client, clusterKey ctrlclient.ObjectKey) (
+	ctrlclient.Client, error,
+)
+
+func newRemoteClient(
+	ctx context.Context,
+	c ctrlclient.Client,
+	clusterKey ctrlclient.ObjectKey,
+) (ctrlclient.Client, error) {
+	return tracing.NewClusterClient(ctx, "", c, clusterKey)
+}
+
+// Reconciler removes the addons that are no longer enabled in the clusterConfig variable of Clusters.
+type Reconciler struct {
+	client       ctrlclient.Client
+	recorder     record.EventRecorder
+	remoteClient remoteClientFunc
+}
+
+func New(client ctrlclient.Client, recorder record.EventRecorder) *Reconciler {
+	return &Reconciler{
+		client:       client,
+		recorder:     recorder,
+		remoteClient: newRemoteClient,
+	}
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("addonremoval").
+		For(&clusterv1.Cluster{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	cluster := &clusterv1.Cluster{}
+	if err := r.client.Get(ctx, req.NamespacedName, cluster); err != nil {
+		return ctrl.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if !cluster.DeletionTimestamp.IsZero() || cluster.Spec.Topology == nil {
+		return ctrl.Result{}, nil
+	}
+
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
+	addons, _, err := variables.Get[v1alpha1.Addons](varMap, clusterconfig.MetaVariableName, "addons")
+	if err != nil {
+		// Never remove addons if the desired addons cannot be determined.
+		return ctrl.Result{}, fmt.Errorf("failed to read addons from cluster definition: %w", err)
+	}
+	desired := desiredAddons(&addons)
+	desiredHelmCharts := sets.New[string]()
+	for i := range addons.HelmCharts {
+		desiredHelmCharts.Insert(addons.HelmCharts[i].Name)
+	}
+
+	if err := r.labelLegacyAddonObjects(ctx, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	selector, err := addonSelector(cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	hcps := &caaphv1.HelmChartProxyList{}
+	if err := r.client.List(ctx, hcps, ctrlclient.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list HelmChartProxies: %w", err)
+	}
+	crss := &crsv1.ClusterResourceSetList{}
+	if err := r.client.List(ctx, crss, ctrlclient.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+	}
+
+	removed := sets.New[string]()
+	failed := map[string]error{}
+	for i := range hcps.Items {
+		hcp := &hcps.Items[i]
+		addon := hcp.Labels[utils.AddonLabel]
+		if addon == utils.AddonHelmCharts {
+			// Each of the additional Helm charts is removed individually.
+			helmChart := hcp.Labels[utils.AddonHelmChartLabel]
+			if desiredHelmCharts.Has(helmChart) {
+				continue
+			}
+			addon = utils.AddonHelmCharts + "/" + helmChart
+		} else if desired.Has(addon) {
+			continue
+		}
+		// The Cluster API Addon Provider for Helm uninstalls the Helm releases of deleted HelmChartProxies.
+		if err := r.client.Delete(ctx, hcp); ctrlclient.IgnoreNotFound(err) != nil {
+			failed[addon] = fmt.Errorf("failed to delete HelmChartProxy %s: %w", hcp.Name, err)
+			continue
+		}
+		removed.Insert(addon)
+	}
+	for i := range crss.Items {
+		crs := &crss.Items[i]
+		addon := crs.Labels[utils.AddonLabel]
+		if desired.Has(addon) {
+			continue
+		}
+		if err := r.deleteClusterResourceSet(ctx, crs); err != nil {
+			failed[addon] = err
+			continue
+		}
+		removed.Insert(addon)
+	}
+
+	for addon, err := range failed {
+		events.AddonRemovalFailed(r.recorder, cluster, addon, err)
+		removed.Delete(addon)
+	}
+	for _, addon := range sets.List(removed) {
+		ctrl.LoggerFrom(ctx).Info("Removed addon", "addon", addon)
+		events.AddonRemoved(r.recorder, cluster, addon)
+	}
+
+	if len(failed) > 0 {
+		return ctrl.Result{}, fmt.Errorf("failed to remove addons %v", sets.List(sets.KeySet(failed)))
+	}
+	return ctrl.Result{}, nil
+}
+
+// desiredAddons returns the addon label values of the addons enabled in the clusterConfig variable. The CNI is always
+// desired: removing the CNI would break networking of the running cluster.
+func desiredAddons(addons *v1alpha1.Addons) sets.Set[string] {
+	desired := sets.New(utils.AddonCNI)
+	if addons.CSIProviders != nil {
+		desired.Insert(utils.AddonCSI)
+	}
+	if addons.CCM != nil {
+		desired.Insert(utils.AddonCCM)
+	}
+	if addons.ClusterAutoscaler != nil {
+		desired.Insert(utils.AddonClusterAutoscaler)
+	}
+	if addons.NFD != nil {
+		desired.Insert(utils.AddonNFD)
+	}
+	return desired
+}
+
+// addonSelector selects the HelmChartProxies and ClusterResourceSets created to install addons for the cluster.
+func addonSelector(cluster *clusterv1.Cluster) (labels.Selector, error) {
+	hasAddonLabel, err := labels.NewRequirement(utils.AddonLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	return labels.SelectorFromSet(labels.Set{
+		clusterv1.ClusterNameLabel:       cluster.Name,
+		utils.AddonClusterNamespaceLabel: cluster.Namespace,
+	}).Add(*hasAddonLabel), nil
+}
+
+// deleteClusterResourceSet deletes the objects applied by the ClusterResourceSet from all clusters it is bound to,
+// before deleting the ClusterResourceSet itself and the resources that are not referenced by any other
+// ClusterResourceSet. As with Helm, CustomResourceDefinitions and Namespaces are not deleted.
+func (r *Reconciler) deleteClusterResourceSet(ctx context.Context, crs *crsv1.ClusterResourceSet) error {
+	objs, resources, err := utils.ClusterResourceSetObjects(ctx, r.client, crs)
+	if err != nil {
+		return err
+	}
+
+	clusterKeys, err := utils.ClusterResourceSetBoundClusters(ctx, r.client, crs)
+	if err != nil {
+		return err
+	}
+	for _, clusterKey := range clusterKeys {
+		if err := r.deleteFromCluster(ctx, clusterKey, objs); err != nil {
+			return fmt.Errorf(
+				"failed to delete resources of ClusterResourceSet %s from cluster %s: %w",
+				crs.Name,
+				clusterKey,
+				err,
+			)
+		}
+	}
+
+	return utils.DeleteClusterResourceSet(ctx, r.client, crs, resources)
+}
+
+func (r *Reconciler) deleteFromCluster(
+	ctx context.Context,
+	clusterKey ctrlclient.ObjectKey,
+	objs []unstructured.Unstructured,
+) error {
+	remoteClient, err := r.remoteClient(ctx, r.client, clusterKey)
+	if err != nil {
+		return fmt.Errorf("error creating remote cluster client: %w", err)
+	}
+
+	for i := range objs {
+		obj := &objs[i]
+		switch obj.GroupVersionKind().GroupKind().String() {
+		case "CustomResourceDefinition.apiextensions.k8s.io", "Namespace":
+			continue
+		}
+		err := remoteClient.Delete(ctx, obj)
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", obj.GetKind(), ctrlclient.ObjectKeyFromObject(obj), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonremoval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+const nfdManifests = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: node-feature-discovery
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-master
+  namespace: node-feature-discovery
+`
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, clusterv1.AddToScheme(scheme))
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+	return scheme
+}
+
+func helmChartLabels(cluster *clusterv1.Cluster, helmChart string) map[string]string {
+	labels := utils.AddonLabels(cluster, utils.AddonHelmCharts)
+	labels[utils.AddonHelmChartLabel] = helmChart
+	return labels
+}
+
+//nolint:funlen // Long tests are OK
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	clusterConfigVar := capitest.VariableWithValue(
+		clusterconfig.MetaVariableName,
+		v1alpha1.ClusterConfigSpec{
+			GenericClusterConfig: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CSIProviders: &v1alpha1.CSI{},
+					HelmCharts:   []v1alpha1.HelmChart{{Name: "ingress-nginx"}},
+				},
+			},
+		},
+	)
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{
+				Variables: []clusterv1.ClusterVariable{{
+					Name:  clusterConfigVar.Name,
+					Value: clusterConfigVar.Value,
+				}},
+			},
+		},
+	}
+
+	// The CNI is never removed, even if it is not enabled in the clusterConfig variable.
+	cniHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "cilium-cni-installation-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonCNI),
+		},
+	}
+	csiCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "aws-ebs-csi-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonCSI),
+		},
+	}
+	// The cluster-autoscaler addon is deployed to another namespace, e.g. of the management cluster.
+	caHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "management",
+			Name:      "cluster-autoscaler-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonClusterAutoscaler),
+		},
+	}
+	// Additional Helm charts are removed individually.
+	ingressNginxHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "helm-chart-ingress-nginx-test-cluster",
+			Labels:    helmChartLabels(cluster, "ingress-nginx"),
+		},
+	}
+	kyvernoHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "helm-chart-kyverno-test-cluster",
+			Labels:    helmChartLabels(cluster, "kyverno"),
+		},
+	}
+	nfdConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "node-feature-discovery-test-cluster"},
+		Data:       map[string]string{"custom-resources.yaml": nfdManifests},
+	}
+	sharedConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shared"},
+	}
+	nfdCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "node-feature-discovery-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonNFD),
+		},
+		Spec: crsv1.ClusterResourceSetSpec{
+			Resources: []crsv1.ResourceRef{
+				{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: nfdConfigMap.Name},
+				{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: sharedConfigMap.Name},
+			},
+		},
+	}
+	otherCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other"},
+		Spec: crsv1.ClusterResourceSetSpec{
+			Resources: []crsv1.ResourceRef{
+				{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: sharedConfigMap.Name},
+			},
+		},
+	}
+	binding := &crsv1.ClusterResourceSetBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: cluster.Name},
+		Spec: crsv1.ClusterResourceSetBindingSpec{
+			Bindings: []*crsv1.ResourceSetBinding{{ClusterResourceSetName: nfdCRS.Name}},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(
+			cluster,
+			cniHCP,
+			csiCRS,
+			caHCP,
+			ingressNginxHCP,
+			kyvernoHCP,
+			nfdConfigMap,
+			sharedConfigMap,
+			nfdCRS,
+			otherCRS,
+			binding,
+		).
+		Build()
+
+	nfdNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "node-feature-discovery"}}
+	nfdDeployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: nfdNamespace.Name, Name: "nfd-master"},
+	}
+	remoteClient := fake.NewClientBuilder().WithObjects(nfdNamespace, nfdDeployment).Build()
+
+	recorder := record.NewFakeRecorder(10)
+	r := New(client, recorder)
+	r.remoteClient = func(
+		_ context.Context,
+		_ ctrlclient.Client,
+		clusterKey ctrlclient.ObjectKey,
+	) (ctrlclient.Client, error) {
+		assert.Equal(t, ctrlclient.ObjectKeyFromObject(cluster), clusterKey)
+		return remoteClient, nil
+	}
+
+	_, err := r.Reconcile(
+		context.Background(),
+		ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(cluster)},
+	)
+	require.NoError(t, err)
+
+	for _, obj := range []ctrlclient.Object{cniHCP, csiCRS, ingressNginxHCP, sharedConfigMap, otherCRS} {
+		assert.NoError(
+			t,
+			client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj),
+			"expected %T %s to exist",
+			obj,
+			obj.GetName(),
+		)
+	}
+	for _, obj := range []ctrlclient.Object{caHCP, kyvernoHCP, nfdCRS, nfdConfigMap} {
+		assert.True(
+			t,
+			apierrors.IsNotFound(client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj)),
+			"expected %T %s to be deleted",
+			obj,
+			obj.GetName(),
+		)
+	}
+
+	assert.True(t, apierrors.IsNotFound(
+		remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(nfdDeployment), nfdDeployment),
+	))
+	assert.NoError(
+		t,
+		remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(nfdNamespace), nfdNamespace),
+	)
+
+	close(recorder.Events)
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.Equal(t, []string{
+		"Normal AddonRemoved Removed cluster-autoscaler addon",
+		"Normal AddonRemoved Removed helm-charts/kyverno addon",
+		"Normal AddonRemoved Removed nfd addon",
+	}, events)
+}
+
+func TestReconcileInvalidVariable(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{
+				Variables: []clusterv1.ClusterVariable{{
+					Name: clusterconfig.MetaVariableName,
+					// Not decodable as the addons of the clusterConfig variable.
+					Value: capitest.VariableWithValue(clusterconfig.MetaVariableName, "invalid").Value,
+				}},
+			},
+		},
+	}
+	nfdHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "nfd-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonNFD),
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(testScheme(t)).WithObjects(cluster, nfdHCP).Build()
+
+	_, err := New(client, record.NewFakeRecorder(10)).Reconcile(
+		context.Background(),
+		ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(cluster)},
+	)
+	require.Error(t, err)
+	assert.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(nfdHCP), nfdHCP))
+}
+
+// TestReconcileLegacyAddons checks that the addons installed before the addon labels were introduced are removed.
+func TestReconcileLegacyAddons(t *testing.T) {
+	t.Parallel()
+
+	clusterConfigVar := capitest.VariableWithValue(
+		clusterconfig.MetaVariableName,
+		v1alpha1.ClusterConfigSpec{GenericClusterConfig: v1alpha1.GenericClusterConfig{Addons: &v1alpha1.Addons{}}},
+	)
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster", UID: "test-cluster-uid"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{
+				Variables: []clusterv1.ClusterVariable{{
+					Name:  clusterConfigVar.Name,
+					Value: clusterConfigVar.Value,
+				}},
+			},
+		},
+	}
+	ownedByCluster := []metav1.OwnerReference{{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+	}}
+
+	// Created by EnsureCRSForClusterFromObjects before the addon labels were introduced.
+	nfdCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            "node-feature-discovery-test-cluster",
+			OwnerReferences: ownedByCluster,
+		},
+		Spec: crsv1.ClusterResourceSetSpec{
+			Strategy: string(crsv1.ClusterResourceSetStrategyReconcile),
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+		},
+	}
+	ccmCRS := nfdCRS.DeepCopy()
+	ccmCRS.Name = "aws-ccm-v1.27.1-test-cluster"
+	// Named like an addon of the cluster, but owned by another cluster, e.g. the nutanix-csi HelmChartProxy of a
+	// cluster named snapshot-test-cluster.
+	snapshotHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "nutanix-csi-snapshot-test-cluster",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: clusterv1.GroupVersion.String(),
+				Kind:       "Cluster",
+				Name:       "snapshot-test-cluster",
+				UID:        "snapshot-test-cluster-uid",
+			}},
+		},
+	}
+	// The cluster-autoscaler ClusterResourceSet is owned by the management cluster.
+	caCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-autoscaler-test-cluster"},
+	}
+	// Not created to install an addon.
+	userCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            "user-test-cluster",
+			OwnerReferences: ownedByCluster,
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(cluster, nfdCRS, ccmCRS, snapshotHCP, caCRS, userCRS).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+	_, err := New(client, recorder).Reconcile(
+		context.Background(),
+		ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(cluster)},
+	)
+	require.NoError(t, err)
+
+	for _, obj := range []ctrlclient.Object{snapshotHCP, userCRS} {
+		assert.NoError(
+			t,
+			client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj),
+			"expected %T %s to exist",
+			obj,
+			obj.GetName(),
+		)
+		assert.NotContains(t, obj.GetLabels(), utils.AddonLabel)
+	}
+	for _, obj := range []ctrlclient.Object{nfdCRS, ccmCRS, caCRS} {
+		assert.True(
+			t,
+			apierrors.IsNotFound(client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj)),
+			"expected %T %s to be deleted",
+			obj,
+			obj.GetName(),
+		)
+	}
+
+	close(recorder.Events)
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, e)
+	}
+	assert.Equal(t, []string{
+		"Normal AddonRemoved Removed ccm addon",
+		"Normal AddonRemoved Removed cluster-autoscaler addon",
+		"Normal AddonRemoved Removed nfd addon",
+	}, events)
+}
@@ -0,0 +1,15 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package addonremoval provides a controller that removes the addons that are no longer enabled in the clusterConfig
+// variable of a Cluster, deleting the HelmChartProxies and ClusterResourceSets created to install them and
+// uninstalling the addons from the clusters they were applied to. The objects created before the addon labels were
+// introduced are labelled first, identified by their names.
+//
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=watch;list;get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesets,verbs=watch;list;get;patch;delete
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesetbindings,verbs=watch;list;get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=watch;list;get;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;delete
+package addonremoval
@@ -0,0 +1,105 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonremoval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+// legacyAddonNames maps the names, without the cluster name suffix, of the HelmChartProxies and ClusterResourceSets
+// created with the default configuration to install addons for a cluster before the addon labels were introduced, to
+// the addon they install.
+var legacyAddonNames = map[string]string{
+	"calico-cni-installation": utils.AddonCNI,
+	"cilium-cni-installation": utils.AddonCNI,
+	"node-feature-discovery":  utils.AddonNFD,
+	"cluster-autoscaler":      utils.AddonClusterAutoscaler,
+	"aws-ebs-csi":             utils.AddonCSI,
+	"nutanix-csi":             utils.AddonCSI,
+	"nutanix-csi-snapshot":    utils.AddonCSI,
+	"nutanix-ccm":             utils.AddonCCM,
+}
+
+// legacyAWSCCMName matches the names, without the cluster name suffix, of the ClusterResourceSets installing the AWS
+// CCM, which include the version of the CCM.
+var legacyAWSCCMName = regexp.MustCompile(`^aws-ccm-v[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// labelLegacyAddonObjects sets the addon labels on the HelmChartProxies and ClusterResourceSets created to install
+// addons for the cluster before the addon labels were introduced, so that they are selected by addonSelector.
+func (r *Reconciler) labelLegacyAddonObjects(ctx context.Context, cluster *clusterv1.Cluster) error {
+	hcps := &caaphv1.HelmChartProxyList{}
+	if err := r.client.List(ctx, hcps); err != nil {
+		return fmt.Errorf("failed to list HelmChartProxies: %w", err)
+	}
+	for i := range hcps.Items {
+		if err := r.labelLegacyAddonObject(ctx, cluster, &hcps.Items[i]); err != nil {
+			return fmt.Errorf("failed to label HelmChartProxy %s: %w", hcps.Items[i].Name, err)
+		}
+	}
+
+	crss := &crsv1.ClusterResourceSetList{}
+	if err := r.client.List(ctx, crss); err != nil {
+		return fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+	}
+	for i := range crss.Items {
+		if err := r.labelLegacyAddonObject(ctx, cluster, &crss.Items[i]); err != nil {
+			return fmt.Errorf("failed to label ClusterResourceSet %s: %w", crss.Items[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) labelLegacyAddonObject(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	obj ctrlclient.Object,
+) error {
+	if _, ok := obj.GetLabels()[utils.AddonLabel]; ok {
+		return nil
+	}
+	addon, ok := legacyAddon(cluster, obj)
+	if !ok {
+		return nil
+	}
+	return utils.LabelAddonObject(ctx, r.client, ctrlclient.ObjectKeyFromObject(obj), obj, cluster, addon)
+}
+
+// legacyAddon returns the addon installed for the cluster by the unlabelled object, identified by its name. As the
+// names of different clusters can overlap, e.g. nutanix-csi-snapshot-a is also nutanix-csi followed by snapshot-a,
+// the object must also be owned by the Cluster. The cluster-autoscaler ClusterResourceSets are owned by the
+// management cluster instead, so they are only identified by their name in the namespace of the cluster.
+func legacyAddon(cluster *clusterv1.Cluster, obj ctrlclient.Object) (string, bool) {
+	name, ok := strings.CutSuffix(obj.GetName(), "-"+cluster.Name)
+	if !ok {
+		return "", false
+	}
+	addon, ok := legacyAddonNames[name]
+	if !ok && legacyAWSCCMName.MatchString(name) {
+		addon, ok = utils.AddonCCM, true
+	}
+	if !ok {
+		return "", false
+	}
+
+	if addon == utils.AddonClusterAutoscaler && obj.GetNamespace() == cluster.Namespace {
+		return addon, true
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "Cluster" && ref.UID == cluster.UID {
+			return addon, true
+		}
+	}
+	return "", false
+}
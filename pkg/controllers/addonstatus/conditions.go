@@ -0,0 +1,54 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonstatus
+
+import (
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+const (
+	// CNIReadyCondition reports whether the CNI addon has been installed on the cluster.
+	CNIReadyCondition clusterv1.ConditionType = "CNIReady"
+	// CSIReadyCondition reports whether the CSI addons have been installed on the cluster.
+	CSIReadyCondition clusterv1.ConditionType = "CSIReady"
+	// CCMReadyCondition reports whether the CCM addon has been installed on the cluster.
+	CCMReadyCondition clusterv1.ConditionType = "CCMReady"
+	// ClusterAutoscalerReadyCondition reports whether the cluster-autoscaler addon has been installed for the cluster.
+	ClusterAutoscalerReadyCondition clusterv1.ConditionType = "ClusterAutoscalerReady"
+	// NFDReadyCondition reports whether the node feature discovery addon has been installed on the cluster.
+	NFDReadyCondition clusterv1.ConditionType = "NFDReady"
+	// HelmChartsReadyCondition reports whether the additional Helm charts have been installed on the cluster.
+	HelmChartsReadyCondition clusterv1.ConditionType = "HelmChartsReady"
+
+	// HelmReleaseNotReadyReason is used when a HelmReleaseProxy of the addon is not ready or has not been created yet.
+	HelmReleaseNotReadyReason = "HelmReleaseNotReady"
+	// ResourcesNotAppliedReason is used when the resources of a ClusterResourceSet of the addon have not been applied.
+	ResourcesNotAppliedReason = "ResourcesNotApplied"
+)
+
+// addonConditions maps the addon label values to the Cluster condition reporting the status of the addon.
+var addonConditions = map[string]clusterv1.ConditionType{
+	utils.AddonCNI:               CNIReadyCondition,
+	utils.AddonCSI:               CSIReadyCondition,
+	utils.AddonCCM:               CCMReadyCondition,
+	utils.AddonClusterAutoscaler: ClusterAutoscalerReadyCondition,
+	utils.AddonNFD:               NFDReadyCondition,
+	utils.AddonHelmCharts:        HelmChartsReadyCondition,
+}
+
+func ownedConditions() []clusterv1.ConditionType {
+	conds := make([]clusterv1.ConditionType, 0, len(addonConditions))
+	for _, c := range addonConditions {
+		conds = append(conds, c)
+	}
+	return conds
+}
+
+// AddonCondition returns the Cluster condition reporting the status of the addon identified by the addon label value.
+func AddonCondition(addon string) (clusterv1.ConditionType, bool) {
+	c, ok := addonConditions[addon]
+	return c, ok
+}
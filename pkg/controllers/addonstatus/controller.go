@@ -0,0 +1,245 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonstatus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+// Reconciler sets conditions on Clusters reporting the status of the addons installed for them.
+type Reconciler struct {
+	client ctrlclient.Client
+}
+
+func New(client ctrlclient.Client) *Reconciler {
+	return &Reconciler{client: client}
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isAddon := builder.WithPredicates(predicate.NewPredicateFuncs(func(obj ctrlclient.Object) bool {
+		_, ok := obj.GetLabels()[utils.AddonLabel]
+		return ok
+	}))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("addonstatus").
+		For(&clusterv1.Cluster{}).
+		Watches(
+			&caaphv1.HelmChartProxy{},
+			handler.EnqueueRequestsFromMapFunc(addonObjectToCluster),
+			isAddon,
+		).
+		Watches(
+			&crsv1.ClusterResourceSet{},
+			handler.EnqueueRequestsFromMapFunc(addonObjectToCluster),
+			isAddon,
+		).
+		Watches(
+			&caaphv1.HelmReleaseProxy{},
+			handler.EnqueueRequestsFromMapFunc(r.helmReleaseProxyToCluster),
+		).
+		Complete(r)
+}
+
+// addonObjectToCluster maps HelmChartProxies and ClusterResourceSets to the Cluster they install an addon for.
+func addonObjectToCluster(_ context.Context, obj ctrlclient.Object) []reconcile.Request {
+	key, ok := clusterKeyFromLabels(obj.GetLabels())
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
+// helmReleaseProxyToCluster maps HelmReleaseProxies to the Cluster their HelmChartProxy installs an addon for.
+func (r *Reconciler) helmReleaseProxyToCluster(ctx context.Context, obj ctrlclient.Object) []reconcile.Request {
+	hcpName, ok := obj.GetLabels()[caaphv1.HelmChartProxyLabelName]
+	if !ok {
+		return nil
+	}
+	hcp := &caaphv1.HelmChartProxy{}
+	if err := r.client.Get(ctx, ctrlclient.ObjectKey{Namespace: obj.GetNamespace(), Name: hcpName}, hcp); err != nil {
+		return nil
+	}
+	return addonObjectToCluster(ctx, hcp)
+}
+
+func clusterKeyFromLabels(l map[string]string) (ctrlclient.ObjectKey, bool) {
+	if _, ok := l[utils.AddonLabel]; !ok {
+		return ctrlclient.ObjectKey{}, false
+	}
+	name, ok := l[clusterv1.ClusterNameLabel]
+	if !ok {
+		return ctrlclient.ObjectKey{}, false
+	}
+	namespace, ok := l[utils.AddonClusterNamespaceLabel]
+	if !ok {
+		return ctrlclient.ObjectKey{}, false
+	}
+	return ctrlclient.ObjectKey{Namespace: namespace, Name: name}, true
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	cluster := &clusterv1.Cluster{}
+	if err := r.client.Get(ctx, req.NamespacedName, cluster); err != nil {
+		return ctrl.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(cluster, r.client)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create patch helper: %w", err)
+	}
+
+	statuses, err := r.addonStatuses(ctx, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for addon, conditionType := range addonConditions {
+		status, ok := statuses[addon]
+		switch {
+		case !ok:
+			conditions.Delete(cluster, conditionType)
+		case len(status.notReady) == 0:
+			conditions.MarkTrue(cluster, conditionType)
+		default:
+			sort.Strings(status.notReady)
+			conditions.MarkFalse(
+				cluster,
+				conditionType,
+				status.reason,
+				clusterv1.ConditionSeverityWarning,
+				"%s",
+				strings.Join(status.notReady, "; "),
+			)
+		}
+	}
+
+	err = patchHelper.Patch(ctx, cluster, patch.WithOwnedConditions{Conditions: ownedConditions()})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to patch Cluster conditions: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+type addonStatus struct {
+	// reason is the reason of the first object found to be not ready.
+	reason   string
+	notReady []string
+}
+
+func (s *addonStatus) markNotReady(reason, message string) {
+	if s.reason == "" {
+		s.reason = reason
+	}
+	s.notReady = append(s.notReady, message)
+}
+
+// addonStatuses returns the status of each addon installed for the cluster, keyed by addon label value.
+func (r *Reconciler) addonStatuses(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+) (map[string]*addonStatus, error) {
+	hasAddonLabel, err := labels.NewRequirement(utils.AddonLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.SelectorFromSet(labels.Set{
+		clusterv1.ClusterNameLabel:       cluster.Name,
+		utils.AddonClusterNamespaceLabel: cluster.Namespace,
+	}).Add(*hasAddonLabel)
+
+	statuses := map[string]*addonStatus{}
+	statusFor := func(obj ctrlclient.Object) *addonStatus {
+		addon := obj.GetLabels()[utils.AddonLabel]
+		if _, ok := statuses[addon]; !ok {
+			statuses[addon] = &addonStatus{}
+		}
+		return statuses[addon]
+	}
+
+	crss := &crsv1.ClusterResourceSetList{}
+	if err := r.client.List(ctx, crss, ctrlclient.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+	}
+	for i := range crss.Items {
+		crs := &crss.Items[i]
+		status := statusFor(crs)
+		if !conditions.IsTrue(crs, crsv1.ResourcesAppliedCondition) {
+			status.markNotReady(
+				ResourcesNotAppliedReason,
+				notReadyMessage("ClusterResourceSet", crs, crsv1.ResourcesAppliedCondition),
+			)
+		}
+	}
+
+	hcps := &caaphv1.HelmChartProxyList{}
+	if err := r.client.List(ctx, hcps, ctrlclient.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list HelmChartProxies: %w", err)
+	}
+	for i := range hcps.Items {
+		hcp := &hcps.Items[i]
+		status := statusFor(hcp)
+
+		hrps := &caaphv1.HelmReleaseProxyList{}
+		err := r.client.List(
+			ctx,
+			hrps,
+			ctrlclient.InNamespace(hcp.Namespace),
+			ctrlclient.MatchingLabels{caaphv1.HelmChartProxyLabelName: hcp.Name},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list HelmReleaseProxies: %w", err)
+		}
+		if len(hrps.Items) == 0 {
+			status.markNotReady(
+				HelmReleaseNotReadyReason,
+				fmt.Sprintf("HelmChartProxy %s has not created a HelmReleaseProxy yet", hcp.Name),
+			)
+			continue
+		}
+		for j := range hrps.Items {
+			hrp := &hrps.Items[j]
+			if !conditions.IsTrue(hrp, clusterv1.ReadyCondition) {
+				status.markNotReady(
+					HelmReleaseNotReadyReason,
+					notReadyMessage("HelmReleaseProxy", hrp, clusterv1.ReadyCondition),
+				)
+			}
+		}
+	}
+
+	return statuses, nil
+}
+
+func notReadyMessage(kind string, obj conditions.Getter, conditionType clusterv1.ConditionType) string {
+	msg := fmt.Sprintf("%s %s is not ready", kind, obj.GetName())
+	if condMsg := conditions.GetMessage(obj, conditionType); condMsg != "" {
+		msg = fmt.Sprintf("%s: %s", msg, condMsg)
+	}
+	return msg
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonstatus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clusterv1.AddToScheme(scheme))
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+	return scheme
+}
+
+//nolint:funlen // Long tests are OK
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+		Status: clusterv1.ClusterStatus{
+			Conditions: clusterv1.Conditions{{
+				Type:   NFDReadyCondition,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+	cniHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "cilium-cni-installation-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonCNI),
+		},
+	}
+	cniHRP := &caaphv1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "cilium-cni-installation-test-cluster-abcde",
+			Labels:    map[string]string{caaphv1.HelmChartProxyLabelName: cniHCP.Name},
+		},
+		Status: caaphv1.HelmReleaseProxyStatus{
+			Conditions: clusterv1.Conditions{{
+				Type:     clusterv1.ReadyCondition,
+				Status:   corev1.ConditionFalse,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "helm install failed",
+			}},
+		},
+	}
+	csiCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "aws-ebs-csi-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonCSI),
+		},
+		Status: crsv1.ClusterResourceSetStatus{
+			Conditions: clusterv1.Conditions{{
+				Type:   crsv1.ResourcesAppliedCondition,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+	// The cluster-autoscaler addon is deployed to another namespace, e.g. of the management cluster.
+	caHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "management",
+			Name:      "cluster-autoscaler-test-cluster",
+			Labels:    utils.AddonLabels(cluster, utils.AddonClusterAutoscaler),
+		},
+	}
+	otherClusterCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "calico-cni-other-cluster",
+			Labels: utils.AddonLabels(
+				&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other-cluster"}},
+				utils.AddonCNI,
+			),
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(testScheme(t)).
+		WithObjects(cluster, cniHCP, cniHRP, csiCRS, caHCP, otherClusterCRS).
+		WithStatusSubresource(&clusterv1.Cluster{}).
+		Build()
+
+	_, err := New(client).Reconcile(
+		context.Background(),
+		ctrl.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(cluster)},
+	)
+	require.NoError(t, err)
+
+	got := &clusterv1.Cluster{}
+	require.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(cluster), got))
+
+	assert.True(t, conditions.IsFalse(got, CNIReadyCondition))
+	assert.Equal(t, HelmReleaseNotReadyReason, conditions.GetReason(got, CNIReadyCondition))
+	assert.Equal(
+		t,
+		"HelmReleaseProxy cilium-cni-installation-test-cluster-abcde is not ready: helm install failed",
+		conditions.GetMessage(got, CNIReadyCondition),
+	)
+
+	assert.True(t, conditions.IsTrue(got, CSIReadyCondition))
+
+	assert.True(t, conditions.IsFalse(got, ClusterAutoscalerReadyCondition))
+	assert.Equal(
+		t,
+		"HelmChartProxy cluster-autoscaler-test-cluster has not created a HelmReleaseProxy yet",
+		conditions.GetMessage(got, ClusterAutoscalerReadyCondition),
+	)
+
+	assert.False(t, conditions.Has(got, CCMReadyCondition))
+	// Conditions of addons that are no longer installed are removed.
+	assert.False(t, conditions.Has(got, NFDReadyCondition))
+}
+
+func TestClusterKeyFromLabels(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "c"}}
+	key, ok := clusterKeyFromLabels(utils.AddonLabels(cluster, utils.AddonCNI))
+	assert.True(t, ok)
+	assert.Equal(t, ctrlclient.ObjectKeyFromObject(cluster), key)
+
+	_, ok = clusterKeyFromLabels(map[string]string{clusterv1.ClusterNameLabel: "c"})
+	assert.False(t, ok)
+}
@@ -5,7 +5,6 @@ package calico
 
 import (
 	"context"
-	"slices"
 
 	"github.com/go-logr/logr"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -19,6 +18,7 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches/selectors"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/aws/mutation/cni"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
 )
 
@@ -95,11 +95,14 @@ func (h *calicoPatchHandler) Mutate(
 		&holderRef,
 		selectors.InfrastructureCluster(capav1.GroupVersion.Version, "AWSClusterTemplate"),
 		log,
-		mutateAWSClusterTemplateFunc(log),
+		mutateAWSClusterTemplateFunc(log, cniVar.Calico),
 	)
 }
 
-func mutateAWSClusterTemplateFunc(log logr.Logger) func(obj *capav1.AWSClusterTemplate) error {
+func mutateAWSClusterTemplateFunc(
+	log logr.Logger,
+	calico *v1alpha1.CalicoNetwork,
+) func(obj *capav1.AWSClusterTemplate) error {
 	return func(obj *capav1.AWSClusterTemplate) error {
 		log.WithValues(
 			"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
@@ -109,55 +112,69 @@ func mutateAWSClusterTemplateFunc(log logr.Logger) func(obj *capav1.AWSClusterTe
 		if obj.Spec.Template.Spec.NetworkSpec.CNI == nil {
 			obj.Spec.Template.Spec.NetworkSpec.CNI = &capav1.CNISpec{}
 		}
-		obj.Spec.Template.Spec.NetworkSpec.CNI.CNIIngressRules = addOrUpdateCNIIngressRules(
+		obj.Spec.Template.Spec.NetworkSpec.CNI.CNIIngressRules = cni.AddOrUpdateCNIIngressRules(
 			obj.Spec.Template.Spec.NetworkSpec.CNI.CNIIngressRules,
-
-			capav1.CNIIngressRule{
-				Description: "typha (calico)",
-				Protocol:    capav1.SecurityGroupProtocolTCP,
-				FromPort:    5473,
-				ToPort:      5473,
-			},
-			capav1.CNIIngressRule{
-				Description: "bgp (calico)",
-				Protocol:    capav1.SecurityGroupProtocolTCP,
-				FromPort:    179,
-				ToPort:      179,
-			},
-			capav1.CNIIngressRule{
-				Description: "IP-in-IP (calico)",
-				Protocol:    capav1.SecurityGroupProtocolIPinIP,
-				FromPort:    -1,
-				ToPort:      65535,
-			},
-			capav1.CNIIngressRule{
-				Description: "node metrics (calico)",
-				Protocol:    capav1.SecurityGroupProtocolTCP,
-				FromPort:    9091,
-				ToPort:      9091,
-			},
-			capav1.CNIIngressRule{
-				Description: "typha metrics (calico)",
-				Protocol:    capav1.SecurityGroupProtocolTCP,
-				FromPort:    9093,
-				ToPort:      9093,
-			},
+			cniIngressRules(calico)...,
 		)
 
 		return nil
 	}
 }
 
-func addOrUpdateCNIIngressRules(
-	rules []capav1.CNIIngressRule, newRules ...capav1.CNIIngressRule,
-) []capav1.CNIIngressRule {
-	clonedRules := slices.Clone(rules)
+// cniIngressRules returns the ingress rules required by Calico. The BGP rule is omitted if BGP is disabled and the
+// encapsulation rule follows the chosen encapsulation, defaulting to IP-in-IP if the encapsulation is not set.
+func cniIngressRules(calico *v1alpha1.CalicoNetwork) []capav1.CNIIngressRule {
+	if calico == nil {
+		calico = &v1alpha1.CalicoNetwork{}
+	}
 
-	for _, newRule := range newRules {
-		if !slices.Contains(clonedRules, newRule) {
-			clonedRules = append(clonedRules, newRule)
-		}
+	rules := []capav1.CNIIngressRule{{
+		Description: "typha (calico)",
+		Protocol:    capav1.SecurityGroupProtocolTCP,
+		FromPort:    5473,
+		ToPort:      5473,
+	}}
+
+	if calico.BGP == nil || *calico.BGP {
+		rules = append(rules, capav1.CNIIngressRule{
+			Description: "bgp (calico)",
+			Protocol:    capav1.SecurityGroupProtocolTCP,
+			FromPort:    179,
+			ToPort:      179,
+		})
 	}
 
-	return clonedRules
+	switch calico.Encapsulation {
+	case "", v1alpha1.CalicoEncapsulationIPIP, v1alpha1.CalicoEncapsulationIPIPCrossSubnet:
+		rules = append(rules, capav1.CNIIngressRule{
+			Description: "IP-in-IP (calico)",
+			Protocol:    capav1.SecurityGroupProtocolIPinIP,
+			FromPort:    -1,
+			ToPort:      65535,
+		})
+	case v1alpha1.CalicoEncapsulationVXLAN, v1alpha1.CalicoEncapsulationVXLANCrossSubnet:
+		rules = append(rules, capav1.CNIIngressRule{
+			Description: "VXLAN (calico)",
+			Protocol:    capav1.SecurityGroupProtocolUDP,
+			FromPort:    4789,
+			ToPort:      4789,
+		})
+	case v1alpha1.CalicoEncapsulationNone:
+		// The traffic between pods is not encapsulated and needs no additional rule.
+	}
+
+	return append(rules,
+		capav1.CNIIngressRule{
+			Description: "node metrics (calico)",
+			Protocol:    capav1.SecurityGroupProtocolTCP,
+			FromPort:    9091,
+			ToPort:      9091,
+		},
+		capav1.CNIIngressRule{
+			Description: "typha metrics (calico)",
+			Protocol:    capav1.SecurityGroupProtocolTCP,
+			FromPort:    9093,
+			ToPort:      9093,
+		},
+	)
 }
@@ -8,6 +8,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 
 	capav1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
@@ -274,6 +275,131 @@ var _ = Describe("Generate AWS Calico CNI ingress patches", func() {
 				),
 			}},
 		},
+		{
+			Name: "provider set with VXLAN encapsulation and BGP disabled",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCalico,
+						Calico: &v1alpha1.CalicoNetwork{
+							Encapsulation: v1alpha1.CalicoEncapsulationVXLAN,
+							BGP:           ptr.To(false),
+						},
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ConsistOf(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "typha (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(5473)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(5473)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "VXLAN (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4789)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4789)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "node metrics (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(9091)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(9091)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "typha metrics (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(9093)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(9093)),
+						),
+					),
+				),
+			}},
+		},
+		{
+			Name: "provider set without encapsulation",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCalico,
+						Calico: &v1alpha1.CalicoNetwork{
+							Encapsulation: v1alpha1.CalicoEncapsulationNone,
+						},
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ConsistOf(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "typha (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(5473)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(5473)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "bgp (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(179)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(179)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "node metrics (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(9091)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(9091)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "typha metrics (calico)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(9093)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(9093)),
+						),
+					),
+				),
+			}},
+		},
 	}
 
 	// create test node for each case
@@ -0,0 +1,190 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cilium
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	capav1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches/selectors"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/aws/mutation/cni"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+const (
+	// HandlerNamePatch is the name of the inject handler.
+	HandlerNamePatch = "CiliumCNIPatch"
+)
+
+type ciliumPatchHandler struct {
+	variableName      string
+	variableFieldPath []string
+}
+
+func NewPatch() *ciliumPatchHandler {
+	return newCiliumPatchHandler(
+		clusterconfig.MetaVariableName,
+		"addons",
+		v1alpha1.CNIVariableName,
+	)
+}
+
+func newCiliumPatchHandler(
+	variableName string,
+	variableFieldPath ...string,
+) *ciliumPatchHandler {
+	return &ciliumPatchHandler{
+		variableName:      variableName,
+		variableFieldPath: variableFieldPath,
+	}
+}
+
+func (h *ciliumPatchHandler) Mutate(
+	ctx context.Context,
+	obj *unstructured.Unstructured,
+	vars map[string]apiextensionsv1.JSON,
+	holderRef runtimehooksv1.HolderReference,
+	_ client.ObjectKey,
+) error {
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"holderRef", holderRef,
+	)
+
+	cniVar, found, err := variables.Get[v1alpha1.CNI](
+		vars,
+		h.variableName,
+		h.variableFieldPath...,
+	)
+	if err != nil {
+		return err
+	}
+	if !found {
+		log.V(5).Info("cni variable not defined")
+		return nil
+	}
+	if cniVar.Provider != v1alpha1.CNIProviderCilium {
+		log.V(5).
+			WithValues("cniProvider", cniVar.Provider).
+			Info("CNI provider not defined as Cilium - skipping")
+		return nil
+	}
+
+	log = log.WithValues(
+		"variableName",
+		h.variableName,
+		"variableFieldPath",
+		h.variableFieldPath,
+		"variableValue",
+		cniVar,
+	)
+
+	return patches.MutateIfApplicable(
+		obj,
+		vars,
+		&holderRef,
+		selectors.InfrastructureCluster(capav1.GroupVersion.Version, "AWSClusterTemplate"),
+		log,
+		mutateAWSClusterTemplateFunc(log, cniVar.Cilium),
+	)
+}
+
+func mutateAWSClusterTemplateFunc(
+	log logr.Logger,
+	cilium *v1alpha1.CiliumConfig,
+) func(obj *capav1.AWSClusterTemplate) error {
+	return func(obj *capav1.AWSClusterTemplate) error {
+		log.WithValues(
+			"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
+			"patchedObjectName", client.ObjectKeyFromObject(obj),
+		).Info("setting CNI ingress rules in AWS cluster spec")
+
+		if obj.Spec.Template.Spec.NetworkSpec.CNI == nil {
+			obj.Spec.Template.Spec.NetworkSpec.CNI = &capav1.CNISpec{}
+		}
+		obj.Spec.Template.Spec.NetworkSpec.CNI.CNIIngressRules = cni.AddOrUpdateCNIIngressRules(
+			obj.Spec.Template.Spec.NetworkSpec.CNI.CNIIngressRules,
+			cniIngressRules(cilium)...,
+		)
+
+		return nil
+	}
+}
+
+// cniIngressRules returns the ingress rules required by Cilium. The tunnel rule follows the chosen routing mode and
+// tunnel protocol, defaulting to a VXLAN tunnel. The WireGuard rule is always added, as transparent encryption can be
+// enabled in the Cilium values, while the ESP rule is only added with the IPsec encryption.
+func cniIngressRules(cilium *v1alpha1.CiliumConfig) []capav1.CNIIngressRule {
+	if cilium == nil {
+		cilium = &v1alpha1.CiliumConfig{}
+	}
+
+	rules := []capav1.CNIIngressRule{
+		{
+			Description: "health (cilium)",
+			Protocol:    capav1.SecurityGroupProtocolTCP,
+			FromPort:    4240,
+			ToPort:      4240,
+		},
+		{
+			// For ICMP, the ports are the ICMP type and code: echo request.
+			Description: "health ICMP (cilium)",
+			Protocol:    capav1.SecurityGroupProtocolICMP,
+			FromPort:    8,
+			ToPort:      0,
+		},
+		{
+			Description: "hubble (cilium)",
+			Protocol:    capav1.SecurityGroupProtocolTCP,
+			FromPort:    4244,
+			ToPort:      4244,
+		},
+	}
+
+	if cilium.RoutingMode != v1alpha1.CiliumRoutingModeNative {
+		switch cilium.TunnelProtocol {
+		case "", v1alpha1.CiliumTunnelProtocolVXLAN:
+			rules = append(rules, capav1.CNIIngressRule{
+				Description: "VXLAN (cilium)",
+				Protocol:    capav1.SecurityGroupProtocolUDP,
+				FromPort:    8472,
+				ToPort:      8472,
+			})
+		case v1alpha1.CiliumTunnelProtocolGeneve:
+			rules = append(rules, capav1.CNIIngressRule{
+				Description: "Geneve (cilium)",
+				Protocol:    capav1.SecurityGroupProtocolUDP,
+				FromPort:    6081,
+				ToPort:      6081,
+			})
+		}
+	}
+
+	rules = append(rules, capav1.CNIIngressRule{
+		Description: "WireGuard (cilium)",
+		Protocol:    capav1.SecurityGroupProtocolUDP,
+		FromPort:    51871,
+		ToPort:      51871,
+	})
+
+	if cilium.Encryption != nil && cilium.Encryption.Type == v1alpha1.CiliumEncryptionTypeIPsec {
+		rules = append(rules, capav1.CNIIngressRule{
+			Description: "IPsec ESP (cilium)",
+			Protocol:    capav1.SecurityGroupProtocolESP,
+			FromPort:    -1,
+			ToPort:      65535,
+		})
+	}
+
+	return rules
+}
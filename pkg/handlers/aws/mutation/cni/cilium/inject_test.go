@@ -0,0 +1,288 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cilium
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+
+	capav1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/mutation"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest/request"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+func TestCiliumPatch(t *testing.T) {
+	gomega.RegisterFailHandler(Fail)
+	RunSpecs(t, "AWS Cilium CNI ingress mutator suite")
+}
+
+var _ = Describe("Generate AWS Cilium CNI ingress patches", func() {
+	patchGenerator := func() mutation.GeneratePatches {
+		return mutation.NewMetaGeneratePatchesHandler("", NewPatch()).(mutation.GeneratePatches)
+	}
+
+	testDefs := []capitest.PatchTestDef{
+		{
+			Name: "unset variable",
+		},
+		{
+			Name: "provider set with AWSClusterTemplate",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ConsistOf(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4240)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4240)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health ICMP (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolICMP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(8)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(0)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "hubble (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4244)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4244)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "VXLAN (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(8472)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(8472)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "WireGuard (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(51871)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(51871)),
+						),
+					),
+				),
+			}},
+		},
+		{
+			Name: "provider set with Geneve tunnel",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Cilium: &v1alpha1.CiliumConfig{
+							TunnelProtocol: v1alpha1.CiliumTunnelProtocolGeneve,
+						},
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ConsistOf(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4240)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4240)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health ICMP (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolICMP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(8)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(0)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "hubble (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4244)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4244)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "Geneve (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(6081)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(6081)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "WireGuard (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(51871)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(51871)),
+						),
+					),
+				),
+			}},
+		},
+		{
+			Name: "provider set with native routing",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Cilium: &v1alpha1.CiliumConfig{
+							RoutingMode: v1alpha1.CiliumRoutingModeNative,
+						},
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ConsistOf(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4240)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4240)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "health ICMP (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolICMP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(8)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(0)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "hubble (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolTCP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(4244)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(4244)),
+						),
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "WireGuard (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolUDP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(51871)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(51871)),
+						),
+					),
+				),
+			}},
+		},
+		{
+			Name: "provider set with IPsec encryption",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Cilium: &v1alpha1.CiliumConfig{
+							Encryption: &v1alpha1.CiliumEncryption{
+								Type:              v1alpha1.CiliumEncryptionTypeIPsec,
+								IPsecKeySecretRef: &corev1.LocalObjectReference{Name: "cilium-ipsec-keys"},
+							},
+						},
+					},
+					"addons",
+					v1alpha1.CNIVariableName,
+				),
+			},
+			RequestItem: request.NewAWSClusterTemplateRequestItem("1234"),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{{
+				Operation: "add",
+				Path:      "/spec/template/spec/network/cni",
+				ValueMatcher: gomega.HaveKeyWithValue(
+					"cniIngressRules",
+					gomega.ContainElement(
+						gomega.SatisfyAll(
+							gomega.HaveKeyWithValue("description", "IPsec ESP (cilium)"),
+							gomega.HaveKeyWithValue(
+								"protocol",
+								gomega.BeEquivalentTo(capav1.SecurityGroupProtocolESP),
+							),
+							gomega.HaveKeyWithValue("fromPort", gomega.BeEquivalentTo(-1)),
+							gomega.HaveKeyWithValue("toPort", gomega.BeEquivalentTo(65535)),
+						),
+					),
+				),
+			}},
+		},
+	}
+
+	// create test node for each case
+	for testIdx := range testDefs {
+		tt := testDefs[testIdx]
+		It(tt.Name, func() {
+			capitest.AssertGeneratePatches(
+				GinkgoT(),
+				patchGenerator,
+				&tt,
+			)
+		})
+	}
+})
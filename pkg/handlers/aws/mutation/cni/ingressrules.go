@@ -0,0 +1,25 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cni
+
+import (
+	"slices"
+
+	capav1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+)
+
+// AddOrUpdateCNIIngressRules returns the rules with the new rules appended, skipping the new rules already present.
+func AddOrUpdateCNIIngressRules(
+	rules []capav1.CNIIngressRule, newRules ...capav1.CNIIngressRule,
+) []capav1.CNIIngressRule {
+	clonedRules := slices.Clone(rules)
+
+	for _, newRule := range newRules {
+		if !slices.Contains(clonedRules, newRule) {
+			clonedRules = append(clonedRules, newRule)
+		}
+	}
+
+	return clonedRules
+}
@@ -0,0 +1,14 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package addonqueue provides a work queue to install addons asynchronously. Addon lifecycle handlers are wrapped so
+// that the runtime hooks only enqueue the installation and respond immediately, instead of blocking on slow remote API
+// servers. Failed installations are retried by the queue with exponential backoff until the addon is healthy. The
+// queue is held in memory: on start, it enqueues again the installations of the addons whose Cluster condition is not
+// true.
+//
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=watch;list;get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesets,verbs=watch;list;get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=watch;list;get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies,verbs=watch;list;get
+package addonqueue
@@ -0,0 +1,101 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonqueue
+
+import (
+	"context"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+)
+
+// AddonHandler is implemented by the addon lifecycle handlers.
+type AddonHandler interface {
+	handlers.Named
+	lifecycle.AfterControlPlaneInitialized
+	lifecycle.AfterControlPlaneUpgrade
+}
+
+type asyncHandler struct {
+	handler AddonHandler
+	addon   string
+	queue   *Queue
+}
+
+var (
+	_ handlers.Named                         = &asyncHandler{}
+	_ lifecycle.AfterControlPlaneInitialized = &asyncHandler{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &asyncHandler{}
+)
+
+// Async wraps the addon handler so that its hooks enqueue the addon installation on the queue instead of applying it
+// synchronously. The addon is the value of the addon label set on the objects created by the handler, used to check
+// the health of the addon once applied.
+func Async(q *Queue, addon string, h AddonHandler) handlers.Named {
+	a := &asyncHandler{handler: h, addon: addon, queue: q}
+	q.register(a)
+	return a
+}
+
+func (a *asyncHandler) Name() string {
+	return a.handler.Name()
+}
+
+// AfterControlPlaneInitialized enqueues the addon installation. This hook is non-blocking, so it always succeeds once
+// the installation is enqueued: the queue retries the installation until the addon is healthy, and its progress is
+// reported by the addon conditions of the Cluster.
+func (a *asyncHandler) AfterControlPlaneInitialized(
+	_ context.Context,
+	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
+) {
+	status := a.enqueueInstall(&req.Cluster)
+
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	resp.SetMessage(status.Message)
+}
+
+// enqueueInstall enqueues the installation of the addon to the cluster, as done when its control plane is initialized.
+func (a *asyncHandler) enqueueInstall(cluster *clusterv1.Cluster) Status {
+	req := &runtimehooksv1.AfterControlPlaneInitializedRequest{Cluster: *cluster.DeepCopy()}
+	return a.queue.Enqueue(
+		a.Name(),
+		a.addon,
+		&req.Cluster,
+		func(ctx context.Context, commonResp *runtimehooksv1.CommonResponse) {
+			hookResp := &runtimehooksv1.AfterControlPlaneInitializedResponse{}
+			a.handler.AfterControlPlaneInitialized(ctx, req, hookResp)
+			*commonResp = hookResp.CommonResponse
+		},
+	)
+}
+
+// AfterControlPlaneUpgrade enqueues the addon installation and blocks the upgrade, by responding with
+// RetryAfterSeconds, until the addon has been applied and is healthy.
+func (a *asyncHandler) AfterControlPlaneUpgrade(
+	_ context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	req = req.DeepCopy()
+	status := a.queue.Enqueue(
+		a.Name(),
+		a.addon,
+		&req.Cluster,
+		func(ctx context.Context, commonResp *runtimehooksv1.CommonResponse) {
+			hookResp := &runtimehooksv1.AfterControlPlaneUpgradeResponse{}
+			a.handler.AfterControlPlaneUpgrade(ctx, req, hookResp)
+			*commonResp = hookResp.CommonResponse
+		},
+	)
+
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	if !status.Done {
+		resp.SetMessage(status.Message)
+		resp.SetRetryAfterSeconds(RetryAfterSeconds)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonqueue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+// NewClusterAddonsHealthCheck returns a HealthCheckFunc that reports an addon of a cluster as healthy when the
+// resources of all the ClusterResourceSets created to install it have been applied and the HelmReleaseProxies of all
+// the HelmChartProxies created to install it are ready. Only the objects labelled as installing the addon for the
+// cluster are checked, so that an addon is not held back by the other addons of the cluster. An addon is not healthy
+// while its objects are being migrated from a ClusterResourceSet to a HelmChartProxy or the reverse, so that the
+// addon is applied again until the migration completes.
+func NewClusterAddonsHealthCheck(c ctrlclient.Reader) HealthCheckFunc {
+	return func(ctx context.Context, cluster *clusterv1.Cluster, addon string) (bool, string, error) {
+		var pending []string
+
+		addonLabels := ctrlclient.MatchingLabels(utils.AddonLabels(cluster, addon))
+
+		crss := &crsv1.ClusterResourceSetList{}
+		if err := c.List(ctx, crss, addonLabels); err != nil {
+			return false, "", fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+		}
+		for i := range crss.Items {
+			crs := &crss.Items[i]
+			if migrating(crs) || !conditions.IsTrue(crs, crsv1.ResourcesAppliedCondition) {
+				pending = append(pending, fmt.Sprintf("ClusterResourceSet %s", crs.Name))
+			}
+		}
+
+		hcps := &caaphv1.HelmChartProxyList{}
+		if err := c.List(ctx, hcps, addonLabels); err != nil {
+			return false, "", fmt.Errorf("failed to list HelmChartProxies: %w", err)
+		}
+		for i := range hcps.Items {
+			hcp := &hcps.Items[i]
+			if migrating(hcp) {
+				pending = append(pending, fmt.Sprintf("HelmChartProxy %s", hcp.Name))
+				continue
+			}
+
+			hrps := &caaphv1.HelmReleaseProxyList{}
+			err := c.List(
+				ctx,
+				hrps,
+				ctrlclient.InNamespace(hcp.Namespace),
+				ctrlclient.MatchingLabels{caaphv1.HelmChartProxyLabelName: hcp.Name},
+			)
+			if err != nil {
+				return false, "", fmt.Errorf("failed to list HelmReleaseProxies: %w", err)
+			}
+			if len(hrps.Items) == 0 {
+				pending = append(pending, fmt.Sprintf("HelmChartProxy %s", hcp.Name))
+				continue
+			}
+			for j := range hrps.Items {
+				hrp := &hrps.Items[j]
+				if !conditions.IsTrue(hrp, clusterv1.ReadyCondition) {
+					pending = append(pending, fmt.Sprintf("HelmReleaseProxy %s", hrp.Name))
+				}
+			}
+		}
+
+		if len(pending) > 0 {
+			return false, fmt.Sprintf("waiting for %s addon to be ready: %s", addon, strings.Join(pending, ", ")), nil
+		}
+		return true, "", nil
+	}
+}
+
+// migrating returns whether the object is being migrated to another strategy.
+func migrating(obj ctrlclient.Object) bool {
+	_, ok := obj.GetAnnotations()[utils.AddonMigratingAnnotation]
+	return ok
+}
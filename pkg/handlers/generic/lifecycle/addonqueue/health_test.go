@@ -0,0 +1,156 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonqueue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+func TestClusterAddonsHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster("v1.29.0")
+
+	hcp := func(name, addon string) ctrlclient.Object {
+		return &caaphv1.HelmChartProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    utils.AddonLabels(cluster, addon),
+			},
+		}
+	}
+	hrp := func(name, hcpName string, ready corev1.ConditionStatus) ctrlclient.Object {
+		return &caaphv1.HelmReleaseProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel:      "test-cluster",
+					caaphv1.HelmChartProxyLabelName: hcpName,
+				},
+			},
+			Status: caaphv1.HelmReleaseProxyStatus{
+				Conditions: clusterv1.Conditions{{Type: clusterv1.ReadyCondition, Status: ready}},
+			},
+		}
+	}
+	crs := func(name, addon string, applied corev1.ConditionStatus) ctrlclient.Object {
+		return &crsv1.ClusterResourceSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels:    utils.AddonLabels(cluster, addon),
+			},
+			Status: crsv1.ClusterResourceSetStatus{
+				Conditions: clusterv1.Conditions{{Type: crsv1.ResourcesAppliedCondition, Status: applied}},
+			},
+		}
+	}
+
+	withMigrating := func(obj ctrlclient.Object) ctrlclient.Object {
+		obj.SetAnnotations(map[string]string{utils.AddonMigratingAnnotation: ""})
+		return obj
+	}
+
+	tests := []struct {
+		name            string
+		objs            []ctrlclient.Object
+		expectedHealthy bool
+		expectedMessage string
+	}{{
+		name:            "no addon objects",
+		expectedHealthy: true,
+	}, {
+		name: "addon ready",
+		objs: []ctrlclient.Object{
+			crs("calico-cni-installation-test-cluster", utils.AddonCNI, corev1.ConditionTrue),
+			hcp("cilium-cni-installation-test-cluster", utils.AddonCNI),
+			hrp("cilium-abcde", "cilium-cni-installation-test-cluster", corev1.ConditionTrue),
+		},
+		expectedHealthy: true,
+	}, {
+		name: "other addons not ready",
+		objs: []ctrlclient.Object{
+			crs("nfd-test-cluster", utils.AddonNFD, corev1.ConditionFalse),
+			hcp("nfd-test-cluster", utils.AddonNFD),
+			hrp("nfd-abcde", "nfd-test-cluster", corev1.ConditionFalse),
+		},
+		expectedHealthy: true,
+	}, {
+		name: "ClusterResourceSet resources not applied",
+		objs: []ctrlclient.Object{
+			crs("calico-cni-installation-test-cluster", utils.AddonCNI, corev1.ConditionFalse),
+		},
+		expectedHealthy: false,
+		expectedMessage: "waiting for cni addon to be ready: ClusterResourceSet calico-cni-installation-test-cluster",
+	}, {
+		name: "HelmReleaseProxy not created yet",
+		objs: []ctrlclient.Object{
+			hcp("cilium-cni-installation-test-cluster", utils.AddonCNI),
+		},
+		expectedHealthy: false,
+		expectedMessage: "waiting for cni addon to be ready: HelmChartProxy cilium-cni-installation-test-cluster",
+	}, {
+		name: "HelmReleaseProxy not ready",
+		objs: []ctrlclient.Object{
+			hcp("cilium-cni-installation-test-cluster", utils.AddonCNI),
+			hrp("cilium-abcde", "cilium-cni-installation-test-cluster", corev1.ConditionFalse),
+		},
+		expectedHealthy: false,
+		expectedMessage: "waiting for cni addon to be ready: HelmReleaseProxy cilium-abcde",
+	}, {
+		name: "ClusterResourceSet migrating to HelmChartProxy",
+		objs: []ctrlclient.Object{
+			withMigrating(crs("calico-cni-installation-test-cluster", utils.AddonCNI, corev1.ConditionTrue)),
+			hcp("calico-cni-installation-test-cluster", utils.AddonCNI),
+			hrp("calico-abcde", "calico-cni-installation-test-cluster", corev1.ConditionTrue),
+		},
+		expectedHealthy: false,
+		expectedMessage: "waiting for cni addon to be ready: ClusterResourceSet calico-cni-installation-test-cluster",
+	}, {
+		name: "HelmChartProxy migrating to ClusterResourceSet",
+		objs: []ctrlclient.Object{
+			crs("calico-cni-installation-test-cluster", utils.AddonCNI, corev1.ConditionTrue),
+			withMigrating(hcp("calico-cni-installation-test-cluster", utils.AddonCNI)),
+			hrp("calico-abcde", "calico-cni-installation-test-cluster", corev1.ConditionTrue),
+		},
+		expectedHealthy: false,
+		expectedMessage: "waiting for cni addon to be ready: HelmChartProxy calico-cni-installation-test-cluster",
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, crsv1.AddToScheme(scheme))
+			require.NoError(t, caaphv1.AddToScheme(scheme))
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+
+			healthy, message, err := NewClusterAddonsHealthCheck(client)(
+				context.Background(),
+				cluster,
+				utils.AddonCNI,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedHealthy, healthy)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+}
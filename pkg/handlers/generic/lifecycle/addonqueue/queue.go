@@ -0,0 +1,321 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/controllers/addonstatus"
+)
+
+const (
+	defaultWorkers          = 5
+	defaultApplyTimeout     = 2 * time.Minute
+	defaultHealthCheckDelay = 10 * time.Second
+
+	// RetryAfterSeconds is returned to CAPI from blocking hooks while the addon installation is in progress.
+	RetryAfterSeconds = 10
+)
+
+// ApplyFunc applies an addon to the cluster, reporting the outcome in resp.
+type ApplyFunc func(ctx context.Context, resp *runtimehooksv1.CommonResponse)
+
+// HealthCheckFunc reports whether the addon applied to the cluster is healthy. If not, the returned message describes
+// why. The addon is identified by the value of the addon label set on the objects created to install it.
+type HealthCheckFunc func(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	addon string,
+) (healthy bool, message string, err error)
+
+type itemKey struct {
+	handler string
+	cluster ctrlclient.ObjectKey
+}
+
+type itemState string
+
+const (
+	statePending   itemState = "Pending"
+	stateFailed    itemState = "Failed"
+	stateUnhealthy itemState = "Unhealthy"
+	stateDone      itemState = "Done"
+)
+
+var (
+	_ handlers.Named                = &Queue{}
+	_ lifecycle.BeforeClusterDelete = &Queue{}
+	_ manager.Runnable              = &Queue{}
+)
+
+type item struct {
+	cluster  *clusterv1.Cluster
+	addon    string
+	revision string
+	apply    ApplyFunc
+
+	state   itemState
+	message string
+}
+
+// Status is the status of an addon installation.
+type Status struct {
+	// Done is true when the addon has been applied and is healthy.
+	Done bool
+	// Message describes the status of the installation.
+	Message string
+}
+
+// Queue installs addons asynchronously. It must be added to the manager to start its workers.
+type Queue struct {
+	client      ctrlclient.Reader
+	queue       workqueue.RateLimitingInterface
+	healthCheck HealthCheckFunc
+
+	workers          int
+	applyTimeout     time.Duration
+	healthCheckDelay time.Duration
+
+	lock     sync.Mutex
+	items    map[itemKey]*item
+	handlers []*asyncHandler
+}
+
+// New returns a queue that checks the health of the installed addons with healthCheck. On start, the queue lists the
+// Clusters with c to enqueue again the installations that are not complete, if c is not nil.
+func New(c ctrlclient.Reader, healthCheck HealthCheckFunc) *Queue {
+	return &Queue{
+		client: c,
+		queue: workqueue.NewRateLimitingQueueWithConfig(
+			workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Name: "addons"},
+		),
+		healthCheck:      healthCheck,
+		workers:          defaultWorkers,
+		applyTimeout:     defaultApplyTimeout,
+		healthCheckDelay: defaultHealthCheckDelay,
+		items:            map[itemKey]*item{},
+	}
+}
+
+// Enqueue queues the installation of an addon by handler to the cluster, unless it is already queued or done for the
+// cluster's current Kubernetes version, and returns the current status of the installation.
+func (q *Queue) Enqueue(handler, addon string, cluster *clusterv1.Cluster, apply ApplyFunc) Status {
+	key := itemKey{handler: handler, cluster: ctrlclient.ObjectKeyFromObject(cluster)}
+	revision := ""
+	if cluster.Spec.Topology != nil {
+		revision = cluster.Spec.Topology.Version
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	it, ok := q.items[key]
+	if ok && it.revision == revision {
+		return it.status()
+	}
+
+	it = &item{
+		cluster:  cluster.DeepCopy(),
+		addon:    addon,
+		revision: revision,
+		apply:    apply,
+		state:    statePending,
+		message:  fmt.Sprintf("%s addon installation is queued", handler),
+	}
+	q.items[key] = it
+	q.queue.Forget(key)
+	q.queue.Add(key)
+
+	return it.status()
+}
+
+// register adds an addon handler whose installations are enqueued again on start.
+func (q *Queue) register(h *asyncHandler) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.handlers = append(q.handlers, h)
+}
+
+func (q *Queue) Name() string {
+	return "AddonQueue"
+}
+
+// BeforeClusterDelete removes all items of the cluster from the queue.
+func (q *Queue) BeforeClusterDelete(
+	_ context.Context,
+	req *runtimehooksv1.BeforeClusterDeleteRequest,
+	resp *runtimehooksv1.BeforeClusterDeleteResponse,
+) {
+	q.remove(ctrlclient.ObjectKeyFromObject(&req.Cluster))
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+}
+
+// remove removes all items of the cluster from the queue.
+func (q *Queue) remove(clusterKey ctrlclient.ObjectKey) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for key := range q.items {
+		if key.cluster == clusterKey {
+			delete(q.items, key)
+			q.queue.Forget(key)
+		}
+	}
+}
+
+func (it *item) status() Status {
+	return Status{Done: it.state == stateDone, Message: it.message}
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface. All replicas serve runtime hooks and so must
+// process the items they enqueue. The queue is not persisted: on start, the installations that are not complete are
+// enqueued again from the state of the Clusters, see requeueIncomplete.
+func (*Queue) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs the workers until the context is done.
+func (q *Queue) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("addonqueue")
+
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q.processNextItem(ctx, log) {
+			}
+		}()
+	}
+
+	if err := q.requeueIncomplete(ctx); err != nil {
+		log.Error(err, "Failed to enqueue incomplete addon installations")
+	}
+
+	<-ctx.Done()
+	q.queue.ShutDown()
+	wg.Wait()
+
+	return nil
+}
+
+// requeueIncomplete enqueues the installation of the addons whose condition is not true on the Clusters with an
+// initialized control plane. The installations enqueued by the hooks before a restart, or by another replica, are
+// thereby completed without relying on CAPI calling the hooks again. Installing an addon that is not enabled for the
+// cluster is a no-op.
+func (q *Queue) requeueIncomplete(ctx context.Context) error {
+	if q.client == nil {
+		return nil
+	}
+
+	clusters := &clusterv1.ClusterList{}
+	if err := q.client.List(ctx, clusters); err != nil {
+		return fmt.Errorf("failed to list Clusters: %w", err)
+	}
+
+	q.lock.Lock()
+	addonHandlers := q.handlers
+	q.lock.Unlock()
+
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		if !cluster.DeletionTimestamp.IsZero() ||
+			!conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+			continue
+		}
+		for _, h := range addonHandlers {
+			if conditionType, ok := addonstatus.AddonCondition(h.addon); ok && conditions.IsTrue(cluster, conditionType) {
+				continue
+			}
+			h.enqueueInstall(cluster)
+		}
+	}
+
+	return nil
+}
+
+func (q *Queue) processNextItem(ctx context.Context, log logr.Logger) bool {
+	obj, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(obj)
+
+	key := obj.(itemKey)
+
+	q.lock.Lock()
+	it, ok := q.items[key]
+	q.lock.Unlock()
+	if !ok {
+		q.queue.Forget(key)
+		return true
+	}
+
+	log = log.WithValues("handler", key.handler, "cluster", key.cluster)
+	state, message := q.process(ctrl.LoggerInto(ctx, log), it)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	// The item may have been replaced by a newer revision while it was being processed, in which case the newer
+	// revision has already been queued.
+	if q.items[key] != it {
+		return true
+	}
+	it.state = state
+	it.message = message
+
+	switch state {
+	case stateDone:
+		log.V(4).Info("Addon installed")
+		q.queue.Forget(key)
+	case stateUnhealthy:
+		q.queue.AddAfter(key, q.healthCheckDelay)
+	default:
+		log.Info("Addon installation failed, will retry", "message", message)
+		q.queue.AddRateLimited(key)
+	}
+
+	return true
+}
+
+func (q *Queue) process(ctx context.Context, it *item) (itemState, string) {
+	ctx, cancel := context.WithTimeout(ctx, q.applyTimeout)
+	defer cancel()
+
+	resp := &runtimehooksv1.CommonResponse{}
+	it.apply(ctx, resp)
+	if resp.GetStatus() == runtimehooksv1.ResponseStatusFailure {
+		return stateFailed, resp.GetMessage()
+	}
+
+	if q.healthCheck == nil {
+		return stateDone, ""
+	}
+
+	healthy, message, err := q.healthCheck(ctx, it.cluster, it.addon)
+	switch {
+	case err != nil:
+		return stateFailed, fmt.Sprintf("failed to check addons health: %v", err)
+	case !healthy:
+		return stateUnhealthy, message
+	default:
+		return stateDone, ""
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package addonqueue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/controllers/addonstatus"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+func testCluster(version string) *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "test-cluster",
+		},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{Version: version},
+		},
+	}
+}
+
+func startQueue(t *testing.T, q *Queue) {
+	t.Helper()
+
+	q.healthCheckDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, q.Start(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestQueueRetriesFailedApply(t *testing.T) {
+	t.Parallel()
+
+	q := New(nil, nil)
+	startQueue(t, q)
+
+	var calls atomic.Int32
+	apply := func(_ context.Context, resp *runtimehooksv1.CommonResponse) {
+		if calls.Add(1) < 3 {
+			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+			resp.SetMessage("defaults ConfigMap not found")
+			return
+		}
+		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	}
+
+	cluster := testCluster("v1.29.0")
+	status := q.Enqueue("TestHandler", "test", cluster, apply)
+	assert.False(t, status.Done)
+
+	require.Eventually(t, func() bool {
+		return q.Enqueue("TestHandler", "test", cluster, apply).Done
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 3, calls.Load())
+
+	// Enqueuing again for the same Kubernetes version does not reapply the addon.
+	assert.True(t, q.Enqueue("TestHandler", "test", cluster, apply).Done)
+	assert.EqualValues(t, 3, calls.Load())
+
+	// Enqueuing for a new Kubernetes version reapplies the addon.
+	assert.False(t, q.Enqueue("TestHandler", "test", testCluster("v1.30.0"), apply).Done)
+	require.Eventually(t, func() bool {
+		return calls.Load() == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQueueWaitsForHealthyAddons(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	q := New(nil, func(_ context.Context, _ *clusterv1.Cluster, addon string) (bool, string, error) {
+		return healthy.Load(), "waiting for " + addon + " addon to be ready: HelmReleaseProxy cilium", nil
+	})
+	startQueue(t, q)
+
+	apply := func(_ context.Context, resp *runtimehooksv1.CommonResponse) {
+		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	}
+	cluster := testCluster("v1.29.0")
+	q.Enqueue("TestHandler", "test", cluster, apply)
+
+	require.Eventually(t, func() bool {
+		return q.Enqueue("TestHandler", "test", cluster, apply).Message ==
+			"waiting for test addon to be ready: HelmReleaseProxy cilium"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, q.Enqueue("TestHandler", "test", cluster, apply).Done)
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool {
+		return q.Enqueue("TestHandler", "test", cluster, apply).Done
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+type testAddonHandler struct {
+	status  runtimehooksv1.ResponseStatus
+	message string
+}
+
+func (h *testAddonHandler) Name() string {
+	return "TestAddonHandler"
+}
+
+func (h *testAddonHandler) AfterControlPlaneInitialized(
+	_ context.Context,
+	_ *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
+) {
+	resp.SetStatus(h.status)
+	resp.SetMessage(h.message)
+}
+
+func (h *testAddonHandler) AfterControlPlaneUpgrade(
+	_ context.Context,
+	_ *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	resp.SetStatus(h.status)
+}
+
+func TestAsyncAfterControlPlaneUpgrade(t *testing.T) {
+	t.Parallel()
+
+	q := New(nil, nil)
+	startQueue(t, q)
+
+	h := Async(q, "test", &testAddonHandler{status: runtimehooksv1.ResponseStatusSuccess}).(*asyncHandler)
+	assert.Equal(t, "TestAddonHandler", h.Name())
+
+	req := &runtimehooksv1.AfterControlPlaneUpgradeRequest{
+		Cluster:           *testCluster("v1.30.0"),
+		KubernetesVersion: "v1.30.0",
+	}
+
+	resp := &runtimehooksv1.AfterControlPlaneUpgradeResponse{}
+	h.AfterControlPlaneUpgrade(context.Background(), req, resp)
+	assert.Equal(t, runtimehooksv1.ResponseStatusSuccess, resp.GetStatus())
+	assert.EqualValues(t, RetryAfterSeconds, resp.GetRetryAfterSeconds())
+
+	require.Eventually(t, func() bool {
+		resp := &runtimehooksv1.AfterControlPlaneUpgradeResponse{}
+		h.AfterControlPlaneUpgrade(context.Background(), req, resp)
+		return resp.GetRetryAfterSeconds() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAsyncAfterControlPlaneInitializedSucceedsOnceEnqueued(t *testing.T) {
+	t.Parallel()
+
+	q := New(nil, nil)
+	h := Async(q, "test", &testAddonHandler{status: runtimehooksv1.ResponseStatusSuccess}).(*asyncHandler)
+	req := &runtimehooksv1.AfterControlPlaneInitializedRequest{Cluster: *testCluster("v1.29.0")}
+
+	resp := &runtimehooksv1.AfterControlPlaneInitializedResponse{}
+	h.AfterControlPlaneInitialized(context.Background(), req, resp)
+	assert.Equal(t, runtimehooksv1.ResponseStatusSuccess, resp.GetStatus())
+	assert.Equal(t, "TestAddonHandler addon installation is queued", resp.GetMessage())
+
+	startQueue(t, q)
+	require.Eventually(t, func() bool {
+		return h.enqueueInstall(testCluster("v1.29.0")).Done
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQueueRequeuesIncompleteInstallationsOnStart(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clusterv1.AddToScheme(scheme))
+
+	initialized := clusterv1.Condition{
+		Type:   clusterv1.ControlPlaneInitializedCondition,
+		Status: corev1.ConditionTrue,
+	}
+	pending := testCluster("v1.29.0")
+	pending.Name = "pending"
+	pending.Status.Conditions = clusterv1.Conditions{
+		initialized,
+		{Type: addonstatus.CNIReadyCondition, Status: corev1.ConditionFalse},
+	}
+	ready := testCluster("v1.29.0")
+	ready.Name = "ready"
+	ready.Status.Conditions = clusterv1.Conditions{
+		initialized,
+		{Type: addonstatus.CNIReadyCondition, Status: corev1.ConditionTrue},
+	}
+	uninitialized := testCluster("v1.29.0")
+	uninitialized.Name = "uninitialized"
+
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pending, ready, uninitialized).Build()
+
+	q := New(client, nil)
+	var (
+		lock    sync.Mutex
+		applied []string
+	)
+	Async(q, utils.AddonCNI, &recordingAddonHandler{applied: func(name string) {
+		lock.Lock()
+		defer lock.Unlock()
+		applied = append(applied, name)
+	}})
+	startQueue(t, q)
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(applied) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	// Give the queue the chance to process any other, unexpected, installation.
+	time.Sleep(100 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"pending"}, applied)
+}
+
+type recordingAddonHandler struct {
+	testAddonHandler
+	applied func(cluster string)
+}
+
+func (h *recordingAddonHandler) AfterControlPlaneInitialized(
+	_ context.Context,
+	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
+) {
+	h.applied(req.Cluster.Name)
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+}
+
+func TestAsyncAfterControlPlaneInitializedReportsProgress(t *testing.T) {
+	t.Parallel()
+
+	q := New(nil, nil)
+	startQueue(t, q)
+	h := Async(q, "test", &testAddonHandler{
+		status:  runtimehooksv1.ResponseStatusFailure,
+		message: "defaults ConfigMap not found",
+	}).(*asyncHandler)
+	req := &runtimehooksv1.AfterControlPlaneInitializedRequest{Cluster: *testCluster("v1.29.0")}
+
+	require.Eventually(t, func() bool {
+		resp := &runtimehooksv1.AfterControlPlaneInitializedResponse{}
+		h.AfterControlPlaneInitialized(context.Background(), req, resp)
+		return resp.GetStatus() == runtimehooksv1.ResponseStatusSuccess &&
+			resp.GetMessage() == "defaults ConfigMap not found"
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -4,27 +4,44 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"text/template"
 
 	"github.com/blang/semver/v4"
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
 	lifecycleutils "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
+const (
+	defaultHelmReleaseName      = "aws-cloud-controller-manager"
+	defaultHelmReleaseNamespace = "kube-system"
+)
+
+// ErrUnsupportedKubernetesVersion is returned when there is no default AWS CCM configured for the minor version of a
+// Kubernetes version.
+var ErrUnsupportedKubernetesVersion = errors.New("no default AWS CCM configured for Kubernetes version")
+
 type AWSCCMConfig struct {
 	*options.GlobalOptions
 
 	kubernetesMinorVersionToCCMConfigMapNames map[string]string
+	defaultValuesTemplateConfigMapName        string
 }
 
 func (a *AWSCCMConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
@@ -37,39 +54,79 @@ func (a *AWSCCMConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 		},
 		"map of provider cluster implementation type to default installation ConfigMap name",
 	)
+	flags.StringVar(
+		&a.defaultValuesTemplateConfigMapName,
+		prefix+".default-values-template-configmap-name",
+		"default-aws-ccm-helm-values-template",
+		"default values ConfigMap name",
+	)
+}
+
+// ConfigMapNameForKubernetesVersion returns the name of the default AWS CCM ConfigMap for the minor version of the
+// given Kubernetes version.
+func (a *AWSCCMConfig) ConfigMapNameForKubernetesVersion(kubernetesVersion string) (string, error) {
+	version, err := semver.ParseTolerant(kubernetesVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Kubernetes version %q: %w", kubernetesVersion, err)
+	}
+	minorVersion := fmt.Sprintf("%d.%d", version.Major, version.Minor)
+	configMapName, ok := a.kubernetesMinorVersionToCCMConfigMapNames[minorVersion]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnsupportedKubernetesVersion, minorVersion)
+	}
+	return configMapName, nil
 }
 
 type AWSCCM struct {
-	client ctrlclient.Client
-	config *AWSCCMConfig
+	client              ctrlclient.Client
+	config              *AWSCCMConfig
+	helmChartInfoGetter *config.HelmChartGetter
 }
 
 func New(
 	c ctrlclient.Client,
 	cfg *AWSCCMConfig,
+	helmChartInfoGetter *config.HelmChartGetter,
 ) *AWSCCM {
 	return &AWSCCM{
-		client: c,
-		config: cfg,
+		client:              c,
+		config:              cfg,
+		helmChartInfoGetter: helmChartInfoGetter,
 	}
 }
 
 func (a *AWSCCM) Apply(
 	ctx context.Context,
 	cluster *clusterv1.Cluster,
-	_ *v1alpha1.ClusterConfigSpec,
+	clusterConfig *v1alpha1.ClusterConfigSpec,
+) error {
+	// No need to check for nil values in the struct, this function will only be called if CCM is not nil
+	ccm := clusterConfig.Addons.CCM
+
+	strategy := ccm.Strategy
+	switch strategy {
+	case v1alpha1.AddonStrategyClusterResourceSet, "":
+		return a.handleCRSApply(ctx, cluster)
+	case v1alpha1.AddonStrategyHelmAddon:
+		return a.handleHelmAddonApply(ctx, cluster, ccm.Values)
+	default:
+		return fmt.Errorf("stategy %s not implemented", strategy)
+	}
+}
+
+func (a *AWSCCM) handleCRSApply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
 ) error {
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		cluster.Name,
 	)
 	log.Info("Creating AWS CCM ConfigMap for Cluster")
-	version, err := semver.ParseTolerant(cluster.Spec.Topology.Version)
+	configMapForMinorVersion, err := a.config.ConfigMapNameForKubernetesVersion(cluster.Spec.Topology.Version)
 	if err != nil {
-		return fmt.Errorf("failed to parse version from cluster %w", err)
+		return err
 	}
-	minorVersion := fmt.Sprintf("%d.%d", version.Major, version.Minor)
-	configMapForMinorVersion := a.config.kubernetesMinorVersionToCCMConfigMapNames[minorVersion]
 	ccmConfigMapForMinorVersion := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: a.config.DefaultsNamespace(),
@@ -90,6 +147,10 @@ func (a *AWSCCM) Apply(
 	}
 
 	ccmConfigMap := generateCCMConfigMapForCluster(ccmConfigMapForMinorVersion, cluster)
+	if err = lifecycleutils.RewriteConfigMapImagesForMirror(cluster, ccmConfigMap); err != nil {
+		return fmt.Errorf("failed to rewrite AWS CCM manifests ConfigMap for registry mirror: %w", err)
+	}
+
 	if err = client.ServerSideApply(ctx, a.client, ccmConfigMap); err != nil {
 		log.Error(err, "failed to apply CCM configmap for cluster")
 		return fmt.Errorf(
@@ -103,12 +164,152 @@ func (a *AWSCCM) Apply(
 		ccmConfigMap.Name,
 		a.client,
 		cluster,
+		lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCCM),
 		ccmConfigMap,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to generate CCM CRS for cluster: %w", err)
 	}
 
+	// After a Kubernetes upgrade the CRS for the previous minor version would continue to reconcile the old CCM
+	// manifests, so remove the resources for all other minor versions.
+	if err = a.deleteCCMResourcesForOtherVersions(ctx, cluster, configMapForMinorVersion); err != nil {
+		return fmt.Errorf("failed to delete AWS CCM resources for previous Kubernetes versions: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AWSCCM) handleHelmAddonApply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	valuesOverride *v1alpha1.AddonValues,
+) error {
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		ctrlclient.ObjectKeyFromObject(cluster),
+	)
+
+	helmChart, err := a.helmChartInfoGetter.For(ctx, log, config.AWSCCM, cluster.Spec.Topology.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get values for aws-ccm-config %w", err)
+	}
+
+	valuesTemplateConfigMap, err := lifecycleutils.RetrieveValuesTemplateConfigMap(
+		ctx,
+		a.client,
+		a.config.defaultValuesTemplateConfigMapName,
+		a.config.DefaultsNamespace(),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to retrieve AWS CCM installation values template ConfigMap for cluster: %w",
+			err,
+		)
+	}
+
+	// The ConfigMap contains the Helm values, templated with the image tag configured for the chart, if any.
+	values, err := templateValues(helmChart.ImageTag, valuesTemplateConfigMap.Data[lifecycleutils.ValuesConfigMapKey])
+	if err != nil {
+		return fmt.Errorf("failed to template Helm values read from ConfigMap: %w", err)
+	}
+	values, err = lifecycleutils.ApplyValuesOverride(ctx, a.client, cluster, values, valuesOverride)
+	if err != nil {
+		return fmt.Errorf("failed to apply AWS CCM installation values override: %w", err)
+	}
+
+	hcp := &caaphv1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: caaphv1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      "aws-ccm-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCCM),
+		},
+		Spec: caaphv1.HelmChartProxySpec{
+			RepoURL:   helmChart.Repository,
+			ChartName: helmChart.Name,
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			ReleaseNamespace: defaultHelmReleaseNamespace,
+			ReleaseName:      defaultHelmReleaseName,
+			Version:          helmChart.Version,
+			Credentials:      helmChart.Credentials(),
+			TLSConfig:        helmChart.TLSConfig(),
+			ValuesTemplate:   values,
+		},
+	}
+
+	if err = controllerutil.SetOwnerReference(cluster, hcp, a.client.Scheme()); err != nil {
+		return fmt.Errorf(
+			"failed to set owner reference on aws-ccm installation HelmChartProxy: %w",
+			err,
+		)
+	}
+
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, a.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite aws-ccm installation HelmChartProxy for registry mirror: %w", err)
+	}
+
+	if err = client.ServerSideApply(ctx, a.client, hcp); err != nil {
+		return fmt.Errorf("failed to apply aws-ccm installation HelmChartProxy: %w", err)
+	}
+
+	return nil
+}
+
+func templateValues(imageTag, text string) (string, error) {
+	helmValuesTemplate, err := template.New("").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Helm values template: %w", err)
+	}
+
+	type input struct {
+		ImageTag string
+	}
+
+	var b bytes.Buffer
+	err = helmValuesTemplate.Execute(&b, input{ImageTag: imageTag})
+	if err != nil {
+		return "", fmt.Errorf("failed setting image tag in template: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+func (a *AWSCCM) deleteCCMResourcesForOtherVersions(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	currentConfigMapName string,
+) error {
+	for _, configMapName := range a.config.kubernetesMinorVersionToCCMConfigMapNames {
+		if configMapName == currentConfigMapName {
+			continue
+		}
+
+		objMeta := metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      fmt.Sprintf("%s-%s", configMapName, cluster.Name),
+		}
+		objs := []ctrlclient.Object{
+			&crsv1.ClusterResourceSet{ObjectMeta: objMeta},
+			&corev1.ConfigMap{ObjectMeta: objMeta},
+		}
+		for _, obj := range objs {
+			if err := a.client.Delete(ctx, obj); ctrlclient.IgnoreNotFound(err) != nil {
+				return fmt.Errorf(
+					"failed to delete %T %s: %w",
+					obj,
+					ctrlclient.ObjectKeyFromObject(obj),
+					err,
+				)
+			}
+		}
+	}
+
 	return nil
 }
 
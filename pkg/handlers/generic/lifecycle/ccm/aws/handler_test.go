@@ -4,12 +4,20 @@
 package aws
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var startAWSCCMConfigMap = `
@@ -123,3 +131,121 @@ func Test_generateCCMConfigMapForCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestAWSCCMConfig_ConfigMapNameForKubernetesVersion(t *testing.T) {
+	cfg := &AWSCCMConfig{
+		kubernetesMinorVersionToCCMConfigMapNames: map[string]string{
+			"1.27": "aws-ccm-v1.27.1",
+			"1.28": "aws-ccm-v1.28.1",
+		},
+	}
+
+	tests := []struct {
+		name              string
+		kubernetesVersion string
+		expected          string
+		expectedErr       error
+	}{{
+		name:              "supported minor version",
+		kubernetesVersion: "v1.28.7",
+		expected:          "aws-ccm-v1.28.1",
+	}, {
+		name:              "unsupported minor version",
+		kubernetesVersion: "v1.29.2",
+		expectedErr:       ErrUnsupportedKubernetesVersion,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, err := cfg.ConfigMapNameForKubernetesVersion(test.kubernetesVersion)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v. got: %v", test.expectedErr, err)
+			}
+			if name != test.expected {
+				t.Errorf("expected configmap name to be %s. got: %s", test.expected, name)
+			}
+		})
+	}
+}
+
+func TestAWSCCM_deleteCCMResourcesForOtherVersions(t *testing.T) {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-cool-aws-cluster",
+			Namespace: "default",
+		},
+	}
+	objMeta := func(configMapName string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", configMapName, cluster.Name),
+			Namespace: cluster.Namespace,
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := crsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&crsv1.ClusterResourceSet{ObjectMeta: objMeta("aws-ccm-v1.27.1")},
+		&corev1.ConfigMap{ObjectMeta: objMeta("aws-ccm-v1.27.1")},
+		&crsv1.ClusterResourceSet{ObjectMeta: objMeta("aws-ccm-v1.28.1")},
+		&corev1.ConfigMap{ObjectMeta: objMeta("aws-ccm-v1.28.1")},
+	).Build()
+
+	a := New(c, &AWSCCMConfig{
+		kubernetesMinorVersionToCCMConfigMapNames: map[string]string{
+			"1.27": "aws-ccm-v1.27.1",
+			"1.28": "aws-ccm-v1.28.1",
+			"1.29": "aws-ccm-v1.29.0",
+		},
+	}, nil)
+	if err := a.deleteCCMResourcesForOtherVersions(context.Background(), cluster, "aws-ccm-v1.28.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for configMapName, shouldExist := range map[string]bool{
+		"aws-ccm-v1.27.1": false,
+		"aws-ccm-v1.28.1": true,
+	} {
+		meta := objMeta(configMapName)
+		for _, obj := range []ctrlclient.Object{&crsv1.ClusterResourceSet{}, &corev1.ConfigMap{}} {
+			err := c.Get(context.Background(), ctrlclient.ObjectKey{Namespace: meta.Namespace, Name: meta.Name}, obj)
+			if shouldExist && err != nil {
+				t.Errorf("expected %T %s to exist. got: %v", obj, meta.Name, err)
+			}
+			if !shouldExist && !apierrors.IsNotFound(err) {
+				t.Errorf("expected %T %s to be deleted. got: %v", obj, meta.Name, err)
+			}
+		}
+	}
+}
+
+func Test_templateValues(t *testing.T) {
+	valuesTemplate := "{{- with .ImageTag }}\nimage:\n  tag: {{ . }}\n{{- end }}\nargs:\n  - --v=2\n"
+	tests := []struct {
+		name     string
+		imageTag string
+		expected string
+	}{{
+		name:     "image tag configured",
+		imageTag: "v1.28.1",
+		expected: "\nimage:\n  tag: v1.28.1\nargs:\n  - --v=2\n",
+	}, {
+		name:     "chart default image tag",
+		expected: "\nargs:\n  - --v=2\n",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := templateValues(test.imageTag, valuesTemplate)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if values != test.expected {
+				t.Errorf("unexpected values: %q", values)
+			}
+		})
+	}
+}
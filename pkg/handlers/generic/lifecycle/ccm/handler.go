@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strings"
 
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -18,6 +19,7 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 )
 
 const (
@@ -30,6 +32,7 @@ type CCMProvider interface {
 
 type CCMHandler struct {
 	client          ctrlclient.Client
+	recorder        record.EventRecorder
 	variableName    string
 	variablePath    []string
 	ProviderHandler map[string]CCMProvider
@@ -38,14 +41,17 @@ type CCMHandler struct {
 var (
 	_ commonhandlers.Named                   = &CCMHandler{}
 	_ lifecycle.AfterControlPlaneInitialized = &CCMHandler{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &CCMHandler{}
 )
 
 func New(
 	c ctrlclient.Client,
+	recorder record.EventRecorder,
 	handlers map[string]CCMProvider,
 ) *CCMHandler {
 	return &CCMHandler{
 		client:          c,
+		recorder:        recorder,
 		variableName:    clusterconfig.MetaVariableName,
 		variablePath:    []string{"addons", variableRootName},
 		ProviderHandler: handlers,
@@ -61,14 +67,30 @@ func (c *CCMHandler) AfterControlPlaneInitialized(
 	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
 	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CCMHandler) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CCMHandler) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
 
-	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
 
 	_, found, err := variables.Get[v1alpha1.CCM](varMap, c.variableName, c.variablePath...)
 	if err != nil {
@@ -86,6 +108,7 @@ func (c *CCMHandler) AfterControlPlaneInitialized(
 	}
 	if !found {
 		log.V(4).Info("Skipping CCM handler.")
+		events.AddonSkipped(c.recorder, cluster, "CCM", "cluster does not specify a CCM addon")
 		return
 	}
 
@@ -107,7 +130,7 @@ func (c *CCMHandler) AfterControlPlaneInitialized(
 		return
 	}
 
-	infraKind := req.Cluster.Spec.InfrastructureRef.Kind
+	infraKind := cluster.Spec.InfrastructureRef.Kind
 	log.Info(fmt.Sprintf("finding CCM handler for %s", infraKind))
 	var handler CCMProvider
 	switch {
@@ -117,15 +140,22 @@ func (c *CCMHandler) AfterControlPlaneInitialized(
 		handler = c.ProviderHandler[v1alpha1.CCMProviderNutanix]
 	default:
 		log.Info(fmt.Sprintf("No CCM handler provided for infra kind %s", infraKind))
+		events.AddonSkipped(
+			c.recorder,
+			cluster,
+			"CCM",
+			fmt.Sprintf("no CCM handler provided for infrastructure kind %s", infraKind),
+		)
 		return
 	}
 
-	err = handler.Apply(ctx, &req.Cluster, &clusterConfigVar)
+	err = handler.Apply(ctx, cluster, &clusterConfigVar)
 	if err != nil {
 		log.Error(
 			err,
 			"failed to deploy CCM for cluster",
 		)
+		events.AddonDeploymentFailed(c.recorder, cluster, "CCM", err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(
 			fmt.Sprintf("failed to deploy CCM for cluster: %v",
@@ -134,4 +164,6 @@ func (c *CCMHandler) AfterControlPlaneInitialized(
 		)
 		return
 	}
+
+	events.AddonDeployed(c.recorder, cluster, "CCM")
 }
@@ -119,17 +119,27 @@ func (p *provider) Apply(
 		"cluster",
 		ctrlclient.ObjectKeyFromObject(cluster),
 	)
-	helmChart, err := p.helmChartInfoGetter.For(ctx, log, config.NutanixCCM)
+	helmChart, err := p.helmChartInfoGetter.For(ctx, log, config.NutanixCCM, cluster.Spec.Topology.Version)
 	if err != nil {
 		return fmt.Errorf("failed to get values for nutanix-ccm-config %w", err)
 	}
 
-	values := valuesTemplateConfigMap.Data["values.yaml"]
+	values := valuesTemplateConfigMap.Data[lifecycleutils.ValuesConfigMapKey]
 	// The configMap will contain the Helm values, but templated with fields that need to be filled in.
 	values, err = templateValues(clusterConfig, values)
 	if err != nil {
 		return fmt.Errorf("failed to template Helm values read from ConfigMap: %w", err)
 	}
+	values, err = lifecycleutils.MergeValuesOverride(
+		ctx,
+		p.client,
+		cluster,
+		values,
+		clusterConfig.Addons.CCM.Values,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply Nutanix CCM installation values override: %w", err)
+	}
 
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
@@ -139,6 +149,7 @@ func (p *provider) Apply(
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
 			Name:      "nutanix-ccm-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCCM),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   helmChart.Repository,
@@ -149,6 +160,8 @@ func (p *provider) Apply(
 			ReleaseNamespace: defaultHelmReleaseNamespace,
 			ReleaseName:      defaultHelmReleaseName,
 			Version:          helmChart.Version,
+			Credentials:      helmChart.Credentials(),
+			TLSConfig:        helmChart.TLSConfig(),
 			ValuesTemplate:   values,
 		},
 	}
@@ -160,6 +173,10 @@ func (p *provider) Apply(
 		)
 	}
 
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, p.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite nutanix-ccm installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err = client.ServerSideApply(ctx, p.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply nutanix-ccm installation HelmChartProxy: %w", err)
 	}
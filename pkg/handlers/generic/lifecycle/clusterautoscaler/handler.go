@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,13 +21,14 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
 type addonStrategy interface {
 	apply(
 		context.Context,
-		*runtimehooksv1.AfterControlPlaneInitializedRequest,
+		*clusterv1.Cluster,
 		string,
 		logr.Logger,
 	) error
@@ -45,6 +48,7 @@ func (c *Config) AddFlags(prefix string, flags *pflag.FlagSet) {
 
 type DefaultClusterAutoscaler struct {
 	client              ctrlclient.Client
+	recorder            record.EventRecorder
 	config              *Config
 	helmChartInfoGetter *config.HelmChartGetter
 
@@ -55,15 +59,18 @@ type DefaultClusterAutoscaler struct {
 var (
 	_ commonhandlers.Named                   = &DefaultClusterAutoscaler{}
 	_ lifecycle.AfterControlPlaneInitialized = &DefaultClusterAutoscaler{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &DefaultClusterAutoscaler{}
 )
 
 func New(
 	c ctrlclient.Client,
+	recorder record.EventRecorder,
 	cfg *Config,
 	helmChartInfoGetter *config.HelmChartGetter,
 ) *DefaultClusterAutoscaler {
 	return &DefaultClusterAutoscaler{
 		client:              c,
+		recorder:            recorder,
 		config:              cfg,
 		helmChartInfoGetter: helmChartInfoGetter,
 		variableName:        clusterconfig.MetaVariableName,
@@ -80,14 +87,30 @@ func (n *DefaultClusterAutoscaler) AfterControlPlaneInitialized(
 	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
 	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	n.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (n *DefaultClusterAutoscaler) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	n.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (n *DefaultClusterAutoscaler) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
 
-	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
 
 	cniVar, found, err := variables.Get[v1alpha1.ClusterAutoscaler](
 		varMap,
@@ -110,6 +133,7 @@ func (n *DefaultClusterAutoscaler) AfterControlPlaneInitialized(
 		log.Info(
 			"Skipping cluster-autoscaler handler, cluster does not specify request cluster-autoscaler addon deployment",
 		)
+		events.AddonSkipped(n.recorder, cluster, "cluster-autoscaler", "cluster does not specify a cluster-autoscaler addon")
 		return
 	}
 
@@ -125,12 +149,14 @@ func (n *DefaultClusterAutoscaler) AfterControlPlaneInitialized(
 			ctx,
 			log,
 			config.Autoscaler,
+			cluster.Spec.Topology.Version,
 		)
 		if err != nil {
 			log.Error(
 				err,
 				"failed to get configmap with helm settings",
 			)
+			events.AddonDeploymentFailed(n.recorder, cluster, "cluster-autoscaler", err)
 			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 			resp.SetMessage(
 				fmt.Sprintf("failed to get config to create helm addon: %v",
@@ -143,8 +169,16 @@ func (n *DefaultClusterAutoscaler) AfterControlPlaneInitialized(
 			config:    n.config.helmAddonConfig,
 			client:    n.client,
 			helmChart: helmChart,
+			values:    cniVar.Values,
+			crsName:   n.config.crsConfig.crsName(cluster),
 		}
 	default:
+		events.AddonDeploymentFailed(
+			n.recorder,
+			cluster,
+			"cluster-autoscaler",
+			fmt.Errorf("unknown addon deployment strategy %q", cniVar.Strategy),
+		)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(
 			fmt.Sprintf("unknown cluster-autoscaler addon deployment strategy %q", cniVar.Strategy),
@@ -152,11 +186,13 @@ func (n *DefaultClusterAutoscaler) AfterControlPlaneInitialized(
 		return
 	}
 
-	if err = strategy.apply(ctx, req, n.config.DefaultsNamespace(), log); err != nil {
+	if err = strategy.apply(ctx, cluster, n.config.DefaultsNamespace(), log); err != nil {
+		events.AddonDeploymentFailed(n.recorder, cluster, "cluster-autoscaler", err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(err.Error())
 		return
 	}
 
+	events.AddonDeployed(n.recorder, cluster, "cluster-autoscaler")
 	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 }
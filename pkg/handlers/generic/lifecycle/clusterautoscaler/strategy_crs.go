@@ -11,11 +11,11 @@ import (
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"sigs.k8s.io/cluster-api/controllers/remote"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 )
 
@@ -41,6 +41,11 @@ func (c *crsConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 	)
 }
 
+// crsName returns the name of the ConfigMap and ClusterResourceSet installing cluster-autoscaler for the cluster.
+func (c *crsConfig) crsName(cluster *clusterv1.Cluster) string {
+	return c.defaultClusterAutoscalerConfigMap + "-" + cluster.Name
+}
+
 type crsStrategy struct {
 	config crsConfig
 
@@ -49,7 +54,7 @@ type crsStrategy struct {
 
 func (s crsStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -75,8 +80,6 @@ func (s crsStrategy) apply(
 
 	log.Info("Ensuring cluster-autoscaler ConfigMap exists for cluster")
 
-	cluster := &req.Cluster
-
 	data := templateData(defaultCM.Data, cluster.Name, cluster.Namespace)
 	cm := &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
@@ -85,18 +88,11 @@ func (s crsStrategy) apply(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      defaultCM.Name + "-" + cluster.Name,
+			Name:      s.config.crsName(cluster),
 		},
 		Data: data,
 	}
 
-	if err := client.ServerSideApply(ctx, s.client, cm); err != nil {
-		return fmt.Errorf(
-			"failed to apply cluster-autoscaler installation ConfigMap: %w",
-			err,
-		)
-	}
-
 	// The cluster-autoscaler is different from other addons.
 	// It requires all resources to be created in the management cluster,
 	// which means creating the ClusterResourceSet always targeting the management cluster.
@@ -105,12 +101,24 @@ func (s crsStrategy) apply(
 		return err
 	}
 
+	// The images are pulled by the nodes of the cluster the ClusterResourceSet targets.
+	if err := utils.RewriteConfigMapImagesForMirror(targetCluster, cm); err != nil {
+		return fmt.Errorf("failed to rewrite cluster-autoscaler installation ConfigMap for registry mirror: %w", err)
+	}
+
+	if err := client.ServerSideApply(ctx, s.client, cm); err != nil {
+		return fmt.Errorf(
+			"failed to apply cluster-autoscaler installation ConfigMap: %w",
+			err,
+		)
+	}
+
 	// In the case when existingManagementCluster is nil, i.e. when s.client points to a bootstrap cluster,
 	// it is possible that the namespace where the cluster will be moved to, and where the cluster-autoscaler resources
 	// will be created, does not exist yet.
 	// In that case, we need to create the namespace in the target cluster.
 	clusterKey := ctrlclient.ObjectKeyFromObject(targetCluster)
-	remoteClient, err := remote.NewClusterClient(ctx, "", s.client, clusterKey)
+	remoteClient, err := tracing.NewClusterClient(ctx, "", s.client, clusterKey)
 	if err != nil {
 		return fmt.Errorf("error creating remote cluster client: %w", err)
 	}
@@ -121,12 +129,33 @@ func (s crsStrategy) apply(
 		)
 	}
 
-	if err = utils.EnsureCRSForClusterFromObjects(ctx, cm.Name, s.client, targetCluster, cm); err != nil {
+	if err = utils.EnsureCRSForClusterFromObjects(
+		ctx,
+		cm.Name,
+		s.client,
+		targetCluster,
+		utils.AddonLabels(cluster, utils.AddonClusterAutoscaler),
+		cm,
+	); err != nil {
 		return fmt.Errorf(
 			"failed to apply cluster-autoscaler installation ClusterResourceSet: %w",
 			err,
 		)
 	}
 
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	if err = utils.MigrateAddonHelmChartProxiesToClusterResourceSet(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonClusterAutoscaler,
+		targetCluster.Namespace,
+		helmChartProxyName(cluster),
+		ctrlclient.ObjectKey{Namespace: targetCluster.Namespace, Name: cm.Name},
+	); err != nil {
+		return fmt.Errorf("failed to migrate cluster-autoscaler installation from HelmChartProxy: %w", err)
+	}
+
 	return nil
 }
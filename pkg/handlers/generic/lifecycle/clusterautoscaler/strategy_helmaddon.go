@@ -11,11 +11,11 @@ import (
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
@@ -43,11 +43,15 @@ type helmAddonStrategy struct {
 
 	client    ctrlclient.Client
 	helmChart *config.HelmChart
+	values    *v1alpha1.AddonValues
+
+	// crsName is the name of the ClusterResourceSet installing the addon with the ClusterResourceSet strategy.
+	crsName string
 }
 
 func (s helmAddonStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *capiv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -65,9 +69,7 @@ func (s helmAddonStrategy) apply(
 		)
 	}
 
-	cluster := &req.Cluster
-
-	values := valuesTemplateConfigMap.Data["values.yaml"]
+	values := valuesTemplateConfigMap.Data[utils.ValuesConfigMapKey]
 
 	// The cluster-autoscaler is different from other addons.
 	// It requires all resources to be created in the management cluster,
@@ -77,6 +79,32 @@ func (s helmAddonStrategy) apply(
 		return err
 	}
 
+	// The values template is rendered for the cluster the HelmChartProxy targets, while the values override is
+	// retrieved from the namespace of the cluster the cluster-autoscaler is deployed for.
+	if s.values != nil {
+		values, err = utils.RenderValuesTemplate(ctx, s.client, targetCluster, values)
+		if err != nil {
+			return fmt.Errorf("failed to render cluster-autoscaler installation values template: %w", err)
+		}
+		values, err = utils.MergeValuesOverride(ctx, s.client, cluster, values, s.values)
+		if err != nil {
+			return fmt.Errorf("failed to apply cluster-autoscaler installation values override: %w", err)
+		}
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	if err = utils.MigrateAddonClusterResourceSetsToHelmRelease(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonClusterAutoscaler,
+		targetCluster.Namespace,
+		s.crsName,
+		utils.HelmRelease{Name: fmt.Sprintf(defaultHelmReleaseNameTemplate, cluster.Name), Namespace: cluster.Namespace},
+	); err != nil {
+		return fmt.Errorf("failed to migrate cluster-autoscaler installation from ClusterResourceSet: %w", err)
+	}
+
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: caaphv1.GroupVersion.String(),
@@ -84,7 +112,8 @@ func (s helmAddonStrategy) apply(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: targetCluster.Namespace,
-			Name:      "cluster-autoscaler-" + req.Cluster.Name,
+			Name:      helmChartProxyName(cluster),
+			Labels:    utils.AddonLabels(cluster, utils.AddonClusterAutoscaler),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   s.helmChart.Repository,
@@ -92,23 +121,35 @@ func (s helmAddonStrategy) apply(
 			ClusterSelector: metav1.LabelSelector{
 				MatchLabels: map[string]string{capiv1.ClusterNameLabel: targetCluster.Name},
 			},
-			ReleaseNamespace: req.Cluster.Namespace,
-			ReleaseName:      fmt.Sprintf(defaultHelmReleaseNameTemplate, req.Cluster.Name),
+			ReleaseNamespace: cluster.Namespace,
+			ReleaseName:      fmt.Sprintf(defaultHelmReleaseNameTemplate, cluster.Name),
 			Version:          s.helmChart.Version,
+			Credentials:      s.helmChart.Credentials(),
+			TLSConfig:        s.helmChart.TLSConfig(),
 			ValuesTemplate:   values,
 		},
 	}
 
-	if err = controllerutil.SetOwnerReference(&req.Cluster, hcp, s.client.Scheme()); err != nil {
+	if err = controllerutil.SetOwnerReference(cluster, hcp, s.client.Scheme()); err != nil {
 		return fmt.Errorf(
 			"failed to set owner reference on cluster-autoscaler installation HelmChartProxy: %w",
 			err,
 		)
 	}
 
+	// The images are pulled by the nodes of the cluster the HelmChartProxy targets.
+	if err = utils.RewriteHelmChartProxyForMirror(ctx, s.client, targetCluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite cluster-autoscaler installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err = client.ServerSideApply(ctx, s.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply cluster-autoscaler installation HelmChartProxy: %w", err)
 	}
 
 	return nil
 }
+
+// helmChartProxyName returns the name of the HelmChartProxy installing cluster-autoscaler for the cluster.
+func helmChartProxyName(cluster *capiv1.Cluster) string {
+	return "cluster-autoscaler-" + cluster.Name
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,13 +21,14 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
 type addonStrategy interface {
 	apply(
 		context.Context,
-		*runtimehooksv1.AfterControlPlaneInitializedRequest,
+		*clusterv1.Cluster,
 		string,
 		logr.Logger,
 	) error
@@ -45,6 +48,7 @@ func (c *CNIConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 
 type CalicoCNI struct {
 	client              ctrlclient.Client
+	recorder            record.EventRecorder
 	config              *CNIConfig
 	helmChartInfoGetter *config.HelmChartGetter
 
@@ -55,15 +59,18 @@ type CalicoCNI struct {
 var (
 	_ commonhandlers.Named                   = &CalicoCNI{}
 	_ lifecycle.AfterControlPlaneInitialized = &CalicoCNI{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &CalicoCNI{}
 )
 
 func New(
 	c ctrlclient.Client,
+	recorder record.EventRecorder,
 	cfg *CNIConfig,
 	helmChartInfoGetter *config.HelmChartGetter,
 ) *CalicoCNI {
 	return &CalicoCNI{
 		client:              c,
+		recorder:            recorder,
 		config:              cfg,
 		helmChartInfoGetter: helmChartInfoGetter,
 		variableName:        clusterconfig.MetaVariableName,
@@ -80,14 +87,30 @@ func (c *CalicoCNI) AfterControlPlaneInitialized(
 	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
 	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CalicoCNI) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CalicoCNI) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
 
-	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
 
 	cniVar, found, err := variables.Get[v1alpha1.CNI](varMap, c.variableName, c.variablePath...)
 	if err != nil {
@@ -108,6 +131,7 @@ func (c *CalicoCNI) AfterControlPlaneInitialized(
 			Info(
 				"Skipping Calico CNI handler, cluster does not specify request CNI addon deployment",
 			)
+		events.AddonSkipped(c.recorder, cluster, "Calico CNI", "cluster does not specify a CNI addon")
 		return
 	}
 	if cniVar.Provider != v1alpha1.CNIProviderCalico {
@@ -126,16 +150,18 @@ func (c *CalicoCNI) AfterControlPlaneInitialized(
 		strategy = crsStrategy{
 			config: c.config.crsConfig,
 			client: c.client,
+			calico: cniVar.Calico,
 		}
 	case v1alpha1.AddonStrategyHelmAddon:
 		// this is tigera and not calico because we deploy calico via operataor
 		log.Info("fetching settings for tigera-operator-config")
-		helmChart, err := c.helmChartInfoGetter.For(ctx, log, config.Tigera)
+		helmChart, err := c.helmChartInfoGetter.For(ctx, log, config.Tigera, cluster.Spec.Topology.Version)
 		if err != nil {
 			log.Error(
 				err,
 				"failed to get configmap with helm settings",
 			)
+			events.AddonDeploymentFailed(c.recorder, cluster, "Calico CNI", err)
 			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 			resp.SetMessage(
 				fmt.Sprintf("failed to get configration to create helm addon: %v",
@@ -149,18 +175,33 @@ func (c *CalicoCNI) AfterControlPlaneInitialized(
 			config:    c.config.helmAddonConfig,
 			client:    c.client,
 			helmChart: helmChart,
+			values:    cniVar.Values,
+			calico:    cniVar.Calico,
 		}
 	default:
+		events.AddonDeploymentFailed(
+			c.recorder,
+			cluster,
+			"Calico CNI",
+			fmt.Errorf("unknown addon deployment strategy %q", cniVar.Strategy),
+		)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(fmt.Sprintf("unknown CNI addon deployment strategy %q", cniVar.Strategy))
 		return
 	}
 
-	if err := strategy.apply(ctx, req, c.config.DefaultsNamespace(), log); err != nil {
+	if err := strategy.apply(ctx, cluster, c.config.DefaultsNamespace(), log); err != nil {
+		events.AddonDeploymentFailed(c.recorder, cluster, "Calico CNI", err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(err.Error())
 		return
 	}
 
+	events.AddonDeployed(c.recorder, cluster, "Calico CNI")
 	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 }
+
+// installationName returns the name of the ClusterResourceSet or HelmChartProxy deploying the Calico CNI.
+func installationName(cluster *clusterv1.Cluster) string {
+	return "calico-cni-installation-" + cluster.Name
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package calico
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	netutils "k8s.io/utils/net"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+)
+
+// setCalicoNetwork sets the Calico network options of the CNI variable on the spec of a Tigera Installation, both in
+// the installation values of the Tigera operator Helm chart and in the Installation applied by the ClusterResourceSet.
+// The encapsulation and NAT options are set on every IP pool of the installation. Options that are not set keep the
+// values of the default installation. The options are validated when the Cluster is admitted, e.g. IPIP encapsulation
+// requires BGP.
+func setCalicoNetwork(spec map[string]interface{}, network *v1alpha1.CalicoNetwork) error {
+	if network == nil {
+		return nil
+	}
+
+	if network.BGP != nil {
+		if err := unstructured.SetNestedField(
+			spec,
+			enabledOrDisabled(*network.BGP),
+			"calicoNetwork", "bgp",
+		); err != nil {
+			return fmt.Errorf("failed to set BGP: %w", err)
+		}
+	}
+	if network.MTU != nil {
+		if err := unstructured.SetNestedField(spec, int64(*network.MTU), "calicoNetwork", "mtu"); err != nil {
+			return fmt.Errorf("failed to set MTU: %w", err)
+		}
+	}
+
+	if network.Encapsulation == "" && network.NATOutgoing == nil {
+		return nil
+	}
+
+	ipPoolsRef, exists, err := unstructured.NestedFieldNoCopy(spec, "calicoNetwork", "ipPools")
+	if err != nil {
+		return fmt.Errorf("failed to get ipPools: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("missing ipPools")
+	}
+	ipPools, ok := ipPoolsRef.([]interface{})
+	if !ok {
+		return fmt.Errorf("ipPools is of type %T, expected a list", ipPoolsRef)
+	}
+
+	for i := range ipPools {
+		ipPool, ok := ipPools[i].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("ipPool %d is of type %T, expected an object", i, ipPools[i])
+		}
+		if network.Encapsulation != "" {
+			cidr, _ := ipPool["cidr"].(string)
+			ipPool["encapsulation"] = ipPoolEncapsulation(cidr, string(network.Encapsulation))
+		}
+		if network.NATOutgoing != nil {
+			ipPool["natOutgoing"] = enabledOrDisabled(*network.NATOutgoing)
+		}
+	}
+
+	return nil
+}
+
+// ipPoolsForSubnets replaces the first IP pool of the default installation with an IP pool for each of the Pods network
+// CIDRs of a single-stack or dual-stack cluster, based on the first IP pool. The block size of the first IP pool is
+// only kept for the IPv4 pool, the IPv6 pool using the default block size of Calico.
+func ipPoolsForSubnets(defaultIPPools []interface{}, subnets []string) ([]interface{}, error) {
+	if len(defaultIPPools) == 0 {
+		return nil, fmt.Errorf("missing default ipPool")
+	}
+	defaultIPPool, ok := defaultIPPools[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ipPool 0 is of type %T, expected an object", defaultIPPools[0])
+	}
+
+	ipPools := make([]interface{}, 0, len(subnets)+len(defaultIPPools)-1)
+	for _, subnet := range subnets {
+		ipPool := runtime.DeepCopyJSON(defaultIPPool)
+		ipPool["cidr"] = subnet
+		if netutils.IsIPv6CIDRString(subnet) {
+			delete(ipPool, "blockSize")
+			if encapsulation, ok := ipPool["encapsulation"].(string); ok {
+				ipPool["encapsulation"] = ipPoolEncapsulation(subnet, encapsulation)
+			}
+		}
+		ipPools = append(ipPools, ipPool)
+	}
+
+	return append(ipPools, defaultIPPools[1:]...), nil
+}
+
+// ipPoolEncapsulation returns the encapsulation of the IP pool of a CIDR. Calico does not support IP-in-IP for IPv6,
+// so IPv6 pools are not encapsulated instead.
+func ipPoolEncapsulation(cidr, encapsulation string) string {
+	if netutils.IsIPv6CIDRString(cidr) && isIPIP(v1alpha1.CalicoEncapsulation(encapsulation)) {
+		return string(v1alpha1.CalicoEncapsulationNone)
+	}
+	return encapsulation
+}
+
+func isIPIP(encapsulation v1alpha1.CalicoEncapsulation) bool {
+	return encapsulation == v1alpha1.CalicoEncapsulationIPIP ||
+		encapsulation == v1alpha1.CalicoEncapsulationIPIPCrossSubnet
+}
+
+// enabledOrDisabled returns the value the Tigera operator API uses for a toggle.
+func enabledOrDisabled(enabled bool) string {
+	if enabled {
+		return "Enabled"
+	}
+	return "Disabled"
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package calico
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+)
+
+//nolint:funlen // Long tests are OK
+func TestSetCalicoNetwork(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		network  *v1alpha1.CalicoNetwork
+		expected map[string]interface{}
+	}{{
+		name: "no network options",
+		expected: map[string]interface{}{
+			"calicoNetwork": map[string]interface{}{
+				"bgp": "Enabled",
+				"ipPools": []interface{}{
+					map[string]interface{}{"cidr": "192.168.0.0/16", "encapsulation": "None"},
+				},
+			},
+		},
+	}, {
+		name: "all network options",
+		network: &v1alpha1.CalicoNetwork{
+			Encapsulation: v1alpha1.CalicoEncapsulationVXLAN,
+			MTU:           ptr.To[int32](8951),
+			BGP:           ptr.To(false),
+			NATOutgoing:   ptr.To(true),
+		},
+		expected: map[string]interface{}{
+			"calicoNetwork": map[string]interface{}{
+				"bgp": "Disabled",
+				"mtu": int64(8951),
+				"ipPools": []interface{}{
+					map[string]interface{}{
+						"cidr":          "192.168.0.0/16",
+						"encapsulation": "VXLAN",
+						"natOutgoing":   "Enabled",
+					},
+				},
+			},
+		},
+	}, {
+		name:    "only MTU",
+		network: &v1alpha1.CalicoNetwork{MTU: ptr.To[int32](1440)},
+		expected: map[string]interface{}{
+			"calicoNetwork": map[string]interface{}{
+				"bgp": "Enabled",
+				"mtu": int64(1440),
+				"ipPools": []interface{}{
+					map[string]interface{}{"cidr": "192.168.0.0/16", "encapsulation": "None"},
+				},
+			},
+		},
+	}}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := map[string]interface{}{
+				"calicoNetwork": map[string]interface{}{
+					"bgp": "Enabled",
+					"ipPools": []interface{}{
+						map[string]interface{}{"cidr": "192.168.0.0/16", "encapsulation": "None"},
+					},
+				},
+			}
+			err := setCalicoNetwork(spec, tt.network)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, spec)
+		})
+	}
+}
+
+func TestSetCalicoNetworkDualStack(t *testing.T) {
+	t.Parallel()
+
+	spec := map[string]interface{}{
+		"calicoNetwork": map[string]interface{}{
+			"ipPools": []interface{}{
+				map[string]interface{}{"cidr": "192.168.0.0/16", "encapsulation": "None"},
+				map[string]interface{}{"cidr": "fd00:100:96::/48", "encapsulation": "None"},
+			},
+		},
+	}
+	err := setCalicoNetwork(spec, &v1alpha1.CalicoNetwork{Encapsulation: v1alpha1.CalicoEncapsulationIPIP})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"calicoNetwork": map[string]interface{}{
+			"ipPools": []interface{}{
+				map[string]interface{}{"cidr": "192.168.0.0/16", "encapsulation": "IPIP"},
+				map[string]interface{}{"cidr": "fd00:100:96::/48", "encapsulation": "None"},
+			},
+		},
+	}, spec, "IPv6 pools must not use IP-in-IP encapsulation")
+}
+
+func TestIPPoolsForSubnets(t *testing.T) {
+	t.Parallel()
+
+	defaultIPPools := []interface{}{
+		map[string]interface{}{
+			"blockSize":     int64(26),
+			"cidr":          "192.168.0.0/16",
+			"encapsulation": "IPIP",
+			"natOutgoing":   "Enabled",
+		},
+		map[string]interface{}{"cidr": "172.16.0.0/16"},
+	}
+
+	ipPools, err := ipPoolsForSubnets(defaultIPPools, []string{"fd00:100:96::/48", "10.0.0.0/16"})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"cidr":          "fd00:100:96::/48",
+			"encapsulation": "None",
+			"natOutgoing":   "Enabled",
+		},
+		map[string]interface{}{
+			"blockSize":     int64(26),
+			"cidr":          "10.0.0.0/16",
+			"encapsulation": "IPIP",
+			"natOutgoing":   "Enabled",
+		},
+		map[string]interface{}{"cidr": "172.16.0.0/16"},
+	}, ipPools)
+	assert.Equal(t, "192.168.0.0/16", defaultIPPools[0].(map[string]interface{})["cidr"],
+		"the default IP pools must not be modified")
+
+	_, err = ipPoolsForSubnets(nil, []string{"10.0.0.0/16"})
+	assert.EqualError(t, err, "missing default ipPool")
+}
+
+func TestHelmAddonInstallationValues(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+		Spec: clusterv1.ClusterSpec{
+			ClusterNetwork: &clusterv1.ClusterNetwork{
+				Pods: &clusterv1.NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16"}},
+			},
+		},
+	}
+	valuesTemplate := `installation:
+  calicoNetwork:
+    bgp: Enabled
+    ipPools:{{ range $cidr := .Cluster.spec.clusterNetwork.pods.cidrBlocks }}
+    - cidr: {{ $cidr }}
+      encapsulation: None
+      natOutgoing: Enabled{{ end }}
+`
+	s := helmAddonStrategy{
+		client: fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "calico-values"},
+			Data:       map[string]string{"values.yaml": "installation:\n  calicoNetwork:\n    mtu: 1440\n"},
+		}).Build(),
+		values: &v1alpha1.AddonValues{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "calico-values"},
+		},
+		calico: &v1alpha1.CalicoNetwork{
+			Encapsulation: v1alpha1.CalicoEncapsulationVXLANCrossSubnet,
+			MTU:           ptr.To[int32](8951),
+			BGP:           ptr.To(false),
+		},
+	}
+
+	values, err := s.installationValues(context.Background(), cluster, valuesTemplate)
+	require.NoError(t, err)
+	assert.Equal(t, `installation:
+  calicoNetwork:
+    bgp: Disabled
+    ipPools:
+    - cidr: 192.168.0.0/16
+      encapsulation: VXLANCrossSubnet
+      natOutgoing: Enabled
+    mtu: 1440
+`, values, "the values override must take precedence over the Calico network options")
+}
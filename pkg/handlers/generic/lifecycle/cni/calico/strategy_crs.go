@@ -17,9 +17,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/parser"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/cni"
@@ -54,28 +54,29 @@ type crsStrategy struct {
 	config crsConfig
 
 	client ctrlclient.Client
+	calico *v1alpha1.CalicoNetwork
 }
 
 func (s crsStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *capiv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
-	infraKind := req.Cluster.Spec.InfrastructureRef.Kind
+	infraKind := cluster.Spec.InfrastructureRef.Kind
 	defaultInstallationConfigMapName, ok := s.config.defaultProviderInstallationConfigMapNames[infraKind]
 	if !ok {
 		log.V(4).Info(
 			fmt.Sprintf(
 				"Skipping Calico CNI handler, no default installation ConfigMap configured for infrastructure provider %q",
-				req.Cluster.Spec.InfrastructureRef.Kind,
+				cluster.Spec.InfrastructureRef.Kind,
 			),
 		)
 		return nil
 	}
 
 	log.Info("Ensuring Tigera manifests ConfigMap exist in cluster namespace")
-	tigeraCM, err := s.ensureTigeraOperatorConfigMap(ctx, &req.Cluster, defaultsNamespace)
+	tigeraCM, err := s.ensureTigeraOperatorConfigMap(ctx, cluster, defaultsNamespace)
 	if err != nil {
 		log.Error(
 			err,
@@ -90,7 +91,7 @@ func (s crsStrategy) apply(
 	log.Info("Ensuring Calico installation CRS and ConfigMap exist for cluster")
 	if err := s.ensureCNICRSForCluster(
 		ctx,
-		&req.Cluster,
+		cluster,
 		defaultsNamespace,
 		defaultInstallationConfigMapName,
 		tigeraCM,
@@ -136,6 +137,7 @@ func (s crsStrategy) ensureCNICRSForCluster(
 	cm, err := generateProviderCNIManifestsConfigMap(
 		defaultInstallationConfigMap,
 		cluster,
+		s.calico,
 	)
 	if err != nil {
 		return fmt.Errorf(
@@ -144,6 +146,10 @@ func (s crsStrategy) ensureCNICRSForCluster(
 		)
 	}
 
+	if err := utils.RewriteConfigMapImagesForMirror(cluster, cm); err != nil {
+		return fmt.Errorf("failed to rewrite Calico CNI installation manifests ConfigMap for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, cm); err != nil {
 		return fmt.Errorf(
 			"failed to apply Calico CNI installation manifests ConfigMap: %w",
@@ -151,13 +157,35 @@ func (s crsStrategy) ensureCNICRSForCluster(
 		)
 	}
 
-	if err := utils.EnsureCRSForClusterFromObjects(ctx, cm.Name, s.client, cluster, tigeraConfigMap, cm); err != nil {
+	if err := utils.EnsureCRSForClusterFromObjects(
+		ctx,
+		cm.Name,
+		s.client,
+		cluster,
+		utils.AddonLabels(cluster, utils.AddonCNI),
+		tigeraConfigMap,
+		cm,
+	); err != nil {
 		return fmt.Errorf(
 			"failed to apply Calico CNI installation ClusterResourceSet: %w",
 			err,
 		)
 	}
 
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	if err := utils.MigrateAddonHelmChartProxiesToClusterResourceSet(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: cm.Name},
+	); err != nil {
+		return fmt.Errorf("failed to migrate Calico CNI installation from HelmChartProxy: %w", err)
+	}
+
 	return nil
 }
 
@@ -185,6 +213,10 @@ func (s crsStrategy) ensureTigeraOperatorConfigMap(
 	}
 
 	tigeraConfigMap := generateTigeraOperatorConfigMap(defaultTigeraOperatorConfigMap, cluster)
+	if err := utils.RewriteConfigMapImagesForMirror(cluster, tigeraConfigMap); err != nil {
+		return nil, fmt.Errorf("failed to rewrite Tigera Operator manifests ConfigMap for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, tigeraConfigMap); err != nil {
 		return nil, fmt.Errorf(
 			"failed to apply Tigera Operator manifests ConfigMap: %w",
@@ -217,6 +249,7 @@ func generateTigeraOperatorConfigMap(
 func generateProviderCNIManifestsConfigMap(
 	installationConfigMap *corev1.ConfigMap,
 	cluster *capiv1.Cluster,
+	calico *v1alpha1.CalicoNetwork,
 ) (*corev1.ConfigMap, error) {
 	defaultManifestStrings := make([]string, 0, len(installationConfigMap.Data))
 	for _, v := range installationConfigMap.Data {
@@ -237,17 +270,21 @@ func generateProviderCNIManifestsConfigMap(
 		},
 	)
 
-	podSubnet, err := cni.PodCIDR(cluster)
-	if err != nil {
+	// Validate the Pods network CIDRs of single-stack and dual-stack clusters.
+	if _, _, err := cni.PodCIDRs(cluster); err != nil {
 		return nil, err
 	}
+	var podSubnets []string
+	if cluster.Spec.ClusterNetwork != nil && cluster.Spec.ClusterNetwork.Pods != nil {
+		podSubnets = cluster.Spec.ClusterNetwork.Pods.CIDRBlocks
+	}
 
 	var b bytes.Buffer
 
 	for _, o := range parsed {
 		calicoInstallationGK := schema.GroupKind{Group: "operator.tigera.io", Kind: "Installation"}
-		if podSubnet != "" &&
-			o.GetObjectKind().GroupVersionKind().GroupKind() == calicoInstallationGK {
+		isInstallation := o.GetObjectKind().GroupVersionKind().GroupKind() == calicoInstallationGK
+		if len(podSubnets) > 0 && isInstallation {
 			obj := o.(*unstructured.Unstructured).Object
 
 			ipPoolsRef, exists, err := unstructured.NestedFieldNoCopy(
@@ -261,15 +298,9 @@ func generateProviderCNIManifestsConfigMap(
 				return nil, fmt.Errorf("missing ipPools in unstructured object")
 			}
 
-			ipPools := ipPoolsRef.([]interface{})
-
-			err = unstructured.SetNestedField(
-				ipPools[0].(map[string]interface{}),
-				podSubnet,
-				"cidr",
-			)
+			ipPools, err := ipPoolsForSubnets(ipPoolsRef.([]interface{}), podSubnets)
 			if err != nil {
-				return nil, fmt.Errorf("failed to set default pod subnet: %w", err)
+				return nil, fmt.Errorf("failed to set default pod subnets: %w", err)
 			}
 
 			err = unstructured.SetNestedSlice(obj, ipPools, "spec", "calicoNetwork", "ipPools")
@@ -278,6 +309,16 @@ func generateProviderCNIManifestsConfigMap(
 			}
 		}
 
+		if isInstallation {
+			spec, ok := o.(*unstructured.Unstructured).Object["spec"].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("missing spec in Calico installation")
+			}
+			if err := setCalicoNetwork(spec, calico); err != nil {
+				return nil, fmt.Errorf("failed to set Calico network options: %w", err)
+			}
+		}
+
 		if err := yamlSerializer.Encode(o, &b); err != nil {
 			return nil, fmt.Errorf("failed to serialize manifests: %w", err)
 		}
@@ -292,7 +333,7 @@ func generateProviderCNIManifestsConfigMap(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      "calico-cni-installation-" + cluster.Name,
+			Name:      installationName(cluster),
 		},
 		Data: map[string]string{
 			"manifests": b.String(),
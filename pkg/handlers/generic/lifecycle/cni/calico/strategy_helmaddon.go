@@ -12,13 +12,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
 
 	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 )
 
 const (
@@ -47,21 +49,23 @@ type helmAddonStrategy struct {
 	config    helmAddonConfig
 	helmChart *config.HelmChart
 	client    ctrlclient.Client
+	values    *v1alpha1.AddonValues
+	calico    *v1alpha1.CalicoNetwork
 }
 
 func (s helmAddonStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *capiv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
-	infraKind := req.Cluster.Spec.InfrastructureRef.Kind
+	infraKind := cluster.Spec.InfrastructureRef.Kind
 	defaultInstallationConfigMapName, ok := s.config.defaultProviderInstallationValuesTemplatesConfigMapNames[infraKind]
 	if !ok {
 		log.Info(
 			fmt.Sprintf(
 				"Skipping Calico CNI handler, no default installation values ConfigMap configured for infrastructure provider %q",
-				req.Cluster.Spec.InfrastructureRef.Kind,
+				cluster.Spec.InfrastructureRef.Kind,
 			),
 		)
 		return nil
@@ -80,35 +84,60 @@ func (s helmAddonStrategy) apply(
 		)
 	}
 
+	values, err := s.installationValues(ctx, cluster, valuesTemplateConfigMap.Data[utils.ValuesConfigMapKey])
+	if err != nil {
+		return fmt.Errorf("failed to apply Calico CNI installation values override: %w", err)
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	if err := utils.MigrateAddonClusterResourceSetsToHelmRelease(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		utils.HelmRelease{Name: defaultTigeraOperatorReleaseName, Namespace: defaultTigerOperatorNamespace},
+	); err != nil {
+		return fmt.Errorf("failed to migrate Calico CNI installation from ClusterResourceSet: %w", err)
+	}
+
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: caaphv1.GroupVersion.String(),
 			Kind:       "HelmChartProxy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: req.Cluster.Namespace,
-			Name:      "calico-cni-installation-" + req.Cluster.Name,
+			Namespace: cluster.Namespace,
+			Name:      installationName(cluster),
+			Labels:    utils.AddonLabels(cluster, utils.AddonCNI),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   s.helmChart.Repository,
 			ChartName: s.helmChart.Name,
 			ClusterSelector: metav1.LabelSelector{
-				MatchLabels: map[string]string{capiv1.ClusterNameLabel: req.Cluster.Name},
+				MatchLabels: map[string]string{capiv1.ClusterNameLabel: cluster.Name},
 			},
 			ReleaseNamespace: defaultTigerOperatorNamespace,
 			ReleaseName:      defaultTigeraOperatorReleaseName,
 			Version:          s.helmChart.Version,
-			ValuesTemplate:   valuesTemplateConfigMap.Data["values.yaml"],
+			Credentials:      s.helmChart.Credentials(),
+			TLSConfig:        s.helmChart.TLSConfig(),
+			ValuesTemplate:   values,
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(&req.Cluster, hcp, s.client.Scheme()); err != nil {
+	if err := controllerutil.SetOwnerReference(cluster, hcp, s.client.Scheme()); err != nil {
 		return fmt.Errorf(
 			"failed to set owner reference on Calico CNI installation HelmChartProxy: %w",
 			err,
 		)
 	}
 
+	if err := utils.RewriteHelmChartProxyForMirror(ctx, s.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite Calico CNI installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply Calico CNI installation HelmChartProxy: %w", err)
 	}
@@ -116,6 +145,41 @@ func (s helmAddonStrategy) apply(
 	return nil
 }
 
+// installationValues returns the installation values template with the Calico network options and then the values
+// override applied. The values template is rendered before setting the Calico network options, so that they are set on
+// every IP pool rendered from the pod CIDRs of the cluster.
+func (s helmAddonStrategy) installationValues(
+	ctx context.Context,
+	cluster *capiv1.Cluster,
+	valuesTemplate string,
+) (string, error) {
+	if s.calico == nil {
+		return utils.ApplyValuesOverride(ctx, s.client, cluster, valuesTemplate, s.values)
+	}
+
+	rendered, err := utils.RenderValuesTemplate(ctx, s.client, cluster, valuesTemplate)
+	if err != nil {
+		return "", err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(rendered), &values); err != nil {
+		return "", fmt.Errorf("failed to parse installation values: %w", err)
+	}
+	installation, ok := values["installation"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("missing installation in installation values")
+	}
+	if err := setCalicoNetwork(installation, s.calico); err != nil {
+		return "", fmt.Errorf("failed to set Calico network options: %w", err)
+	}
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal installation values: %w", err)
+	}
+
+	return utils.MergeValuesOverride(ctx, s.client, cluster, string(b), s.values)
+}
+
 func (s helmAddonStrategy) retrieveValuesTemplateConfigMap(
 	ctx context.Context,
 	defaultsNamespace string,
@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,13 +21,14 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
 type addonStrategy interface {
 	apply(
 		context.Context,
-		*runtimehooksv1.AfterControlPlaneInitializedRequest,
+		*clusterv1.Cluster,
 		string,
 		logr.Logger,
 	) error
@@ -45,6 +48,7 @@ func (c *CNIConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 
 type CiliumCNI struct {
 	client              ctrlclient.Client
+	recorder            record.EventRecorder
 	config              *CNIConfig
 	helmChartInfoGetter *config.HelmChartGetter
 
@@ -55,15 +59,18 @@ type CiliumCNI struct {
 var (
 	_ commonhandlers.Named                   = &CiliumCNI{}
 	_ lifecycle.AfterControlPlaneInitialized = &CiliumCNI{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &CiliumCNI{}
 )
 
 func New(
 	c ctrlclient.Client,
+	recorder record.EventRecorder,
 	cfg *CNIConfig,
 	helmChartInfoGetter *config.HelmChartGetter,
 ) *CiliumCNI {
 	return &CiliumCNI{
 		client:              c,
+		recorder:            recorder,
 		config:              cfg,
 		helmChartInfoGetter: helmChartInfoGetter,
 		variableName:        clusterconfig.MetaVariableName,
@@ -80,14 +87,30 @@ func (c *CiliumCNI) AfterControlPlaneInitialized(
 	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
 	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CiliumCNI) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CiliumCNI) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
 
-	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
 
 	cniVar, found, err := variables.Get[v1alpha1.CNI](varMap, c.variableName, c.variablePath...)
 	if err != nil {
@@ -108,6 +131,7 @@ func (c *CiliumCNI) AfterControlPlaneInitialized(
 			Info(
 				"Skipping Cilium CNI handler, cluster does not specify request CNI addon deployment",
 			)
+		events.AddonSkipped(c.recorder, cluster, "Cilium CNI", "cluster does not specify a CNI addon")
 		return
 	}
 	if cniVar.Provider != v1alpha1.CNIProviderCilium {
@@ -126,14 +150,16 @@ func (c *CiliumCNI) AfterControlPlaneInitialized(
 		strategy = crsStrategy{
 			config: c.config.crsConfig,
 			client: c.client,
+			cilium: cniVar.Cilium,
 		}
 	case v1alpha1.AddonStrategyHelmAddon:
-		helmChart, err := c.helmChartInfoGetter.For(ctx, log, config.Cilium)
+		helmChart, err := c.helmChartInfoGetter.For(ctx, log, config.Cilium, cluster.Spec.Topology.Version)
 		if err != nil {
 			log.Error(
 				err,
 				"failed to get configmap with helm settings",
 			)
+			events.AddonDeploymentFailed(c.recorder, cluster, "Cilium CNI", err)
 			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 			resp.SetMessage(
 				fmt.Sprintf("failed to get configration to create helm addon: %v",
@@ -146,18 +172,33 @@ func (c *CiliumCNI) AfterControlPlaneInitialized(
 			config:    c.config.helmAddonConfig,
 			client:    c.client,
 			helmChart: helmChart,
+			values:    cniVar.Values,
+			cilium:    cniVar.Cilium,
 		}
 	default:
+		events.AddonDeploymentFailed(
+			c.recorder,
+			cluster,
+			"Cilium CNI",
+			fmt.Errorf("unknown addon deployment strategy %q", cniVar.Strategy),
+		)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(fmt.Sprintf("unknown CNI addon deployment strategy %q", cniVar.Strategy))
 		return
 	}
 
-	if err := strategy.apply(ctx, req, c.config.DefaultsNamespace(), log); err != nil {
+	if err := strategy.apply(ctx, cluster, c.config.DefaultsNamespace(), log); err != nil {
+		events.AddonDeploymentFailed(c.recorder, cluster, "Cilium CNI", err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(err.Error())
 		return
 	}
 
+	events.AddonDeployed(c.recorder, cluster, "Cilium CNI")
 	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 }
+
+// installationName returns the name of the ClusterResourceSet or HelmChartProxy deploying the Cilium CNI.
+func installationName(cluster *clusterv1.Cluster) string {
+	return "cilium-cni-installation-" + cluster.Name
+}
@@ -0,0 +1,419 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cilium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/cni"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+const (
+	ciliumAgentConfigMapName = "cilium-config"
+	ciliumAgentName          = "cilium"
+	ciliumAgentContainerName = "cilium-agent"
+	ciliumOperatorName       = "cilium-operator"
+	ciliumNamespace          = "kube-system"
+
+	// The IPsec key is copied to the Secret expected by default by the Cilium Helm chart, and mounted in the Cilium
+	// agent at the same path as in the Helm chart.
+	ciliumIPsecKeysSecretName = "cilium-ipsec-keys"
+	ciliumIPsecKeysVolumeName = "cilium-ipsec-secrets"
+	ciliumIPsecKeysMountPath  = "/etc/ipsec"
+	ciliumIPsecKeysSecretKey  = "keys"
+)
+
+var (
+	errTunnelProtocolRequiresTunnel = errors.New("tunnelProtocol can only be set with the tunnel routing mode")
+	errNativeRoutingRequiresPodCIDR = errors.New("native routing mode requires the cluster to have a Pods network CIDR")
+
+	errKubeProxyReplacementRequiresControlPlaneEndpoint = errors.New(
+		"kube-proxy replacement requires the cluster to have a control plane endpoint",
+	)
+
+	errHubbleRelayRequiresHubble    = errors.New("hubble relay requires hubble to be enabled")
+	errHubbleUIRequiresHubbleRelay  = errors.New("hubble UI requires hubble relay to be deployed")
+	errHubbleRelayRequiresHelmAddon = errors.New(
+		"hubble relay and UI can only be deployed with the HelmAddon strategy",
+	)
+	errIPsecRequiresKeySecret          = errors.New("ipsec encryption requires ipsecKeySecretRef to be set")
+	errIPsecKeySecretRequiresIPsec     = errors.New("ipsecKeySecretRef can only be set with the IPsec encryption")
+	errUnsupportedCiliumEncryptionType = errors.New("unsupported Cilium encryption type")
+)
+
+// ciliumOptions holds the Cilium configuration of the CNI variable and of the IP families of the cluster both as Helm
+// values, for the HelmAddon strategy,
+// and as entries of the cilium-config ConfigMap and environment variables of the Cilium containers, for the
+// ClusterResourceSet strategy.
+type ciliumOptions struct {
+	values      map[string]interface{}
+	agentConfig map[string]string
+	env         []corev1.EnvVar
+
+	// hubbleRelay is set when Hubble Relay is deployed, which is not part of the ClusterResourceSet manifests.
+	hubbleRelay bool
+	// ipsecKeySecretName is the name of the Secret containing the IPsec key in the cluster namespace, to be copied
+	// to the workload cluster and mounted in the Cilium agent.
+	ipsecKeySecretName string
+}
+
+func newCiliumOptions(cluster *clusterv1.Cluster, cilium *v1alpha1.CiliumConfig) (*ciliumOptions, error) {
+	opts := &ciliumOptions{
+		values:      map[string]interface{}{},
+		agentConfig: map[string]string{},
+	}
+
+	ipv4PodCIDR, ipv6PodCIDR, err := cni.PodCIDRs(cluster)
+	if err != nil {
+		return nil, err
+	}
+	// Cilium is IPv4 only by default, IPv6 is enabled for IPv6 only and dual-stack clusters.
+	if ipv6PodCIDR != "" {
+		opts.values["ipv6"] = map[string]interface{}{"enabled": true}
+		opts.agentConfig["enable-ipv6"] = "true"
+		if ipv4PodCIDR == "" {
+			opts.values["ipv4"] = map[string]interface{}{"enabled": false}
+			opts.agentConfig["enable-ipv4"] = "false"
+		}
+	}
+
+	if cilium == nil {
+		return opts, nil
+	}
+
+	switch cilium.RoutingMode {
+	case v1alpha1.CiliumRoutingModeNative:
+		if cilium.TunnelProtocol != "" {
+			return nil, errTunnelProtocolRequiresTunnel
+		}
+		if ipv4PodCIDR == "" && ipv6PodCIDR == "" {
+			return nil, errNativeRoutingRequiresPodCIDR
+		}
+		// Without a tunnel, every node needs a route to the pods of the other nodes.
+		opts.values["routingMode"] = string(v1alpha1.CiliumRoutingModeNative)
+		opts.values["autoDirectNodeRoutes"] = true
+		opts.agentConfig["routing-mode"] = string(v1alpha1.CiliumRoutingModeNative)
+		opts.agentConfig["auto-direct-node-routes"] = "true"
+		if ipv4PodCIDR != "" {
+			opts.values["ipv4NativeRoutingCIDR"] = ipv4PodCIDR
+			opts.agentConfig["ipv4-native-routing-cidr"] = ipv4PodCIDR
+		}
+		if ipv6PodCIDR != "" {
+			opts.values["ipv6NativeRoutingCIDR"] = ipv6PodCIDR
+			opts.agentConfig["ipv6-native-routing-cidr"] = ipv6PodCIDR
+		}
+	case v1alpha1.CiliumRoutingModeTunnel:
+		opts.values["routingMode"] = string(v1alpha1.CiliumRoutingModeTunnel)
+		opts.agentConfig["routing-mode"] = string(v1alpha1.CiliumRoutingModeTunnel)
+	}
+
+	if cilium.TunnelProtocol != "" {
+		opts.values["tunnelProtocol"] = string(cilium.TunnelProtocol)
+		opts.agentConfig["tunnel-protocol"] = string(cilium.TunnelProtocol)
+	}
+
+	if cilium.KubeProxyReplacement {
+		// Without kube-proxy, Cilium cannot reach the API server through the kubernetes Service and must use the
+		// control plane endpoint instead.
+		endpoint := cluster.Spec.ControlPlaneEndpoint
+		if !endpoint.IsValid() {
+			return nil, errKubeProxyReplacementRequiresControlPlaneEndpoint
+		}
+		opts.values["kubeProxyReplacement"] = "true"
+		opts.values["k8sServiceHost"] = endpoint.Host
+		opts.values["k8sServicePort"] = endpoint.Port
+		opts.agentConfig["kube-proxy-replacement"] = "true"
+		opts.env = []corev1.EnvVar{
+			{Name: "KUBERNETES_SERVICE_HOST", Value: endpoint.Host},
+			{Name: "KUBERNETES_SERVICE_PORT", Value: strconv.Itoa(int(endpoint.Port))},
+		}
+	}
+
+	if err := opts.setHubble(cilium.Hubble); err != nil {
+		return nil, err
+	}
+
+	if err := opts.setEncryption(cilium.Encryption); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func (o *ciliumOptions) setHubble(hubble *v1alpha1.CiliumHubble) error {
+	if hubble == nil {
+		return nil
+	}
+	if hubble.Relay && !hubble.Enabled {
+		return errHubbleRelayRequiresHubble
+	}
+	if hubble.UI && !hubble.Relay {
+		return errHubbleUIRequiresHubbleRelay
+	}
+
+	o.values["hubble"] = map[string]interface{}{
+		"enabled": hubble.Enabled,
+		"relay":   map[string]interface{}{"enabled": hubble.Relay},
+		"ui":      map[string]interface{}{"enabled": hubble.UI},
+	}
+	o.agentConfig["enable-hubble"] = strconv.FormatBool(hubble.Enabled)
+	o.hubbleRelay = hubble.Relay
+
+	return nil
+}
+
+func (o *ciliumOptions) setEncryption(encryption *v1alpha1.CiliumEncryption) error {
+	if encryption == nil {
+		return nil
+	}
+
+	switch encryption.Type {
+	case v1alpha1.CiliumEncryptionTypeWireGuard:
+		if encryption.IPsecKeySecretRef != nil {
+			return errIPsecKeySecretRequiresIPsec
+		}
+		o.values["encryption"] = map[string]interface{}{
+			"enabled": true,
+			"type":    string(v1alpha1.CiliumEncryptionTypeWireGuard),
+		}
+		o.agentConfig["enable-wireguard"] = "true"
+	case v1alpha1.CiliumEncryptionTypeIPsec:
+		if encryption.IPsecKeySecretRef == nil || encryption.IPsecKeySecretRef.Name == "" {
+			return errIPsecRequiresKeySecret
+		}
+		o.values["encryption"] = map[string]interface{}{
+			"enabled": true,
+			"type":    string(v1alpha1.CiliumEncryptionTypeIPsec),
+			"ipsec": map[string]interface{}{
+				"secretName": ciliumIPsecKeysSecretName,
+				"keyFile":    ciliumIPsecKeysSecretKey,
+				"mountPath":  ciliumIPsecKeysMountPath,
+			},
+		}
+		o.agentConfig["enable-ipsec"] = "true"
+		o.agentConfig["ipsec-key-file"] = path.Join(ciliumIPsecKeysMountPath, ciliumIPsecKeysSecretKey)
+		o.ipsecKeySecretName = encryption.IPsecKeySecretRef.Name
+	default:
+		return fmt.Errorf("%w: %q", errUnsupportedCiliumEncryptionType, encryption.Type)
+	}
+
+	return nil
+}
+
+// copyIPsecKeySecret copies the Secret containing the IPsec key to the workload cluster, if IPsec encryption is
+// enabled.
+func (o *ciliumOptions) copyIPsecKeySecret(
+	ctx context.Context,
+	c ctrlclient.Client,
+	cluster *clusterv1.Cluster,
+) error {
+	if o.ipsecKeySecretName == "" {
+		return nil
+	}
+
+	if err := utils.CopySecretToRemoteCluster(
+		ctx,
+		c,
+		o.ipsecKeySecretName,
+		ctrlclient.ObjectKey{Namespace: ciliumNamespace, Name: ciliumIPsecKeysSecretName},
+		cluster,
+	); err != nil {
+		return fmt.Errorf(
+			"error creating Cilium IPsec key Secret on the remote cluster: %w",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// setManifests sets the entries of the cilium-config ConfigMap and the environment variables of the containers of
+// the Cilium agent DaemonSet and operator Deployment found in the manifests of a ClusterResourceSet ConfigMap,
+// returning the updated data of the ClusterResourceSet ConfigMap. With IPsec encryption, the Secret containing the
+// IPsec key is also mounted in the Cilium agent.
+func (o *ciliumOptions) setManifests(cm *corev1.ConfigMap) (map[string]string, error) {
+	if o.hubbleRelay {
+		return nil, errHubbleRelayRequiresHelmAddon
+	}
+	if len(o.agentConfig) == 0 && len(o.env) == 0 {
+		return cm.Data, nil
+	}
+
+	data := make(map[string]string, len(cm.Data))
+	foundAgentConfig := false
+	for k, v := range cm.Data {
+		objs, isJSONList, err := utils.ParseManifests(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifests in key %s of ConfigMap %s: %w", k, cm.Name, err)
+		}
+
+		changed := false
+		for i := range objs {
+			obj := &objs[i]
+			if obj.GetNamespace() != ciliumNamespace {
+				continue
+			}
+
+			switch {
+			case obj.GetKind() == "ConfigMap" && obj.GetName() == ciliumAgentConfigMapName:
+				for configKey, configValue := range o.agentConfig {
+					if err := unstructured.SetNestedField(obj.Object, configValue, "data", configKey); err != nil {
+						return nil, fmt.Errorf("failed to set %s in %s ConfigMap: %w", configKey, obj.GetName(), err)
+					}
+				}
+				foundAgentConfig = true
+				changed = true
+			case obj.GetKind() == "DaemonSet" && obj.GetName() == ciliumAgentName,
+				obj.GetKind() == "Deployment" && obj.GetName() == ciliumOperatorName:
+				if len(o.env) > 0 {
+					if err := setContainersEnv(obj, o.env); err != nil {
+						return nil, fmt.Errorf(
+							"failed to set environment variables in %s %s: %w",
+							obj.GetKind(),
+							obj.GetName(),
+							err,
+						)
+					}
+					changed = true
+				}
+				if o.ipsecKeySecretName != "" && obj.GetKind() == "DaemonSet" {
+					if err := mountIPsecKeys(obj); err != nil {
+						return nil, fmt.Errorf(
+							"failed to mount IPsec key in %s %s: %w",
+							obj.GetKind(),
+							obj.GetName(),
+							err,
+						)
+					}
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			data[k] = v
+			continue
+		}
+
+		b, err := utils.SerializeManifests(objs, isJSONList)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal manifests in key %s of ConfigMap %s: %w", k, cm.Name, err)
+		}
+		data[k] = string(b)
+	}
+	if !foundAgentConfig {
+		return nil, fmt.Errorf("missing %s ConfigMap in ConfigMap %s", ciliumAgentConfigMapName, cm.Name)
+	}
+
+	return data, nil
+}
+
+// setContainersEnv sets the environment variables in all the containers and init containers of the Pod template of
+// the object, replacing the variables already set with the same names.
+func setContainersEnv(obj *unstructured.Unstructured, env []corev1.EnvVar) error {
+	for _, containersField := range []string{"containers", "initContainers"} {
+		containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", containersField)
+		if err != nil {
+			return err
+		}
+		for i := range containers {
+			container, ok := containers[i].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("container %d is of type %T, expected an object", i, containers[i])
+			}
+			containerEnv, _, err := unstructured.NestedSlice(container, "env")
+			if err != nil {
+				return err
+			}
+			containerEnv = slices.DeleteFunc(containerEnv, func(e interface{}) bool {
+				envVar, ok := e.(map[string]interface{})
+				return ok && slices.ContainsFunc(env, func(newEnvVar corev1.EnvVar) bool {
+					return envVar["name"] == newEnvVar.Name
+				})
+			})
+			for _, newEnvVar := range env {
+				containerEnv = append(
+					containerEnv,
+					map[string]interface{}{"name": newEnvVar.Name, "value": newEnvVar.Value},
+				)
+			}
+			container["env"] = containerEnv
+		}
+		if len(containers) == 0 {
+			continue
+		}
+		if err := unstructured.SetNestedSlice(
+			obj.Object,
+			containers,
+			"spec", "template", "spec", containersField,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountIPsecKeys adds the Secret containing the IPsec key as a volume of the Pod template of the object and mounts
+// it in the Cilium agent container, replacing the volume and mount already set with the same name.
+func mountIPsecKeys(obj *unstructured.Unstructured) error {
+	volumes, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "volumes")
+	if err != nil {
+		return err
+	}
+	volumes = append(
+		slices.DeleteFunc(volumes, hasName(ciliumIPsecKeysVolumeName)),
+		map[string]interface{}{
+			"name":   ciliumIPsecKeysVolumeName,
+			"secret": map[string]interface{}{"secretName": ciliumIPsecKeysSecretName},
+		},
+	)
+	if err := unstructured.SetNestedSlice(obj.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+		return err
+	}
+
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(containers, hasName(ciliumAgentContainerName))
+	if i < 0 {
+		return fmt.Errorf("missing %s container", ciliumAgentContainerName)
+	}
+	container, ok := containers[i].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("container %d is of type %T, expected an object", i, containers[i])
+	}
+	volumeMounts, _, err := unstructured.NestedSlice(container, "volumeMounts")
+	if err != nil {
+		return err
+	}
+	container["volumeMounts"] = append(
+		slices.DeleteFunc(volumeMounts, hasName(ciliumIPsecKeysVolumeName)),
+		map[string]interface{}{
+			"name":      ciliumIPsecKeysVolumeName,
+			"mountPath": ciliumIPsecKeysMountPath,
+			"readOnly":  true,
+		},
+	)
+
+	return unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+}
+
+func hasName(name string) func(interface{}) bool {
+	return func(o interface{}) bool {
+		m, ok := o.(map[string]interface{})
+		return ok && m["name"] == name
+	}
+}
@@ -0,0 +1,487 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cilium
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+)
+
+func testCluster(podCIDRs ...string) *clusterv1.Cluster {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+	if len(podCIDRs) > 0 {
+		cluster.Spec.ClusterNetwork = &clusterv1.ClusterNetwork{
+			Pods: &clusterv1.NetworkRanges{CIDRBlocks: podCIDRs},
+		}
+	}
+	return cluster
+}
+
+func testClusterWithControlPlaneEndpoint() *clusterv1.Cluster {
+	cluster := testCluster("192.168.0.0/16")
+	cluster.Spec.ControlPlaneEndpoint = clusterv1.APIEndpoint{Host: "10.0.0.10", Port: 6443}
+	return cluster
+}
+
+//nolint:funlen // Long tests are OK
+func TestNewCiliumOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                string
+		cluster             *clusterv1.Cluster
+		cilium              *v1alpha1.CiliumConfig
+		expectedValues      map[string]interface{}
+		expectedAgentConfig map[string]string
+		expectedErr         string
+	}{{
+		name:                "no Cilium configuration",
+		cluster:             testCluster("192.168.0.0/16"),
+		expectedValues:      map[string]interface{}{},
+		expectedAgentConfig: map[string]string{},
+	}, {
+		name:    "IPv6 cluster without Cilium configuration",
+		cluster: testCluster("fd00:100:96::/48"),
+		expectedValues: map[string]interface{}{
+			"ipv4": map[string]interface{}{"enabled": false},
+			"ipv6": map[string]interface{}{"enabled": true},
+		},
+		expectedAgentConfig: map[string]string{
+			"enable-ipv4": "false",
+			"enable-ipv6": "true",
+		},
+	}, {
+		name:    "dual-stack cluster with native routing mode",
+		cluster: testCluster("192.168.0.0/16", "fd00:100:96::/48"),
+		cilium:  &v1alpha1.CiliumConfig{RoutingMode: v1alpha1.CiliumRoutingModeNative},
+		expectedValues: map[string]interface{}{
+			"ipv6":                  map[string]interface{}{"enabled": true},
+			"routingMode":           "native",
+			"autoDirectNodeRoutes":  true,
+			"ipv4NativeRoutingCIDR": "192.168.0.0/16",
+			"ipv6NativeRoutingCIDR": "fd00:100:96::/48",
+		},
+		expectedAgentConfig: map[string]string{
+			"enable-ipv6":              "true",
+			"routing-mode":             "native",
+			"auto-direct-node-routes":  "true",
+			"ipv4-native-routing-cidr": "192.168.0.0/16",
+			"ipv6-native-routing-cidr": "fd00:100:96::/48",
+		},
+	}, {
+		name:    "tunnel routing mode with Geneve",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			RoutingMode:    v1alpha1.CiliumRoutingModeTunnel,
+			TunnelProtocol: v1alpha1.CiliumTunnelProtocolGeneve,
+		},
+		expectedValues: map[string]interface{}{
+			"routingMode":    "tunnel",
+			"tunnelProtocol": "geneve",
+		},
+		expectedAgentConfig: map[string]string{
+			"routing-mode":    "tunnel",
+			"tunnel-protocol": "geneve",
+		},
+	}, {
+		name:    "native routing mode",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium:  &v1alpha1.CiliumConfig{RoutingMode: v1alpha1.CiliumRoutingModeNative},
+		expectedValues: map[string]interface{}{
+			"routingMode":           "native",
+			"autoDirectNodeRoutes":  true,
+			"ipv4NativeRoutingCIDR": "192.168.0.0/16",
+		},
+		expectedAgentConfig: map[string]string{
+			"routing-mode":             "native",
+			"auto-direct-node-routes":  "true",
+			"ipv4-native-routing-cidr": "192.168.0.0/16",
+		},
+	}, {
+		name:        "native routing mode without Pods network CIDR",
+		cluster:     testCluster(),
+		cilium:      &v1alpha1.CiliumConfig{RoutingMode: v1alpha1.CiliumRoutingModeNative},
+		expectedErr: "native routing mode requires the cluster to have a Pods network CIDR",
+	}, {
+		name:    "native routing mode with tunnel protocol",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			RoutingMode:    v1alpha1.CiliumRoutingModeNative,
+			TunnelProtocol: v1alpha1.CiliumTunnelProtocolVXLAN,
+		},
+		expectedErr: "tunnelProtocol can only be set with the tunnel routing mode",
+	}, {
+		name:    "kube-proxy replacement",
+		cluster: testClusterWithControlPlaneEndpoint(),
+		cilium:  &v1alpha1.CiliumConfig{KubeProxyReplacement: true},
+		expectedValues: map[string]interface{}{
+			"kubeProxyReplacement": "true",
+			"k8sServiceHost":       "10.0.0.10",
+			"k8sServicePort":       int32(6443),
+		},
+		expectedAgentConfig: map[string]string{
+			"kube-proxy-replacement": "true",
+		},
+	}, {
+		name:        "kube-proxy replacement without control plane endpoint",
+		cluster:     testCluster("192.168.0.0/16"),
+		cilium:      &v1alpha1.CiliumConfig{KubeProxyReplacement: true},
+		expectedErr: "kube-proxy replacement requires the cluster to have a control plane endpoint",
+	}, {
+		name:    "Hubble with Relay and UI",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Hubble: &v1alpha1.CiliumHubble{Enabled: true, Relay: true, UI: true},
+		},
+		expectedValues: map[string]interface{}{
+			"hubble": map[string]interface{}{
+				"enabled": true,
+				"relay":   map[string]interface{}{"enabled": true},
+				"ui":      map[string]interface{}{"enabled": true},
+			},
+		},
+		expectedAgentConfig: map[string]string{
+			"enable-hubble": "true",
+		},
+	}, {
+		name:    "Hubble Relay without Hubble",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Hubble: &v1alpha1.CiliumHubble{Relay: true},
+		},
+		expectedErr: "hubble relay requires hubble to be enabled",
+	}, {
+		name:    "Hubble UI without Relay",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Hubble: &v1alpha1.CiliumHubble{Enabled: true, UI: true},
+		},
+		expectedErr: "hubble UI requires hubble relay to be deployed",
+	}, {
+		name:    "WireGuard encryption",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Encryption: &v1alpha1.CiliumEncryption{Type: v1alpha1.CiliumEncryptionTypeWireGuard},
+		},
+		expectedValues: map[string]interface{}{
+			"encryption": map[string]interface{}{
+				"enabled": true,
+				"type":    "wireguard",
+			},
+		},
+		expectedAgentConfig: map[string]string{
+			"enable-wireguard": "true",
+		},
+	}, {
+		name:    "WireGuard encryption with IPsec key",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Encryption: &v1alpha1.CiliumEncryption{
+				Type:              v1alpha1.CiliumEncryptionTypeWireGuard,
+				IPsecKeySecretRef: &corev1.LocalObjectReference{Name: "ipsec-key"},
+			},
+		},
+		expectedErr: "ipsecKeySecretRef can only be set with the IPsec encryption",
+	}, {
+		name:    "IPsec encryption",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Encryption: &v1alpha1.CiliumEncryption{
+				Type:              v1alpha1.CiliumEncryptionTypeIPsec,
+				IPsecKeySecretRef: &corev1.LocalObjectReference{Name: "ipsec-key"},
+			},
+		},
+		expectedValues: map[string]interface{}{
+			"encryption": map[string]interface{}{
+				"enabled": true,
+				"type":    "ipsec",
+				"ipsec": map[string]interface{}{
+					"secretName": "cilium-ipsec-keys",
+					"keyFile":    "keys",
+					"mountPath":  "/etc/ipsec",
+				},
+			},
+		},
+		expectedAgentConfig: map[string]string{
+			"enable-ipsec":   "true",
+			"ipsec-key-file": "/etc/ipsec/keys",
+		},
+	}, {
+		name:    "IPsec encryption without key",
+		cluster: testCluster("192.168.0.0/16"),
+		cilium: &v1alpha1.CiliumConfig{
+			Encryption: &v1alpha1.CiliumEncryption{Type: v1alpha1.CiliumEncryptionTypeIPsec},
+		},
+		expectedErr: "ipsec encryption requires ipsecKeySecretRef to be set",
+	}}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := newCiliumOptions(tt.cluster, tt.cilium)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, opts.values)
+			assert.Equal(t, tt.expectedAgentConfig, opts.agentConfig)
+		})
+	}
+}
+
+func TestSetManifests(t *testing.T) {
+	t.Parallel()
+
+	opts, err := newCiliumOptions(
+		testCluster("192.168.0.0/16"),
+		&v1alpha1.CiliumConfig{TunnelProtocol: v1alpha1.CiliumTunnelProtocolGeneve},
+	)
+	require.NoError(t, err)
+
+	defaultData := map[string]string{
+		"cilium.json": `[{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"cilium"}},` +
+			`{"apiVersion":"v1","data":{"routing-mode":"tunnel","tunnel-protocol":"vxlan"},"kind":"ConfigMap",` +
+			`"metadata":{"name":"cilium-config","namespace":"kube-system"}}]`,
+	}
+	data, err := opts.setManifests(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cilium"},
+		Data:       defaultData,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cilium.json": `[{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"cilium"}},` +
+			`{"apiVersion":"v1","data":{"routing-mode":"tunnel","tunnel-protocol":"geneve"},"kind":"ConfigMap",` +
+			`"metadata":{"name":"cilium-config","namespace":"kube-system"}}]`,
+	}, data)
+	assert.Contains(t, defaultData["cilium.json"], `"tunnel-protocol":"vxlan"`, "the default data must not be modified")
+
+	_, err = opts.setManifests(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cilium"},
+		Data:       map[string]string{"cilium.yaml": "apiVersion: v1\nkind: ServiceAccount\n"},
+	})
+	assert.EqualError(t, err, "missing cilium-config ConfigMap in ConfigMap cilium")
+}
+
+func TestSetManifestsKubeProxyReplacement(t *testing.T) {
+	t.Parallel()
+
+	opts, err := newCiliumOptions(
+		testClusterWithControlPlaneEndpoint(),
+		&v1alpha1.CiliumConfig{KubeProxyReplacement: true},
+	)
+	require.NoError(t, err)
+
+	data, err := opts.setManifests(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cilium"},
+		Data: map[string]string{
+			"cilium.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cilium-config
+  namespace: kube-system
+data:
+  kube-proxy-replacement: "false"
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: cilium
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: cilium-agent
+        env:
+        - name: K8S_NODE_NAME
+          value: node
+        - name: KUBERNETES_SERVICE_HOST
+          value: kubernetes.default.svc
+      initContainers:
+      - name: config
+`,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cilium.yaml": `apiVersion: v1
+data:
+  kube-proxy-replacement: "true"
+kind: ConfigMap
+metadata:
+  name: cilium-config
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: cilium
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: K8S_NODE_NAME
+          value: node
+        - name: KUBERNETES_SERVICE_HOST
+          value: 10.0.0.10
+        - name: KUBERNETES_SERVICE_PORT
+          value: "6443"
+        name: cilium-agent
+      initContainers:
+      - env:
+        - name: KUBERNETES_SERVICE_HOST
+          value: 10.0.0.10
+        - name: KUBERNETES_SERVICE_PORT
+          value: "6443"
+        name: config`,
+	}, data)
+}
+
+func TestSetManifestsIPsec(t *testing.T) {
+	t.Parallel()
+
+	opts, err := newCiliumOptions(
+		testCluster("192.168.0.0/16"),
+		&v1alpha1.CiliumConfig{
+			Encryption: &v1alpha1.CiliumEncryption{
+				Type:              v1alpha1.CiliumEncryptionTypeIPsec,
+				IPsecKeySecretRef: &corev1.LocalObjectReference{Name: "ipsec-key"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "ipsec-key", opts.ipsecKeySecretName)
+
+	data, err := opts.setManifests(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cilium"},
+		Data: map[string]string{
+			"cilium.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cilium-config
+  namespace: kube-system
+data:
+  enable-ipsec: "false"
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: cilium
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: cilium-agent
+        volumeMounts:
+        - mountPath: /var/run/cilium
+          name: cilium-run
+      volumes:
+      - hostPath:
+          path: /var/run/cilium
+        name: cilium-run
+`,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"cilium.yaml": `apiVersion: v1
+data:
+  enable-ipsec: "true"
+  ipsec-key-file: /etc/ipsec/keys
+kind: ConfigMap
+metadata:
+  name: cilium-config
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: cilium
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: cilium-agent
+        volumeMounts:
+        - mountPath: /var/run/cilium
+          name: cilium-run
+        - mountPath: /etc/ipsec
+          name: cilium-ipsec-secrets
+          readOnly: true
+      volumes:
+      - hostPath:
+          path: /var/run/cilium
+        name: cilium-run
+      - name: cilium-ipsec-secrets
+        secret:
+          secretName: cilium-ipsec-keys`,
+	}, data)
+}
+
+func TestSetManifestsHubbleRelay(t *testing.T) {
+	t.Parallel()
+
+	opts, err := newCiliumOptions(
+		testCluster("192.168.0.0/16"),
+		&v1alpha1.CiliumConfig{
+			Hubble: &v1alpha1.CiliumHubble{Enabled: true, Relay: true},
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = opts.setManifests(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cilium"},
+		Data:       map[string]string{},
+	})
+	assert.EqualError(t, err, "hubble relay and UI can only be deployed with the HelmAddon strategy")
+}
+
+func TestHelmAddonInstallationValues(t *testing.T) {
+	t.Parallel()
+
+	s := helmAddonStrategy{
+		client: fake.NewClientBuilder().Build(),
+		values: &v1alpha1.AddonValues{
+			Inline: &apiextensionsv1.JSON{Raw: []byte(`{"tunnelProtocol":"vxlan"}`)},
+		},
+	}
+	cluster := testCluster("192.168.0.0/16")
+	opts, err := newCiliumOptions(cluster, &v1alpha1.CiliumConfig{
+		RoutingMode:    v1alpha1.CiliumRoutingModeTunnel,
+		TunnelProtocol: v1alpha1.CiliumTunnelProtocolGeneve,
+	})
+	require.NoError(t, err)
+
+	values, err := s.installationValues(
+		context.Background(),
+		cluster,
+		"cluster:\n  name: {{ .Cluster.metadata.name }}\n",
+		opts,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `cluster:
+  name: test-cluster
+routingMode: tunnel
+tunnelProtocol: vxlan
+`, values, "the values override must take precedence over the Cilium configuration")
+}
@@ -11,9 +11,10 @@ import (
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 )
@@ -35,11 +36,12 @@ type crsStrategy struct {
 	config crsConfig
 
 	client ctrlclient.Client
+	cilium *v1alpha1.CiliumConfig
 }
 
 func (s crsStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -63,9 +65,12 @@ func (s crsStrategy) apply(
 		return fmt.Errorf("failed to get default Cilium ConfigMap: %w", err)
 	}
 
-	log.Info("Ensuring Cilium installation CRS and ConfigMap exist for cluster")
+	opts, err := newCiliumOptions(cluster, s.cilium)
+	if err != nil {
+		return fmt.Errorf("invalid Cilium configuration: %w", err)
+	}
 
-	cluster := &req.Cluster
+	log.Info("Ensuring Cilium installation CRS and ConfigMap exist for cluster")
 
 	cm := &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
@@ -74,12 +79,25 @@ func (s crsStrategy) apply(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      "cilium-cni-installation-" + cluster.Name,
+			Name:      installationName(cluster),
 		},
 		Data:       defaultCiliumConfigMap.Data,
 		BinaryData: defaultCiliumConfigMap.BinaryData,
 	}
 
+	cm.Data, err = opts.setManifests(cm)
+	if err != nil {
+		return fmt.Errorf("failed to set Cilium configuration in Cilium CNI installation ConfigMap: %w", err)
+	}
+
+	if err := utils.RewriteConfigMapImagesForMirror(cluster, cm); err != nil {
+		return fmt.Errorf("failed to rewrite Cilium CNI installation ConfigMap for registry mirror: %w", err)
+	}
+
+	if err := opts.copyIPsecKeySecret(ctx, s.client, cluster); err != nil {
+		return err
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, cm); err != nil {
 		return fmt.Errorf(
 			"failed to apply Cilium CNI installation ConfigMap: %w",
@@ -87,12 +105,33 @@ func (s crsStrategy) apply(
 		)
 	}
 
-	if err := utils.EnsureCRSForClusterFromObjects(ctx, cm.Name, s.client, cluster, cm); err != nil {
+	if err := utils.EnsureCRSForClusterFromObjects(
+		ctx,
+		cm.Name,
+		s.client,
+		cluster,
+		utils.AddonLabels(cluster, utils.AddonCNI),
+		cm,
+	); err != nil {
 		return fmt.Errorf(
 			"failed to apply Cilium CNI installation ClusterResourceSet: %w",
 			err,
 		)
 	}
 
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	if err := utils.MigrateAddonHelmChartProxiesToClusterResourceSet(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: cm.Name},
+	); err != nil {
+		return fmt.Errorf("failed to migrate Cilium CNI installation from HelmChartProxy: %w", err)
+	}
+
 	return nil
 }
@@ -12,13 +12,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 )
 
 const (
@@ -43,11 +44,13 @@ type helmAddonStrategy struct {
 	config    helmAddonConfig
 	client    ctrlclient.Client
 	helmChart *config.HelmChart
+	values    *v1alpha1.AddonValues
+	cilium    *v1alpha1.CiliumConfig
 }
 
 func (s helmAddonStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *capiv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -60,35 +63,69 @@ func (s helmAddonStrategy) apply(
 		)
 	}
 
+	opts, err := newCiliumOptions(cluster, s.cilium)
+	if err != nil {
+		return fmt.Errorf("invalid Cilium configuration: %w", err)
+	}
+
+	values, err := s.installationValues(ctx, cluster, valuesTemplateConfigMap.Data[utils.ValuesConfigMapKey], opts)
+	if err != nil {
+		return fmt.Errorf("failed to apply Cilium CNI installation values override: %w", err)
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	if err := utils.MigrateAddonClusterResourceSetsToHelmRelease(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		utils.HelmRelease{Name: defaultCiliumReleaseName, Namespace: defaultCiliumNamespace},
+	); err != nil {
+		return fmt.Errorf("failed to migrate Cilium CNI installation from ClusterResourceSet: %w", err)
+	}
+
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: caaphv1.GroupVersion.String(),
 			Kind:       "HelmChartProxy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: req.Cluster.Namespace,
-			Name:      "cilium-cni-installation-" + req.Cluster.Name,
+			Namespace: cluster.Namespace,
+			Name:      installationName(cluster),
+			Labels:    utils.AddonLabels(cluster, utils.AddonCNI),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   s.helmChart.Repository,
 			ChartName: s.helmChart.Name,
 			ClusterSelector: metav1.LabelSelector{
-				MatchLabels: map[string]string{capiv1.ClusterNameLabel: req.Cluster.Name},
+				MatchLabels: map[string]string{capiv1.ClusterNameLabel: cluster.Name},
 			},
 			ReleaseNamespace: defaultCiliumNamespace,
 			ReleaseName:      defaultCiliumReleaseName,
 			Version:          s.helmChart.Version,
-			ValuesTemplate:   valuesTemplateConfigMap.Data["values.yaml"],
+			Credentials:      s.helmChart.Credentials(),
+			TLSConfig:        s.helmChart.TLSConfig(),
+			ValuesTemplate:   values,
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(&req.Cluster, hcp, s.client.Scheme()); err != nil {
+	if err := controllerutil.SetOwnerReference(cluster, hcp, s.client.Scheme()); err != nil {
 		return fmt.Errorf(
 			"failed to set owner reference on Cilium CNI installation HelmChartProxy: %w",
 			err,
 		)
 	}
 
+	if err := utils.RewriteHelmChartProxyForMirror(ctx, s.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite Cilium CNI installation HelmChartProxy for registry mirror: %w", err)
+	}
+
+	if err := opts.copyIPsecKeySecret(ctx, s.client, cluster); err != nil {
+		return err
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply Cilium CNI installation HelmChartProxy: %w", err)
 	}
@@ -96,6 +133,30 @@ func (s helmAddonStrategy) apply(
 	return nil
 }
 
+// installationValues returns the installation values template with the Cilium configuration and then the values
+// override applied.
+func (s helmAddonStrategy) installationValues(
+	ctx context.Context,
+	cluster *capiv1.Cluster,
+	valuesTemplate string,
+	opts *ciliumOptions,
+) (string, error) {
+	if len(opts.values) == 0 {
+		return utils.ApplyValuesOverride(ctx, s.client, cluster, valuesTemplate, s.values)
+	}
+
+	rendered, err := utils.RenderValuesTemplate(ctx, s.client, cluster, valuesTemplate)
+	if err != nil {
+		return "", err
+	}
+	values, err := utils.MergeValues(rendered, opts.values)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.MergeValuesOverride(ctx, s.client, cluster, values, s.values)
+}
+
 func (s helmAddonStrategy) retrieveValuesTemplateConfigMap(
 	ctx context.Context,
 	defaultsNamespace string,
@@ -5,11 +5,19 @@ package cni
 
 import (
 	"errors"
+	"fmt"
 
+	netutils "k8s.io/utils/net"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
-var ErrMultiplePodsCIDRBlocks = errors.New("cluster has more than 1 Pods network CIDR blocks")
+var (
+	ErrMultiplePodsCIDRBlocks     = errors.New("cluster has more than 1 Pods network CIDR blocks")
+	ErrTooManyPodsCIDRBlocks      = errors.New("cluster has more than 2 Pods network CIDR blocks")
+	ErrSameIPFamilyPodsCIDRBlocks = errors.New(
+		"cluster has more than 1 Pods network CIDR blocks of the same IP family",
+	)
+)
 
 // PodCIDR will return the Pods network CIDR.
 // If not set returns an empty string.
@@ -29,3 +37,36 @@ func PodCIDR(cluster *capiv1.Cluster) (string, error) {
 		return "", nil
 	}
 }
+
+// PodCIDRs will return the IPv4 and IPv6 Pods network CIDRs of a single-stack or dual-stack cluster.
+// The CIDR of an IP family is an empty string if not set.
+// If more than 2 CIDRBlocks, or 2 CIDRBlocks of the same IP family, are defined will return an error.
+func PodCIDRs(cluster *capiv1.Cluster) (ipv4CIDR, ipv6CIDR string, err error) {
+	var subnets []string
+	if cluster.Spec.ClusterNetwork != nil &&
+		cluster.Spec.ClusterNetwork.Pods != nil {
+		subnets = cluster.Spec.ClusterNetwork.Pods.CIDRBlocks
+	}
+	if len(subnets) > 2 {
+		return "", "", ErrTooManyPodsCIDRBlocks
+	}
+
+	for _, subnet := range subnets {
+		switch netutils.IPFamilyOfCIDRString(subnet) {
+		case netutils.IPv4:
+			if ipv4CIDR != "" {
+				return "", "", ErrSameIPFamilyPodsCIDRBlocks
+			}
+			ipv4CIDR = subnet
+		case netutils.IPv6:
+			if ipv6CIDR != "" {
+				return "", "", ErrSameIPFamilyPodsCIDRBlocks
+			}
+			ipv6CIDR = subnet
+		default:
+			return "", "", fmt.Errorf("invalid Pods network CIDR block %q", subnet)
+		}
+	}
+
+	return ipv4CIDR, ipv6CIDR, nil
+}
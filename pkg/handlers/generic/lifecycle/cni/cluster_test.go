@@ -74,3 +74,61 @@ func Test_PodCIDR(t *testing.T) {
 		})
 	}
 }
+
+func Test_PodCIDRs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		podCIDRs     []string
+		wantIPv4CIDR string
+		wantIPv6CIDR string
+		wantErr      error
+	}{
+		{
+			name: "no Pods CIDR set",
+		},
+		{
+			name:         "IPv4 Pods CIDR set",
+			podCIDRs:     []string{"192.168.0.0/16"},
+			wantIPv4CIDR: "192.168.0.0/16",
+		},
+		{
+			name:         "IPv6 Pods CIDR set",
+			podCIDRs:     []string{"fd00:100:96::/48"},
+			wantIPv6CIDR: "fd00:100:96::/48",
+		},
+		{
+			name:         "dual-stack Pods CIDRs set",
+			podCIDRs:     []string{"fd00:100:96::/48", "192.168.0.0/16"},
+			wantIPv4CIDR: "192.168.0.0/16",
+			wantIPv6CIDR: "fd00:100:96::/48",
+		},
+		{
+			name:     "error: multiple IPv4 Pods CIDRs set",
+			podCIDRs: []string{"192.168.0.0/16", "10.0.0.0/16"},
+			wantErr:  ErrSameIPFamilyPodsCIDRBlocks,
+		},
+		{
+			name:     "error: more than 2 Pods CIDRs set",
+			podCIDRs: []string{"192.168.0.0/16", "fd00:100:96::/48", "10.0.0.0/16"},
+			wantErr:  ErrTooManyPodsCIDRBlocks,
+		},
+	}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cluster := &v1beta1.Cluster{}
+			if tt.podCIDRs != nil {
+				cluster.Spec.ClusterNetwork = &v1beta1.ClusterNetwork{
+					Pods: &v1beta1.NetworkRanges{CIDRBlocks: tt.podCIDRs},
+				}
+			}
+			ipv4CIDR, ipv6CIDR, err := PodCIDRs(cluster)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantIPv4CIDR, ipv4CIDR)
+			assert.Equal(t, tt.wantIPv6CIDR, ipv6CIDR)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package custom provides a handler for managing the deployment of a CNI provided by the user on clusters, either
+// from manifests held by ConfigMaps and Secrets in the cluster namespace or from a Helm chart, configurable via
+// variables on the Cluster resource. The ClusterResourceSet or HelmChartProxy deploying the CNI is owned by the
+// Cluster and so is deleted together with it.
+//
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=clusterresourcesets,verbs=watch;list;get;create;patch;update;delete
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=watch;list;get;create;patch;update;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=watch;list;get
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=watch;list;get
+package custom
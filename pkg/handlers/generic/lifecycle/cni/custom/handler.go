@@ -0,0 +1,184 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	commonhandlers "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+)
+
+const addonName = "Custom CNI"
+
+var (
+	errMissingCustomConfig = errors.New("custom CNI configuration must be set with the Custom CNI provider")
+	errMissingResources    = errors.New(
+		"custom CNI resources must be set with the ClusterResourceSet strategy",
+	)
+	errMissingHelmChart = errors.New("custom CNI Helm chart must be set with the HelmAddon strategy")
+)
+
+type addonStrategy interface {
+	apply(
+		context.Context,
+		*clusterv1.Cluster,
+		logr.Logger,
+	) error
+}
+
+type CustomCNI struct {
+	client   ctrlclient.Client
+	recorder record.EventRecorder
+
+	variableName string
+	variablePath []string
+}
+
+var (
+	_ commonhandlers.Named                   = &CustomCNI{}
+	_ lifecycle.AfterControlPlaneInitialized = &CustomCNI{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &CustomCNI{}
+)
+
+func New(
+	c ctrlclient.Client,
+	recorder record.EventRecorder,
+) *CustomCNI {
+	return &CustomCNI{
+		client:       c,
+		recorder:     recorder,
+		variableName: clusterconfig.MetaVariableName,
+		variablePath: []string{"addons", v1alpha1.CNIVariableName},
+	}
+}
+
+func (c *CustomCNI) Name() string {
+	return "CustomCNI"
+}
+
+func (c *CustomCNI) AfterControlPlaneInitialized(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CustomCNI) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CustomCNI) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
+
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		clusterKey,
+	)
+
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
+
+	cniVar, found, err := variables.Get[v1alpha1.CNI](varMap, c.variableName, c.variablePath...)
+	if err != nil {
+		log.Error(
+			err,
+			"failed to read CNI provider from cluster definition",
+		)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(
+			fmt.Sprintf("failed to read CNI provider from cluster definition: %v",
+				err,
+			),
+		)
+		return
+	}
+	if !found {
+		log.Info("Skipping Custom CNI handler, cluster does not specify request CNI addon deployment")
+		events.AddonSkipped(c.recorder, cluster, addonName, "cluster does not specify a CNI addon")
+		return
+	}
+	if cniVar.Provider != v1alpha1.CNIProviderCustom {
+		log.Info(
+			fmt.Sprintf(
+				"Skipping Custom CNI handler, cluster does not specify %q as value of CNI provider variable",
+				v1alpha1.CNIProviderCustom,
+			),
+		)
+		return
+	}
+
+	strategy, err := c.strategy(cniVar)
+	if err != nil {
+		events.AddonDeploymentFailed(c.recorder, cluster, addonName, err)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(err.Error())
+		return
+	}
+
+	if err := strategy.apply(ctx, cluster, log); err != nil {
+		events.AddonDeploymentFailed(c.recorder, cluster, addonName, err)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(err.Error())
+		return
+	}
+
+	events.AddonDeployed(c.recorder, cluster, addonName)
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+}
+
+// strategy returns the addon strategy deploying the custom CNI, checking that the custom CNI configuration required
+// by the strategy is set.
+func (c *CustomCNI) strategy(cniVar v1alpha1.CNI) (addonStrategy, error) {
+	if cniVar.Custom == nil {
+		return nil, errMissingCustomConfig
+	}
+
+	switch cniVar.Strategy {
+	case v1alpha1.AddonStrategyClusterResourceSet:
+		if len(cniVar.Custom.Resources) == 0 {
+			return nil, errMissingResources
+		}
+		return crsStrategy{
+			client:    c.client,
+			resources: cniVar.Custom.Resources,
+		}, nil
+	case v1alpha1.AddonStrategyHelmAddon:
+		if cniVar.Custom.HelmChart == nil {
+			return nil, errMissingHelmChart
+		}
+		return helmAddonStrategy{
+			client:    c.client,
+			helmChart: cniVar.Custom.HelmChart,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown CNI addon deployment strategy %q", cniVar.Strategy)
+	}
+}
+
+// installationName returns the name of the ClusterResourceSet or HelmChartProxy deploying the custom CNI.
+func installationName(cluster *clusterv1.Cluster) string {
+	return "custom-cni-installation-" + cluster.Name
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+func testCluster(t *testing.T, cni *v1alpha1.CNI) *clusterv1.Cluster {
+	t.Helper()
+
+	v := capitest.VariableWithValue(clusterconfig.MetaVariableName, cni, "addons", v1alpha1.CNIVariableName)
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster", UID: "test-uid"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{
+				Variables: []clusterv1.ClusterVariable{{Name: v.Name, Value: v.Value}},
+			},
+		},
+	}
+}
+
+func testClient(t *testing.T, objs ...ctrlclient.Object) ctrlclient.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, clusterv1.AddToScheme(scheme))
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		// The fake client does not support server-side apply, so applied objects are created instead.
+		Patch: func(
+			ctx context.Context,
+			c ctrlclient.WithWatch,
+			obj ctrlclient.Object,
+			patch ctrlclient.Patch,
+			opts ...ctrlclient.PatchOption,
+		) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			return c.Create(ctx, obj)
+		},
+	}).Build()
+}
+
+func afterControlPlaneInitialized(
+	t *testing.T,
+	client ctrlclient.Client,
+	cluster *clusterv1.Cluster,
+) *runtimehooksv1.AfterControlPlaneInitializedResponse {
+	t.Helper()
+
+	resp := &runtimehooksv1.AfterControlPlaneInitializedResponse{}
+	New(client, record.NewFakeRecorder(10)).AfterControlPlaneInitialized(
+		context.Background(),
+		&runtimehooksv1.AfterControlPlaneInitializedRequest{Cluster: *cluster},
+		resp,
+	)
+	return resp
+}
+
+func TestClusterResourceSetStrategy(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, &v1alpha1.CNI{
+		Provider: v1alpha1.CNIProviderCustom,
+		Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+		Custom: &v1alpha1.CustomCNI{
+			Resources: []v1alpha1.CustomCNIResourceRef{
+				{Kind: v1alpha1.CustomCNIResourceKindConfigMap, Name: "flannel"},
+				{Kind: v1alpha1.CustomCNIResourceKindSecret, Name: "flannel-config"},
+			},
+		},
+	})
+	client := testClient(
+		t,
+		cluster,
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "flannel"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "flannel-config"}},
+	)
+
+	resp := afterControlPlaneInitialized(t, client, cluster)
+	require.Equal(t, runtimehooksv1.ResponseStatusSuccess, resp.Status, resp.Message)
+
+	crs := &crsv1.ClusterResourceSet{}
+	require.NoError(
+		t,
+		client.Get(
+			context.Background(),
+			ctrlclient.ObjectKey{Namespace: "default", Name: "custom-cni-installation-test-cluster"},
+			crs,
+		),
+	)
+	assert.Equal(t, []crsv1.ResourceRef{
+		{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: "flannel"},
+		{Kind: string(crsv1.SecretClusterResourceSetResourceKind), Name: "flannel-config"},
+	}, crs.Spec.Resources)
+	assert.Equal(t, utils.AddonLabels(cluster, utils.AddonCNI), crs.Labels)
+	require.Len(t, crs.OwnerReferences, 1)
+	assert.Equal(t, cluster.UID, crs.OwnerReferences[0].UID)
+}
+
+func TestClusterResourceSetStrategyMissingResource(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, &v1alpha1.CNI{
+		Provider: v1alpha1.CNIProviderCustom,
+		Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+		Custom: &v1alpha1.CustomCNI{
+			Resources: []v1alpha1.CustomCNIResourceRef{
+				{Kind: v1alpha1.CustomCNIResourceKindSecret, Name: "flannel"},
+			},
+		},
+	})
+	client := testClient(t, cluster)
+
+	resp := afterControlPlaneInitialized(t, client, cluster)
+	assert.Equal(t, runtimehooksv1.ResponseStatusFailure, resp.Status)
+	assert.Contains(t, resp.Message, `failed to get custom CNI Secret "default/flannel"`)
+}
+
+func TestHelmAddonStrategy(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, &v1alpha1.CNI{
+		Provider: v1alpha1.CNIProviderCustom,
+		Strategy: v1alpha1.AddonStrategyHelmAddon,
+		Custom: &v1alpha1.CustomCNI{
+			HelmChart: &v1alpha1.HelmChart{
+				Name:             "antrea",
+				RepoURL:          "https://charts.antrea.io",
+				ChartName:        "antrea",
+				Version:          "1.15.0",
+				ReleaseNamespace: "kube-system",
+				ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+					Inline: "trafficEncapMode: encap\n",
+				},
+			},
+		},
+	})
+	client := testClient(t, cluster)
+
+	resp := afterControlPlaneInitialized(t, client, cluster)
+	require.Equal(t, runtimehooksv1.ResponseStatusSuccess, resp.Status, resp.Message)
+
+	hcp := &caaphv1.HelmChartProxy{}
+	require.NoError(
+		t,
+		client.Get(
+			context.Background(),
+			ctrlclient.ObjectKey{Namespace: "default", Name: "custom-cni-installation-test-cluster"},
+			hcp,
+		),
+	)
+	assert.Equal(t, caaphv1.HelmChartProxySpec{
+		RepoURL:   "https://charts.antrea.io",
+		ChartName: "antrea",
+		ClusterSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{clusterv1.ClusterNameLabel: "test-cluster"},
+		},
+		ReleaseNamespace: "kube-system",
+		ReleaseName:      "antrea",
+		Version:          "1.15.0",
+		ValuesTemplate:   "trafficEncapMode: encap\n",
+	}, hcp.Spec)
+	assert.Equal(t, utils.AddonLabels(cluster, utils.AddonCNI), hcp.Labels)
+	require.Len(t, hcp.OwnerReferences, 1)
+	assert.Equal(t, cluster.UID, hcp.OwnerReferences[0].UID)
+}
+
+func TestHelmAddonStrategyRegistryMirror(t *testing.T) {
+	t.Parallel()
+
+	v := capitest.VariableWithValue(clusterconfig.MetaVariableName, v1alpha1.ClusterConfigSpec{
+		GenericClusterConfig: v1alpha1.GenericClusterConfig{
+			GlobalImageRegistryMirror: &v1alpha1.GlobalImageRegistryMirror{
+				URL:                    "https://registry.example.com/mirror",
+				RewriteAddonReferences: true,
+			},
+			Addons: &v1alpha1.Addons{
+				CNI: &v1alpha1.CNI{
+					Provider: v1alpha1.CNIProviderCustom,
+					Strategy: v1alpha1.AddonStrategyHelmAddon,
+					Custom: &v1alpha1.CustomCNI{
+						HelmChart: &v1alpha1.HelmChart{
+							Name:             "antrea",
+							RepoURL:          "https://charts.antrea.io",
+							ChartName:        "antrea",
+							Version:          "1.15.0",
+							ReleaseNamespace: "kube-system",
+						},
+					},
+				},
+			},
+		},
+	})
+	cluster := testCluster(t, nil)
+	cluster.Spec.Topology.Variables = []clusterv1.ClusterVariable{{Name: v.Name, Value: v.Value}}
+	client := testClient(t, cluster)
+
+	resp := afterControlPlaneInitialized(t, client, cluster)
+	require.Equal(t, runtimehooksv1.ResponseStatusSuccess, resp.Status, resp.Message)
+
+	hcp := &caaphv1.HelmChartProxy{}
+	require.NoError(
+		t,
+		client.Get(
+			context.Background(),
+			ctrlclient.ObjectKey{Namespace: "default", Name: "custom-cni-installation-test-cluster"},
+			hcp,
+		),
+	)
+	assert.Equal(t, "oci://registry.example.com/mirror", hcp.Spec.RepoURL)
+}
+
+func TestInvalidCustomConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cni     *v1alpha1.CNI
+		wantErr error
+	}{{
+		name: "missing custom configuration",
+		cni: &v1alpha1.CNI{
+			Provider: v1alpha1.CNIProviderCustom,
+			Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+		},
+		wantErr: errMissingCustomConfig,
+	}, {
+		name: "missing resources with ClusterResourceSet strategy",
+		cni: &v1alpha1.CNI{
+			Provider: v1alpha1.CNIProviderCustom,
+			Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+			Custom: &v1alpha1.CustomCNI{
+				HelmChart: &v1alpha1.HelmChart{Name: "antrea"},
+			},
+		},
+		wantErr: errMissingResources,
+	}, {
+		name: "missing Helm chart with HelmAddon strategy",
+		cni: &v1alpha1.CNI{
+			Provider: v1alpha1.CNIProviderCustom,
+			Strategy: v1alpha1.AddonStrategyHelmAddon,
+			Custom: &v1alpha1.CustomCNI{
+				Resources: []v1alpha1.CustomCNIResourceRef{
+					{Kind: v1alpha1.CustomCNIResourceKindConfigMap, Name: "antrea"},
+				},
+			},
+		},
+		wantErr: errMissingHelmChart,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cluster := testCluster(t, tt.cni)
+			resp := afterControlPlaneInitialized(t, testClient(t, cluster), cluster)
+			assert.Equal(t, runtimehooksv1.ResponseStatusFailure, resp.Status)
+			assert.Equal(t, tt.wantErr.Error(), resp.Message)
+		})
+	}
+}
+
+func TestSkipOtherProviders(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, &v1alpha1.CNI{
+		Provider: v1alpha1.CNIProviderCalico,
+		Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+	})
+	client := testClient(t, cluster)
+
+	resp := afterControlPlaneInitialized(t, client, cluster)
+	assert.Empty(t, resp.Status)
+
+	crss := &crsv1.ClusterResourceSetList{}
+	require.NoError(t, client.List(context.Background(), crss))
+	assert.Empty(t, crss.Items)
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+type crsStrategy struct {
+	client    ctrlclient.Client
+	resources []v1alpha1.CustomCNIResourceRef
+}
+
+func (s crsStrategy) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	log logr.Logger,
+) error {
+	objs := make([]runtime.Object, 0, len(s.resources))
+	for _, ref := range s.resources {
+		obj, err := s.resource(ctx, cluster, ref)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+	}
+
+	log.Info("Ensuring custom CNI installation CRS exists for cluster")
+
+	if err := utils.EnsureCRSForClusterFromObjects(
+		ctx,
+		installationName(cluster),
+		s.client,
+		cluster,
+		utils.AddonLabels(cluster, utils.AddonCNI),
+		objs...,
+	); err != nil {
+		return fmt.Errorf(
+			"failed to apply custom CNI installation ClusterResourceSet: %w",
+			err,
+		)
+	}
+
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	if err := utils.MigrateAddonHelmChartProxiesToClusterResourceSet(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: installationName(cluster)},
+	); err != nil {
+		return fmt.Errorf("failed to migrate custom CNI installation from HelmChartProxy: %w", err)
+	}
+
+	return nil
+}
+
+// resource returns the referenced ConfigMap or Secret in the cluster namespace, so that a missing resource fails the
+// deployment instead of leaving the ClusterResourceSet waiting for it.
+func (s crsStrategy) resource(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	ref v1alpha1.CustomCNIResourceRef,
+) (runtime.Object, error) {
+	var obj ctrlclient.Object
+	switch ref.Kind {
+	case v1alpha1.CustomCNIResourceKindConfigMap:
+		obj = &corev1.ConfigMap{}
+	case v1alpha1.CustomCNIResourceKindSecret:
+		obj = &corev1.Secret{}
+	default:
+		return nil, fmt.Errorf("unsupported custom CNI resource kind %q", ref.Kind)
+	}
+
+	key := ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: ref.Name}
+	if err := s.client.Get(ctx, key, obj); err != nil {
+		return nil, fmt.Errorf("failed to get custom CNI %s %q: %w", ref.Kind, key, err)
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package custom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+type helmAddonStrategy struct {
+	client    ctrlclient.Client
+	helmChart *v1alpha1.HelmChart
+}
+
+func (s helmAddonStrategy) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	log logr.Logger,
+) error {
+	valuesTemplate, err := utils.HelmChartValuesTemplate(ctx, s.client, cluster, s.helmChart.ValuesTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to get custom CNI Helm chart values template: %w", err)
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	if err := utils.MigrateAddonClusterResourceSetsToHelmRelease(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonCNI,
+		cluster.Namespace,
+		installationName(cluster),
+		utils.HelmRelease{Name: s.helmChart.Name, Namespace: s.helmChart.ReleaseNamespace},
+	); err != nil {
+		return fmt.Errorf("failed to migrate custom CNI installation from ClusterResourceSet: %w", err)
+	}
+
+	log.Info("Ensuring custom CNI installation HelmChartProxy exists for cluster")
+
+	hcp := helmChartProxy(cluster, s.helmChart, valuesTemplate)
+	if err := controllerutil.SetOwnerReference(cluster, hcp, s.client.Scheme()); err != nil {
+		return fmt.Errorf(
+			"failed to set owner reference on custom CNI installation HelmChartProxy: %w",
+			err,
+		)
+	}
+
+	if err := utils.RewriteHelmChartProxyForMirror(ctx, s.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite custom CNI installation HelmChartProxy for registry mirror: %w", err)
+	}
+
+	if err := client.ServerSideApply(ctx, s.client, hcp); err != nil {
+		return fmt.Errorf("failed to apply custom CNI installation HelmChartProxy: %w", err)
+	}
+
+	return nil
+}
+
+func helmChartProxy(
+	cluster *clusterv1.Cluster,
+	helmChart *v1alpha1.HelmChart,
+	valuesTemplate string,
+) *caaphv1.HelmChartProxy {
+	return &caaphv1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: caaphv1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      installationName(cluster),
+			Labels:    utils.AddonLabels(cluster, utils.AddonCNI),
+		},
+		Spec: caaphv1.HelmChartProxySpec{
+			RepoURL:   helmChart.RepoURL,
+			ChartName: helmChart.ChartName,
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			ReleaseNamespace: helmChart.ReleaseNamespace,
+			ReleaseName:      helmChart.Name,
+			Version:          helmChart.Version,
+			ValuesTemplate:   valuesTemplate,
+		},
+	}
+}
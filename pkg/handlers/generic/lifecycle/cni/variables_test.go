@@ -6,6 +6,7 @@ package cni
 import (
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/utils/ptr"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
@@ -66,5 +67,164 @@ func TestVariableValidation(t *testing.T) {
 			},
 			ExpectError: true,
 		},
+		capitest.VariableTestDef{
+			Name: "set with Calico network options",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCalico,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Calico: &v1alpha1.CalicoNetwork{
+							Encapsulation: v1alpha1.CalicoEncapsulationVXLANCrossSubnet,
+							MTU:           ptr.To[int32](8951),
+							BGP:           ptr.To(false),
+							NATOutgoing:   ptr.To(true),
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "set with invalid Calico encapsulation",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCalico,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Calico:   &v1alpha1.CalicoNetwork{Encapsulation: "GRE"},
+					},
+				},
+			},
+			ExpectError: true,
+		},
+		capitest.VariableTestDef{
+			Name: "set with out of range Calico MTU",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCalico,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Calico:   &v1alpha1.CalicoNetwork{MTU: ptr.To[int32](100)},
+					},
+				},
+			},
+			ExpectError: true,
+		},
+		capitest.VariableTestDef{
+			Name: "set with Cilium configuration",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Cilium: &v1alpha1.CiliumConfig{
+							RoutingMode:          v1alpha1.CiliumRoutingModeTunnel,
+							TunnelProtocol:       v1alpha1.CiliumTunnelProtocolGeneve,
+							KubeProxyReplacement: true,
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "set with invalid Cilium routing mode",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Cilium:   &v1alpha1.CiliumConfig{RoutingMode: "eni"},
+					},
+				},
+			},
+			ExpectError: true,
+		},
+		capitest.VariableTestDef{
+			Name: "set with Cilium Hubble and IPsec encryption",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Cilium: &v1alpha1.CiliumConfig{
+							Hubble: &v1alpha1.CiliumHubble{Enabled: true, Relay: true, UI: true},
+							Encryption: &v1alpha1.CiliumEncryption{
+								Type:              v1alpha1.CiliumEncryptionTypeIPsec,
+								IPsecKeySecretRef: &corev1.LocalObjectReference{Name: "cilium-ipsec-key"},
+							},
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "set with invalid Cilium encryption type",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCilium,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Cilium: &v1alpha1.CiliumConfig{
+							Encryption: &v1alpha1.CiliumEncryption{Type: "macsec"},
+						},
+					},
+				},
+			},
+			ExpectError: true,
+		},
+		capitest.VariableTestDef{
+			Name: "set with Custom provider resources",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCustom,
+						Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+						Custom: &v1alpha1.CustomCNI{
+							Resources: []v1alpha1.CustomCNIResourceRef{
+								{Kind: v1alpha1.CustomCNIResourceKindConfigMap, Name: "flannel"},
+								{Kind: v1alpha1.CustomCNIResourceKindSecret, Name: "flannel-config"},
+							},
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "set with Custom provider Helm chart",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCustom,
+						Strategy: v1alpha1.AddonStrategyHelmAddon,
+						Custom: &v1alpha1.CustomCNI{
+							HelmChart: &v1alpha1.HelmChart{
+								Name:             "antrea",
+								RepoURL:          "https://charts.antrea.io",
+								ChartName:        "antrea",
+								Version:          "1.15.0",
+								ReleaseNamespace: "kube-system",
+							},
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "set with invalid Custom provider resource kind",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Provider: v1alpha1.CNIProviderCustom,
+						Strategy: v1alpha1.AddonStrategyClusterResourceSet,
+						Custom: &v1alpha1.CustomCNI{
+							Resources: []v1alpha1.CustomCNIResourceRef{
+								{Kind: "Deployment", Name: "flannel"},
+							},
+						},
+					},
+				},
+			},
+			ExpectError: true,
+		},
 	)
 }
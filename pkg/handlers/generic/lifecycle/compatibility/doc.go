@@ -0,0 +1,8 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package compatibility provides a BeforeClusterUpgrade handler that blocks Kubernetes upgrades of a cluster until
+// every enabled addon can be deployed for the target Kubernetes version.
+//
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=watch;list;get
+package compatibility
@@ -0,0 +1,269 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package compatibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	commonhandlers "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	awsccm "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/ccm/aws"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+)
+
+// retryAfterSeconds is returned when the compatibility of an addon cannot currently be determined, e.g. when the
+// default addon configuration cannot be read.
+const retryAfterSeconds = 30
+
+type AddonCompatibilityGate struct {
+	client              ctrlclient.Client
+	helmChartInfoGetter *config.HelmChartGetter
+	awsCCMConfig        *awsccm.AWSCCMConfig
+}
+
+var (
+	_ commonhandlers.Named           = &AddonCompatibilityGate{}
+	_ lifecycle.BeforeClusterUpgrade = &AddonCompatibilityGate{}
+)
+
+func New(
+	c ctrlclient.Client,
+	helmChartInfoGetter *config.HelmChartGetter,
+	awsCCMConfig *awsccm.AWSCCMConfig,
+) *AddonCompatibilityGate {
+	return &AddonCompatibilityGate{
+		client:              c,
+		helmChartInfoGetter: helmChartInfoGetter,
+		awsCCMConfig:        awsCCMConfig,
+	}
+}
+
+func (g *AddonCompatibilityGate) Name() string {
+	return "AddonCompatibilityGate"
+}
+
+func (g *AddonCompatibilityGate) BeforeClusterUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.BeforeClusterUpgradeRequest,
+	resp *runtimehooksv1.BeforeClusterUpgradeResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		clusterKey,
+		"toKubernetesVersion",
+		req.ToKubernetesVersion,
+	)
+
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+
+	if req.Cluster.Spec.Topology == nil {
+		return
+	}
+
+	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	clusterConfigVar, found, err := variables.Get[v1alpha1.ClusterConfigSpec](
+		varMap,
+		clusterconfig.MetaVariableName,
+	)
+	if err != nil {
+		log.Error(
+			err,
+			"failed to read clusterConfig variable from cluster definition",
+		)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(
+			fmt.Sprintf("failed to read clusterConfig variable from cluster definition: %v",
+				err,
+			),
+		)
+		return
+	}
+	if !found || clusterConfigVar.Addons == nil {
+		log.V(4).Info("Skipping addon compatibility checks, no addons enabled")
+		return
+	}
+
+	var incompatibleAddons []string
+
+	for _, component := range helmChartComponents(&req.Cluster, clusterConfigVar.Addons) {
+		_, err := g.helmChartInfoGetter.For(ctx, log, component, req.ToKubernetesVersion)
+		if errors.Is(err, config.ErrUnsupportedKubernetesVersion) {
+			incompatibleAddons = append(incompatibleAddons, fmt.Sprintf("%s: %v", component, err))
+			continue
+		}
+		if err != nil {
+			log.Error(err, "failed to get helm chart settings", "component", component)
+			resp.SetMessage(
+				fmt.Sprintf("failed to get helm chart settings for %s: %v", component, err),
+			)
+			resp.SetRetryAfterSeconds(retryAfterSeconds)
+			return
+		}
+	}
+
+	if clusterConfigVar.Addons.CCM != nil && infraKindIs(&req.Cluster, v1alpha1.CCMProviderAWS) {
+		incompatible, err := g.checkAWSCCM(ctx, clusterConfigVar.Addons.CCM, req.ToKubernetesVersion, log)
+		if err != nil {
+			resp.SetMessage(err.Error())
+			resp.SetRetryAfterSeconds(retryAfterSeconds)
+			return
+		}
+		if incompatible != "" {
+			incompatibleAddons = append(incompatibleAddons, incompatible)
+		}
+	}
+
+	if len(incompatibleAddons) > 0 {
+		log.Info("Blocking upgrade due to incompatible addons", "addons", incompatibleAddons)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(fmt.Sprintf(
+			"cannot upgrade to Kubernetes %s, incompatible addons: %s",
+			req.ToKubernetesVersion,
+			strings.Join(incompatibleAddons, "; "),
+		))
+	}
+}
+
+// checkAWSCCM returns a non-empty description if the AWS CCM cannot be deployed for the given Kubernetes version.
+// An error is returned if compatibility cannot currently be determined.
+func (g *AddonCompatibilityGate) checkAWSCCM(
+	ctx context.Context,
+	ccm *v1alpha1.CCM,
+	kubernetesVersion string,
+	log logr.Logger,
+) (string, error) {
+	if ccm.Strategy == v1alpha1.AddonStrategyHelmAddon {
+		return g.checkAWSCCMHelmChart(ctx, kubernetesVersion, log)
+	}
+
+	configMapName, err := g.awsCCMConfig.ConfigMapNameForKubernetesVersion(kubernetesVersion)
+	if err != nil {
+		if errors.Is(err, awsccm.ErrUnsupportedKubernetesVersion) {
+			return fmt.Sprintf("%s ccm: %v", v1alpha1.CCMProviderAWS, err), nil
+		}
+		return "", err
+	}
+
+	cm := &corev1.ConfigMap{}
+	objName := ctrlclient.ObjectKey{
+		Namespace: g.awsCCMConfig.DefaultsNamespace(),
+		Name:      configMapName,
+	}
+	if err := g.client.Get(ctx, objName, cm); err != nil {
+		log.Error(err, "failed to fetch AWS CCM ConfigMap for target Kubernetes version")
+		return "", fmt.Errorf(
+			"failed to retrieve default AWS CCM manifests ConfigMap %q: %w",
+			objName,
+			err,
+		)
+	}
+
+	return "", nil
+}
+
+// checkAWSCCMHelmChart returns a non-empty description if there is no AWS CCM chart for the given Kubernetes version,
+// or if the chart does not deploy the AWS CCM image of the same Kubernetes minor version.
+func (g *AddonCompatibilityGate) checkAWSCCMHelmChart(
+	ctx context.Context,
+	kubernetesVersion string,
+	log logr.Logger,
+) (string, error) {
+	helmChart, err := g.helmChartInfoGetter.For(ctx, log, config.AWSCCM, kubernetesVersion)
+	if errors.Is(err, config.ErrUnsupportedKubernetesVersion) {
+		return fmt.Sprintf("%s: %v", config.AWSCCM, err), nil
+	}
+	if err != nil {
+		log.Error(err, "failed to get helm chart settings", "component", config.AWSCCM)
+		return "", fmt.Errorf("failed to get helm chart settings for %s: %w", config.AWSCCM, err)
+	}
+
+	version, err := semver.ParseTolerant(kubernetesVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Kubernetes version %q: %w", kubernetesVersion, err)
+	}
+	imageVersion, err := semver.ParseTolerant(helmChart.ImageTag)
+	if err != nil || imageVersion.Major != version.Major || imageVersion.Minor != version.Minor {
+		return fmt.Sprintf(
+			"%s: chart %s %s does not set an image tag for Kubernetes version %d.%d, got %q",
+			config.AWSCCM,
+			helmChart.Name,
+			helmChart.Version,
+			version.Major,
+			version.Minor,
+			helmChart.ImageTag,
+		), nil
+	}
+
+	return "", nil
+}
+
+// helmChartComponents returns the Helm chart components of all addons that are deployed with the HelmAddon
+// strategy, except for the AWS CCM which is checked by checkAWSCCM.
+func helmChartComponents(cluster *clusterv1.Cluster, addons *v1alpha1.Addons) []config.Component {
+	var components []config.Component
+
+	if addons.CNI != nil && addons.CNI.Strategy == v1alpha1.AddonStrategyHelmAddon {
+		switch addons.CNI.Provider {
+		case v1alpha1.CNIProviderCalico:
+			components = append(components, config.Tigera)
+		case v1alpha1.CNIProviderCilium:
+			components = append(components, config.Cilium)
+		}
+	}
+
+	if addons.NFD != nil && addons.NFD.Strategy == v1alpha1.AddonStrategyHelmAddon {
+		components = append(components, config.NFD)
+	}
+
+	if addons.ClusterAutoscaler != nil &&
+		addons.ClusterAutoscaler.Strategy == v1alpha1.AddonStrategyHelmAddon {
+		components = append(components, config.Autoscaler)
+	}
+
+	if addons.CSIProviders != nil {
+		for _, provider := range addons.CSIProviders.Providers {
+			if provider.Strategy != v1alpha1.AddonStrategyHelmAddon {
+				continue
+			}
+			switch provider.Name {
+			case v1alpha1.CSIProviderAWSEBS:
+				// The snapshot controller is deployed with the Nutanix snapshot chart.
+				components = append(components, config.AWSEBSCSI, config.NutanixSnapshotCSI)
+			case v1alpha1.CSIProviderNutanix:
+				components = append(components, config.NutanixStorageCSI, config.NutanixSnapshotCSI)
+			}
+		}
+	}
+
+	if addons.CCM != nil && infraKindIs(cluster, v1alpha1.CCMProviderNutanix) {
+		components = append(components, config.NutanixCCM)
+	}
+
+	return components
+}
+
+// infraKindIs returns whether the cluster infrastructure kind matches the provider, in the same way as the CCM
+// handler selects the CCM provider.
+func infraKindIs(cluster *clusterv1.Cluster, provider string) bool {
+	if cluster.Spec.InfrastructureRef == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(cluster.Spec.InfrastructureRef.Kind), provider)
+}
@@ -0,0 +1,270 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package compatibility
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	awsccm "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/ccm/aws"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
+)
+
+func newCluster(infraKind string, addons *v1alpha1.Addons) clusterv1.Cluster {
+	clusterConfigVar := capitest.VariableWithValue(
+		clusterconfig.MetaVariableName,
+		v1alpha1.ClusterConfigSpec{
+			GenericClusterConfig: v1alpha1.GenericClusterConfig{Addons: addons},
+		},
+	)
+	return clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "default"},
+		Spec: clusterv1.ClusterSpec{
+			InfrastructureRef: &corev1.ObjectReference{Kind: infraKind},
+			Topology: &clusterv1.Topology{
+				Version: "v1.27.5",
+				Variables: []clusterv1.ClusterVariable{{
+					Name:  clusterConfigVar.Name,
+					Value: clusterConfigVar.Value,
+				}},
+			},
+		},
+	}
+}
+
+//nolint:funlen // Long tests are OK
+func TestBeforeClusterUpgrade(t *testing.T) {
+	t.Parallel()
+
+	globalOptions := options.NewGlobalOptions()
+	awsCCMConfig := &awsccm.AWSCCMConfig{GlobalOptions: globalOptions}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	globalOptions.AddFlags(flags)
+	awsCCMConfig.AddFlags("awsccm", flags)
+	require.NoError(t, flags.Parse(nil))
+
+	helmAddonsConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      globalOptions.HelmAddonsConfigMapName(),
+			Namespace: globalOptions.DefaultsNamespace(),
+		},
+		Data: map[string]string{
+			string(config.Cilium): `
+ChartName: cilium
+ChartVersion: 1.15.0
+RepositoryURL: https://helm.cilium.io/
+KubernetesVersionRange: ">=1.27.0 <1.30.0"
+`,
+			string(config.NFD): `
+ChartName: node-feature-discovery
+ChartVersion: 0.15.2
+RepositoryURL: https://kubernetes-sigs.github.io/node-feature-discovery/charts
+KubernetesVersionRange: ">=1.27.0 <1.29.0"
+`,
+			string(config.Tigera): `
+- ChartName: tigera-operator
+  ChartVersion: v3.26.4
+  RepositoryURL: https://docs.tigera.io/calico/charts
+  KubernetesVersionRange: ">=1.27.0 <1.29.0"
+- ChartName: tigera-operator
+  ChartVersion: v3.27.2
+  RepositoryURL: https://docs.tigera.io/calico/charts
+  KubernetesVersionRange: ">=1.29.0 <1.30.0"
+`,
+			string(config.AWSEBSCSI): `
+ChartName: aws-ebs-csi-driver
+ChartVersion: v2.28.1
+RepositoryURL: https://kubernetes-sigs.github.io/aws-ebs-csi-driver
+`,
+			string(config.NutanixSnapshotCSI): `
+ChartName: nutanix-csi-snapshot
+ChartVersion: v6.3.2
+RepositoryURL: https://nutanix.github.io/helm/
+KubernetesVersionRange: ">=1.27.0 <1.29.0"
+`,
+			string(config.AWSCCM): `
+- ChartName: aws-cloud-controller-manager
+  ChartVersion: 0.0.8
+  RepositoryURL: https://kubernetes.github.io/cloud-provider-aws
+  KubernetesVersionRange: ">=1.27.0 <1.28.0"
+  ImageTag: v1.27.1
+- ChartName: aws-cloud-controller-manager
+  ChartVersion: 0.0.8
+  RepositoryURL: https://kubernetes.github.io/cloud-provider-aws
+  KubernetesVersionRange: ">=1.28.0 <1.29.0"
+  ImageTag: v1.28.1
+- ChartName: aws-cloud-controller-manager
+  ChartVersion: 0.0.8
+  RepositoryURL: https://kubernetes.github.io/cloud-provider-aws
+  KubernetesVersionRange: ">=1.29.0 <1.30.0"
+  ImageTag: v1.28.1
+`,
+		},
+	}
+	awsCCMConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aws-ccm-v1.28.1",
+			Namespace: globalOptions.DefaultsNamespace(),
+		},
+	}
+
+	tests := []struct {
+		name                      string
+		cluster                   clusterv1.Cluster
+		toKubernetesVersion       string
+		expectedStatus            runtimehooksv1.ResponseStatus
+		expectedMessage           string
+		expectedRetryAfterSeconds int32
+	}{{
+		name:                "no addons",
+		cluster:             newCluster("DockerCluster", nil),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "compatible helm chart",
+		cluster: newCluster("DockerCluster", &v1alpha1.Addons{
+			CNI: &v1alpha1.CNI{
+				Provider: v1alpha1.CNIProviderCilium,
+				Strategy: v1alpha1.AddonStrategyHelmAddon,
+			},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "incompatible helm chart",
+		cluster: newCluster("DockerCluster", &v1alpha1.Addons{
+			NFD: &v1alpha1.NFD{Strategy: v1alpha1.AddonStrategyHelmAddon},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusFailure,
+		expectedMessage: `nfd: no chart supports Kubernetes version v1.29.2, ` +
+			`chart node-feature-discovery 0.15.2 supports Kubernetes versions ">=1.27.0 <1.29.0"`,
+	}, {
+		name: "helm chart compatible with a later chart version",
+		cluster: newCluster("DockerCluster", &v1alpha1.Addons{
+			CNI: &v1alpha1.CNI{
+				Provider: v1alpha1.CNIProviderCalico,
+				Strategy: v1alpha1.AddonStrategyHelmAddon,
+			},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "incompatible helm chart deployed with ClusterResourceSet strategy",
+		cluster: newCluster("DockerCluster", &v1alpha1.Addons{
+			NFD: &v1alpha1.NFD{Strategy: v1alpha1.AddonStrategyClusterResourceSet},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "AWS EBS CSI with incompatible snapshot chart",
+		cluster: newCluster("AWSCluster", &v1alpha1.Addons{
+			CSIProviders: &v1alpha1.CSI{
+				Providers: []v1alpha1.CSIProvider{{
+					Name:     v1alpha1.CSIProviderAWSEBS,
+					Strategy: v1alpha1.AddonStrategyHelmAddon,
+				}},
+			},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusFailure,
+		expectedMessage:     "nutanix-snapshot-csi: no chart supports Kubernetes version v1.29.2",
+	}, {
+		name: "missing helm chart settings",
+		cluster: newCluster("DockerCluster", &v1alpha1.Addons{
+			ClusterAutoscaler: &v1alpha1.ClusterAutoscaler{Strategy: v1alpha1.AddonStrategyHelmAddon},
+		}),
+		toKubernetesVersion:       "v1.29.2",
+		expectedStatus:            runtimehooksv1.ResponseStatusSuccess,
+		expectedMessage:           "failed to get helm chart settings for cluster-autoscaler",
+		expectedRetryAfterSeconds: retryAfterSeconds,
+	}, {
+		name:                "compatible AWS CCM",
+		cluster:             newCluster("AWSCluster", &v1alpha1.Addons{CCM: &v1alpha1.CCM{}}),
+		toKubernetesVersion: "v1.28.7",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name:                "AWS CCM without configuration for target version",
+		cluster:             newCluster("AWSCluster", &v1alpha1.Addons{CCM: &v1alpha1.CCM{}}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusFailure,
+		expectedMessage:     "no default AWS CCM configured for Kubernetes version 1.29",
+	}, {
+		name:                      "AWS CCM ConfigMap for target version does not exist",
+		cluster:                   newCluster("AWSCluster", &v1alpha1.Addons{CCM: &v1alpha1.CCM{}}),
+		toKubernetesVersion:       "v1.27.11",
+		expectedStatus:            runtimehooksv1.ResponseStatusSuccess,
+		expectedMessage:           "failed to retrieve default AWS CCM manifests ConfigMap",
+		expectedRetryAfterSeconds: retryAfterSeconds,
+	}, {
+		name: "compatible AWS CCM deployed with HelmAddon strategy",
+		cluster: newCluster("AWSCluster", &v1alpha1.Addons{
+			CCM: &v1alpha1.CCM{Strategy: v1alpha1.AddonStrategyHelmAddon},
+		}),
+		toKubernetesVersion: "v1.27.11",
+		expectedStatus:      runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "AWS CCM deployed with HelmAddon strategy without chart for target version",
+		cluster: newCluster("AWSCluster", &v1alpha1.Addons{
+			CCM: &v1alpha1.CCM{Strategy: v1alpha1.AddonStrategyHelmAddon},
+		}),
+		toKubernetesVersion: "v1.30.1",
+		expectedStatus:      runtimehooksv1.ResponseStatusFailure,
+		expectedMessage:     "aws-ccm: no chart supports Kubernetes version v1.30.1",
+	}, {
+		name: "AWS CCM deployed with HelmAddon strategy with image of another Kubernetes version",
+		cluster: newCluster("AWSCluster", &v1alpha1.Addons{
+			CCM: &v1alpha1.CCM{Strategy: v1alpha1.AddonStrategyHelmAddon},
+		}),
+		toKubernetesVersion: "v1.29.2",
+		expectedStatus:      runtimehooksv1.ResponseStatusFailure,
+		expectedMessage: `aws-ccm: chart aws-cloud-controller-manager 0.0.8 does not set an image tag for ` +
+			`Kubernetes version 1.29, got "v1.28.1"`,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := fake.NewClientBuilder().WithObjects(helmAddonsConfigMap, awsCCMConfigMap).Build()
+			gate := New(
+				c,
+				config.NewHelmChartGetterFromConfigMap(
+					globalOptions.HelmAddonsConfigMapName(),
+					globalOptions.DefaultsNamespace(),
+					c,
+				),
+				awsCCMConfig,
+			)
+
+			resp := &runtimehooksv1.BeforeClusterUpgradeResponse{}
+			gate.BeforeClusterUpgrade(
+				context.Background(),
+				&runtimehooksv1.BeforeClusterUpgradeRequest{
+					Cluster:               tt.cluster,
+					FromKubernetesVersion: tt.cluster.Spec.Topology.Version,
+					ToKubernetesVersion:   tt.toKubernetesVersion,
+				},
+				resp,
+			)
+
+			assert.Equal(t, tt.expectedStatus, resp.GetStatus(), resp.GetMessage())
+			assert.Contains(t, resp.GetMessage(), tt.expectedMessage)
+			assert.Equal(t, tt.expectedRetryAfterSeconds, resp.GetRetryAfterSeconds())
+		})
+	}
+}
@@ -5,19 +5,27 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 
+	"github.com/blang/semver/v4"
 	"github.com/go-logr/logr"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 )
 
 type Component string
 
 const (
 	Autoscaler         Component = "cluster-autoscaler"
+	AWSEBSCSI          Component = "aws-ebs-csi"
+	AWSCCM             Component = "aws-ccm"
 	Tigera             Component = "tigera-operator"
 	Cilium             Component = "cilium"
 	NFD                Component = "nfd"
@@ -26,6 +34,10 @@ const (
 	NutanixCCM         Component = "nutanix-ccm"
 )
 
+// ErrUnsupportedKubernetesVersion is returned when none of the charts configured for a component supports the
+// Kubernetes version of a cluster.
+var ErrUnsupportedKubernetesVersion = errors.New("no chart supports Kubernetes version")
+
 type HelmChartGetter struct {
 	cl          ctrlclient.Reader
 	cmName      string
@@ -36,6 +48,93 @@ type HelmChart struct {
 	Name       string `yaml:"ChartName"`
 	Version    string `yaml:"ChartVersion"`
 	Repository string `yaml:"RepositoryURL"`
+	// KubernetesVersionRange is an optional semver range, e.g. ">=1.27.0 <1.30.0", of the Kubernetes versions
+	// supported by this chart version. An empty range supports all Kubernetes versions.
+	KubernetesVersionRange string `yaml:"KubernetesVersionRange,omitempty"`
+	// ImageTag is the optional image tag deployed by this chart version, for charts whose default image does not
+	// follow the Kubernetes version of the cluster, e.g. the AWS CCM. Combined with KubernetesVersionRange, it allows
+	// configuring the image per Kubernetes minor version. Defaults to the image tag of the chart.
+	ImageTag string `yaml:"ImageTag,omitempty"`
+	// CredentialsSecretName is the optional name of a Secret in the namespace of the helm addons ConfigMap containing
+	// the credentials of the OCI registry hosting the chart, in the Docker config.json format.
+	CredentialsSecretName string `yaml:"CredentialsSecretName,omitempty"`
+	// CredentialsSecretKey is the key of the credentials in the CredentialsSecretName Secret. Defaults to config.json.
+	CredentialsSecretKey string `yaml:"CredentialsSecretKey,omitempty"`
+	// CASecretName is the optional name of a Secret in the namespace of the helm addons ConfigMap containing the CA
+	// bundle used to verify the certificate of the chart repository at the key ca.crt.
+	CASecretName string `yaml:"CASecretName,omitempty"`
+
+	// secretNamespace is the namespace of the helm addons ConfigMap the chart was read from.
+	secretNamespace string
+}
+
+// Credentials returns the credentials to pull the chart with, or nil if the chart does not require credentials.
+func (c *HelmChart) Credentials() *caaphv1.Credentials {
+	if c.CredentialsSecretName == "" {
+		return nil
+	}
+	key := c.CredentialsSecretKey
+	if key == "" {
+		key = caaphv1.DefaultOCIKey
+	}
+	return &caaphv1.Credentials{
+		Secret: corev1.SecretReference{Name: c.CredentialsSecretName, Namespace: c.secretNamespace},
+		Key:    key,
+	}
+}
+
+// TLSConfig returns the TLS configuration to pull the chart with, or nil if the chart repository does not require a
+// custom CA bundle.
+func (c *HelmChart) TLSConfig() *caaphv1.TLSConfig {
+	if c.CASecretName == "" {
+		return nil
+	}
+	return &caaphv1.TLSConfig{
+		CASecretRef: &corev1.SecretReference{Name: c.CASecretName, Namespace: c.secretNamespace},
+	}
+}
+
+// validate checks that the chart repository URL is either an OCI registry or an HTTP repository.
+func (c *HelmChart) validate() error {
+	repositoryURL, err := url.Parse(c.Repository)
+	if err != nil {
+		return fmt.Errorf("failed to parse repository URL %q for chart %s: %w", c.Repository, c.Name, err)
+	}
+	switch repositoryURL.Scheme {
+	case "oci", "http", "https":
+		return nil
+	default:
+		return fmt.Errorf(
+			"unsupported scheme %q in repository URL %q for chart %s, must be one of oci, http or https",
+			repositoryURL.Scheme,
+			c.Repository,
+			c.Name,
+		)
+	}
+}
+
+// SupportsKubernetesVersion returns whether the chart supports the given Kubernetes version.
+func (c *HelmChart) SupportsKubernetesVersion(kubernetesVersion string) (bool, error) {
+	if c.KubernetesVersionRange == "" {
+		return true, nil
+	}
+	versionRange, err := semver.ParseRange(c.KubernetesVersionRange)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to parse Kubernetes version range %q for chart %s: %w",
+			c.KubernetesVersionRange,
+			c.Name,
+			err,
+		)
+	}
+	version, err := semver.ParseTolerant(kubernetesVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse Kubernetes version %q: %w", kubernetesVersion, err)
+	}
+	// Ignore pre-release and build metadata so that e.g. v1.29.0-rc.1 matches a range of >=1.29.0.
+	version.Pre = nil
+	version.Build = nil
+	return versionRange(version), nil
 }
 
 func NewHelmChartGetterFromConfigMap(
@@ -70,10 +169,14 @@ func (h *HelmChartGetter) get(
 	return cm, err
 }
 
+// For returns the chart of the component that supports the given Kubernetes version. The component can be configured
+// either with a single chart, or with a list of charts, e.g. one per Kubernetes minor version, in which case the first
+// chart whose KubernetesVersionRange includes the Kubernetes version is returned.
 func (h *HelmChartGetter) For(
 	ctx context.Context,
 	log logr.Logger,
 	name Component,
+	kubernetesVersion string,
 ) (*HelmChart, error) {
 	log.Info(
 		fmt.Sprintf("Fetching HelmChart info for %s from configmap %s/%s",
@@ -89,7 +192,53 @@ func (h *HelmChartGetter) For(
 	if !ok {
 		return nil, fmt.Errorf("did not find key %s in %v", name, cm.Data)
 	}
-	var settings HelmChart
-	err = yaml.Unmarshal([]byte(d), &settings)
-	return &settings, err
+	charts, err := parseHelmCharts([]byte(d))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HelmChart info for %s: %w", name, err)
+	}
+	if len(charts) == 0 {
+		return nil, fmt.Errorf("no charts configured for %s", name)
+	}
+
+	available := make([]string, 0, len(charts))
+	for i := range charts {
+		chart := &charts[i]
+		supported, err := chart.SupportsKubernetesVersion(kubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !supported {
+			available = append(available, fmt.Sprintf(
+				"%s %s supports Kubernetes versions %q",
+				chart.Name,
+				chart.Version,
+				chart.KubernetesVersionRange,
+			))
+			continue
+		}
+		if err := chart.validate(); err != nil {
+			return nil, err
+		}
+		chart.secretNamespace = h.cmNamespace
+		return chart, nil
+	}
+	return nil, fmt.Errorf(
+		"%w %s, chart %s",
+		ErrUnsupportedKubernetesVersion,
+		kubernetesVersion,
+		strings.Join(available, ", chart "),
+	)
+}
+
+// parseHelmCharts parses either a single chart or a list of charts.
+func parseHelmCharts(data []byte) ([]HelmChart, error) {
+	var charts []HelmChart
+	if err := yaml.Unmarshal(data, &charts); err == nil {
+		return charts, nil
+	}
+	var chart HelmChart
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, err
+	}
+	return []HelmChart{chart}, nil
 }
@@ -0,0 +1,206 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+func TestHelmChart_SupportsKubernetesVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		versionRange      string
+		kubernetesVersion string
+		supported         bool
+		wantErr           bool
+	}{{
+		name:              "no range supports all versions",
+		kubernetesVersion: "v1.29.2",
+		supported:         true,
+	}, {
+		name:              "version within range",
+		versionRange:      ">=1.27.0 <1.30.0",
+		kubernetesVersion: "v1.29.2",
+		supported:         true,
+	}, {
+		name:              "pre-release version within range",
+		versionRange:      ">=1.29.0 <1.30.0",
+		kubernetesVersion: "v1.29.0-rc.1",
+		supported:         true,
+	}, {
+		name:              "version outside range",
+		versionRange:      ">=1.27.0 <1.29.0",
+		kubernetesVersion: "v1.29.2",
+		supported:         false,
+	}, {
+		name:              "invalid range",
+		versionRange:      "not-a-range",
+		kubernetesVersion: "v1.29.2",
+		wantErr:           true,
+	}, {
+		name:              "invalid version",
+		versionRange:      ">=1.27.0",
+		kubernetesVersion: "not-a-version",
+		wantErr:           true,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			chart := &HelmChart{Name: "chart", KubernetesVersionRange: tt.versionRange}
+			supported, err := chart.SupportsKubernetesVersion(tt.kubernetesVersion)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.supported, supported)
+		})
+	}
+}
+
+//nolint:funlen // Long tests are OK
+func TestHelmChartGetter_For(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		data                string
+		kubernetesVersion   string
+		wantErr             string
+		expectedVersion     string
+		expectedImageTag    string
+		expectedCredentials *caaphv1.Credentials
+		expectedTLSConfig   *caaphv1.TLSConfig
+	}{{
+		name: "HTTP repository",
+		data: `ChartName: cilium
+ChartVersion: 1.15.0
+RepositoryURL: https://helm.cilium.io/
+`,
+		expectedVersion: "1.15.0",
+	}, {
+		name: "chart for Kubernetes version",
+		data: `- ChartName: cilium
+  ChartVersion: 1.14.5
+  RepositoryURL: https://helm.cilium.io/
+  KubernetesVersionRange: ">=1.27.0 <1.29.0"
+  ImageTag: v1.14.5
+- ChartName: cilium
+  ChartVersion: 1.15.0
+  RepositoryURL: https://helm.cilium.io/
+  KubernetesVersionRange: ">=1.29.0 <1.30.0"
+  ImageTag: v1.15.0
+`,
+		kubernetesVersion: "v1.28.7",
+		expectedVersion:   "1.14.5",
+		expectedImageTag:  "v1.14.5",
+	}, {
+		name: "no chart for Kubernetes version",
+		data: `- ChartName: cilium
+  ChartVersion: 1.14.5
+  RepositoryURL: https://helm.cilium.io/
+  KubernetesVersionRange: ">=1.27.0 <1.29.0"
+- ChartName: cilium
+  ChartVersion: 1.15.0
+  RepositoryURL: https://helm.cilium.io/
+  KubernetesVersionRange: ">=1.29.0 <1.30.0"
+`,
+		kubernetesVersion: "v1.30.0",
+		wantErr: `no chart supports Kubernetes version v1.30.0, ` +
+			`chart cilium 1.14.5 supports Kubernetes versions ">=1.27.0 <1.29.0", ` +
+			`chart cilium 1.15.0 supports Kubernetes versions ">=1.29.0 <1.30.0"`,
+	}, {
+		name:    "no charts",
+		data:    "[]",
+		wantErr: "no charts configured for cilium",
+	}, {
+		name: "OCI repository with credentials and CA bundle",
+		data: `ChartName: cilium
+ChartVersion: 1.15.0
+RepositoryURL: oci://registry.example.com/charts
+CredentialsSecretName: registry-credentials
+CASecretName: registry-ca
+`,
+		expectedVersion: "1.15.0",
+		expectedCredentials: &caaphv1.Credentials{
+			Secret: corev1.SecretReference{Namespace: "kube-system", Name: "registry-credentials"},
+			Key:    caaphv1.DefaultOCIKey,
+		},
+		expectedTLSConfig: &caaphv1.TLSConfig{
+			CASecretRef: &corev1.SecretReference{Namespace: "kube-system", Name: "registry-ca"},
+		},
+	}, {
+		name: "OCI repository with credentials at a custom key",
+		data: `ChartName: cilium
+ChartVersion: 1.15.0
+RepositoryURL: oci://registry.example.com/charts
+CredentialsSecretName: registry-credentials
+CredentialsSecretKey: .dockerconfigjson
+`,
+		expectedVersion: "1.15.0",
+		expectedCredentials: &caaphv1.Credentials{
+			Secret: corev1.SecretReference{Namespace: "kube-system", Name: "registry-credentials"},
+			Key:    ".dockerconfigjson",
+		},
+	}, {
+		name: "unsupported repository scheme",
+		data: `ChartName: cilium
+ChartVersion: 1.15.0
+RepositoryURL: ftp://charts.example.com
+`,
+		wantErr: `unsupported scheme "ftp" in repository URL "ftp://charts.example.com" for chart cilium, ` +
+			"must be one of oci, http or https",
+	}, {
+		name:    "missing component",
+		wantErr: "did not find key cilium",
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "default-helm-addons-config"},
+			}
+			if tt.data != "" {
+				cm.Data = map[string]string{string(Cilium): tt.data}
+			}
+			getter := NewHelmChartGetterFromConfigMap(
+				cm.Name,
+				cm.Namespace,
+				fake.NewClientBuilder().WithObjects(cm).Build(),
+			)
+
+			kubernetesVersion := tt.kubernetesVersion
+			if kubernetesVersion == "" {
+				kubernetesVersion = "v1.29.2"
+			}
+			chart, err := getter.For(context.Background(), logr.Discard(), Cilium, kubernetesVersion)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "cilium", chart.Name)
+			assert.Equal(t, tt.expectedVersion, chart.Version)
+			assert.Equal(t, tt.expectedImageTag, chart.ImageTag)
+			assert.Equal(t, tt.expectedCredentials, chart.Credentials())
+			assert.Equal(t, tt.expectedTLSConfig, chart.TLSConfig())
+		})
+	}
+}
@@ -6,21 +6,34 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
 	lifecycleutils "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
+const (
+	defaultHelmReleaseName      = "aws-ebs-csi-driver"
+	defaultHelmReleaseNamespace = "kube-system"
+
+	defaultSnapshotHelmReleaseName      = "snapshot-controller"
+	defaultSnapshotHelmReleaseNamespace = "kube-system"
+)
+
 var defaultStorageClassParams = map[string]string{
 	"csi.storage.k8s.io/fstype": "ext4",
 	"type":                      "gp3",
@@ -28,7 +41,8 @@ var defaultStorageClassParams = map[string]string{
 
 type AWSEBSConfig struct {
 	*options.GlobalOptions
-	defaultAWSEBSConfigMapName string
+	defaultAWSEBSConfigMapName         string
+	defaultValuesTemplateConfigMapName string
 }
 
 func (a *AWSEBSConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
@@ -38,20 +52,29 @@ func (a *AWSEBSConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 		"aws-ebs-csi",
 		"name of the ConfigMap used to deploy AWS EBS CSI driver",
 	)
+	flags.StringVar(
+		&a.defaultValuesTemplateConfigMapName,
+		prefix+".default-values-template-configmap-name",
+		"default-aws-ebs-csi-helm-values-template",
+		"default values ConfigMap name",
+	)
 }
 
 type AWSEBS struct {
-	client ctrlclient.Client
-	config *AWSEBSConfig
+	client              ctrlclient.Client
+	config              *AWSEBSConfig
+	helmChartInfoGetter *config.HelmChartGetter
 }
 
 func New(
 	c ctrlclient.Client,
 	cfg *AWSEBSConfig,
+	helmChartInfoGetter *config.HelmChartGetter,
 ) *AWSEBS {
 	return &AWSEBS{
-		client: c,
-		config: cfg,
+		client:              c,
+		config:              cfg,
+		helmChartInfoGetter: helmChartInfoGetter,
 	}
 }
 
@@ -59,23 +82,27 @@ func (a *AWSEBS) Apply(
 	ctx context.Context,
 	provider v1alpha1.CSIProvider,
 	defaultStorageConfig *v1alpha1.DefaultStorage,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 ) error {
 	strategy := provider.Strategy
 	switch strategy {
 	case v1alpha1.AddonStrategyClusterResourceSet:
-		err := a.handleCRSApply(ctx, req)
+		err := a.handleCRSApply(ctx, cluster)
 		if err != nil {
 			return err
 		}
 	case v1alpha1.AddonStrategyHelmAddon:
+		err := a.handleHelmAddonApply(ctx, cluster, provider.Values)
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("stategy %s not implemented", strategy)
 	}
 	return a.createStorageClasses(
 		ctx,
 		provider.StorageClassConfig,
-		&req.Cluster,
+		cluster,
 		defaultStorageConfig,
 	)
 }
@@ -113,12 +140,13 @@ func (a *AWSEBS) createStorageClasses(ctx context.Context,
 		"aws-storageclass-crs",
 		a.client,
 		cluster,
+		lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
 		cm,
 	)
 }
 
 func (a *AWSEBS) handleCRSApply(ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 ) error {
 	awsEBSCSIConfigMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -137,8 +165,11 @@ func (a *AWSEBS) handleCRSApply(ctx context.Context,
 			err,
 		)
 	}
-	cluster := req.Cluster
-	cm := generateAWSEBSCSIConfigMap(awsEBSCSIConfigMap, &cluster)
+	cm := generateAWSEBSCSIConfigMap(awsEBSCSIConfigMap, cluster)
+	if err := lifecycleutils.RewriteConfigMapImagesForMirror(cluster, cm); err != nil {
+		return fmt.Errorf("failed to rewrite AWS EBS CSI manifests ConfigMap for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, a.client, cm); err != nil {
 		return fmt.Errorf(
 			"failed to apply AWS EBS CSI manifests ConfigMap: %w",
@@ -149,15 +180,198 @@ func (a *AWSEBS) handleCRSApply(ctx context.Context,
 		ctx,
 		cm.Name,
 		a.client,
-		&req.Cluster,
+		cluster,
+		lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
 		cm,
 	)
 	if err != nil {
 		return err
 	}
+
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	for _, hcpName := range []string{"aws-ebs-csi-" + cluster.Name, "aws-ebs-csi-snapshot-" + cluster.Name} {
+		err = lifecycleutils.MigrateHelmChartProxyToClusterResourceSet(
+			ctx,
+			a.client,
+			ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: hcpName},
+			ctrlclient.ObjectKeyFromObject(cm),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to migrate AWS EBS CSI installation from HelmChartProxy: %w", err)
+		}
+	}
 	return nil
 }
 
+func (a *AWSEBS) handleHelmAddonApply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	valuesOverride *v1alpha1.AddonValues,
+) error {
+	valuesTemplateConfigMap, err := lifecycleutils.RetrieveValuesTemplateConfigMap(
+		ctx,
+		a.client,
+		a.config.defaultValuesTemplateConfigMapName,
+		a.config.DefaultsNamespace(),
+	)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to retrieve AWS EBS CSI installation values template ConfigMap for cluster: %w",
+			err,
+		)
+	}
+	values, err := lifecycleutils.ApplyValuesOverride(
+		ctx,
+		a.client,
+		cluster,
+		valuesTemplateConfigMap.Data[lifecycleutils.ValuesConfigMapKey],
+		valuesOverride,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply AWS EBS CSI installation values override: %w", err)
+	}
+
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		ctrlclient.ObjectKeyFromObject(cluster),
+	)
+	helmChart, err := a.helmChartInfoGetter.For(ctx, log, config.AWSEBSCSI, cluster.Spec.Topology.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get values for aws-ebs-csi-config %w", err)
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	err = lifecycleutils.MigrateClusterResourceSetToHelmRelease(
+		ctx,
+		a.client,
+		ctrlclient.ObjectKey{
+			Namespace: cluster.Namespace,
+			Name:      fmt.Sprintf("%s-%s", a.config.defaultAWSEBSConfigMapName, cluster.Name),
+		},
+		helmReleaseFor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate AWS EBS CSI installation from ClusterResourceSet: %w", err)
+	}
+
+	hcp := &caaphv1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: caaphv1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      "aws-ebs-csi-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
+		},
+		Spec: caaphv1.HelmChartProxySpec{
+			RepoURL:   helmChart.Repository,
+			ChartName: helmChart.Name,
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			ReleaseNamespace: defaultHelmReleaseNamespace,
+			ReleaseName:      defaultHelmReleaseName,
+			Version:          helmChart.Version,
+			Credentials:      helmChart.Credentials(),
+			TLSConfig:        helmChart.TLSConfig(),
+			ValuesTemplate:   values,
+		},
+	}
+
+	if err = controllerutil.SetOwnerReference(cluster, hcp, a.client.Scheme()); err != nil {
+		return fmt.Errorf(
+			"failed to set owner reference on aws-ebs-csi installation HelmChartProxy: %w",
+			err,
+		)
+	}
+
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, a.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite aws-ebs-csi installation HelmChartProxy for registry mirror: %w", err)
+	}
+
+	if err = client.ServerSideApply(ctx, a.client, hcp); err != nil {
+		return fmt.Errorf("failed to apply aws-ebs-csi installation HelmChartProxy: %w", err)
+	}
+
+	return a.handleSnapshotControllerHelmAddonApply(ctx, cluster)
+}
+
+// handleSnapshotControllerHelmAddonApply deploys the snapshot controller and the VolumeSnapshot CRDs, which the
+// aws-ebs-csi-driver Helm chart does not include. These are deployed from the same external-snapshotter chart as for
+// the Nutanix CSI driver.
+func (a *AWSEBS) handleSnapshotControllerHelmAddonApply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+) error {
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		ctrlclient.ObjectKeyFromObject(cluster),
+	)
+	helmChart, err := a.helmChartInfoGetter.For(ctx, log, config.NutanixSnapshotCSI, cluster.Spec.Topology.Version)
+	if err != nil {
+		return fmt.Errorf("failed to get values for snapshot-controller %w", err)
+	}
+
+	hcp := &caaphv1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: caaphv1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      "aws-ebs-csi-snapshot-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
+		},
+		Spec: caaphv1.HelmChartProxySpec{
+			RepoURL:   helmChart.Repository,
+			ChartName: helmChart.Name,
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			ReleaseNamespace: defaultSnapshotHelmReleaseNamespace,
+			ReleaseName:      defaultSnapshotHelmReleaseName,
+			Version:          helmChart.Version,
+			Credentials:      helmChart.Credentials(),
+			TLSConfig:        helmChart.TLSConfig(),
+		},
+	}
+
+	if err = controllerutil.SetOwnerReference(cluster, hcp, a.client.Scheme()); err != nil {
+		return fmt.Errorf(
+			"failed to set owner reference on aws-ebs-csi-snapshot installation HelmChartProxy: %w",
+			err,
+		)
+	}
+
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, a.client, cluster, hcp); err != nil {
+		return fmt.Errorf(
+			"failed to rewrite aws-ebs-csi-snapshot installation HelmChartProxy for registry mirror: %w",
+			err,
+		)
+	}
+
+	if err = client.ServerSideApply(ctx, a.client, hcp); err != nil {
+		return fmt.Errorf("failed to apply aws-ebs-csi-snapshot installation HelmChartProxy: %w", err)
+	}
+
+	return nil
+}
+
+// helmReleaseFor returns the Helm release that adopts the object applied by the ClusterResourceSet: the snapshot
+// controller and the VolumeSnapshot CRDs belong to the snapshot controller chart, everything else to the driver chart.
+func helmReleaseFor(obj *unstructured.Unstructured) lifecycleutils.HelmRelease {
+	if strings.HasPrefix(obj.GetName(), "snapshot-controller") ||
+		strings.HasSuffix(obj.GetName(), ".snapshot.storage.k8s.io") {
+		return lifecycleutils.HelmRelease{
+			Name:      defaultSnapshotHelmReleaseName,
+			Namespace: defaultSnapshotHelmReleaseNamespace,
+		}
+	}
+	return lifecycleutils.HelmRelease{Name: defaultHelmReleaseName, Namespace: defaultHelmReleaseNamespace}
+}
+
 func generateAWSEBSCSIConfigMap(
 	defaultAWSEBSCSIConfigMap *corev1.ConfigMap, cluster *clusterv1.Cluster,
 ) *corev1.ConfigMap {
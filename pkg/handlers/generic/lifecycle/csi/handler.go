@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -16,6 +18,7 @@ import (
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 )
 
 const (
@@ -27,12 +30,13 @@ type CSIProvider interface {
 		context.Context,
 		v1alpha1.CSIProvider,
 		*v1alpha1.DefaultStorage,
-		*runtimehooksv1.AfterControlPlaneInitializedRequest,
+		*clusterv1.Cluster,
 	) error
 }
 
 type CSIHandler struct {
 	client          ctrlclient.Client
+	recorder        record.EventRecorder
 	variableName    string
 	variablePath    []string
 	ProviderHandler map[string]CSIProvider
@@ -41,14 +45,17 @@ type CSIHandler struct {
 var (
 	_ commonhandlers.Named                   = &CSIHandler{}
 	_ lifecycle.AfterControlPlaneInitialized = &CSIHandler{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &CSIHandler{}
 )
 
 func New(
 	c ctrlclient.Client,
+	recorder record.EventRecorder,
 	handlers map[string]CSIProvider,
 ) *CSIHandler {
 	return &CSIHandler{
 		client:          c,
+		recorder:        recorder,
 		variableName:    clusterconfig.MetaVariableName,
 		variablePath:    []string{"addons", variableRootName},
 		ProviderHandler: handlers,
@@ -64,13 +71,29 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
 	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CSIHandler) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	c.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (c *CSIHandler) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
-	varMap := variables.ClusterVariablesToVariablesMap(req.Cluster.Spec.Topology.Variables)
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
 	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 	csiProviders, found, err := variables.Get[v1alpha1.CSI](
 		varMap,
@@ -96,6 +119,7 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 				csiProviders,
 			),
 		)
+		events.AddonSkipped(c.recorder, cluster, "CSI", "cluster does not specify any CSI providers")
 		return
 	}
 	if len(csiProviders.Providers) == 1 &&
@@ -116,6 +140,12 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 					provider.Name,
 				),
 			)
+			events.AddonSkipped(
+				c.recorder,
+				cluster,
+				provider.Name+" CSI",
+				"no handler for CSI provider",
+			)
 			continue
 		}
 		log.Info(fmt.Sprintf("Creating CSI provider %s", provider.Name))
@@ -123,7 +153,7 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 			ctx,
 			provider,
 			csiProviders.DefaultStorage,
-			req,
+			cluster,
 		)
 		if err != nil {
 			log.Error(
@@ -133,6 +163,7 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 					provider.Name,
 				),
 			)
+			events.AddonDeploymentFailed(c.recorder, cluster, provider.Name+" CSI", err)
 			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 			resp.SetMessage(
 				fmt.Sprintf(
@@ -140,6 +171,8 @@ func (c *CSIHandler) AfterControlPlaneInitialized(
 					err,
 				),
 			)
+			continue
 		}
+		events.AddonDeployed(c.recorder, cluster, provider.Name+" CSI")
 	}
 }
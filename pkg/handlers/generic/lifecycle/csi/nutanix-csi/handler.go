@@ -6,12 +6,14 @@ package nutanix
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -31,27 +33,48 @@ const (
 	defaultSnapshotHelmReleaseName      = "nutanix-csi-snapshot"
 	defaultSnapshotHelmReleaseNamespace = "ntnx-system"
 
+	// defaultCRSNamespace is the namespace the manifests deployed via ClusterResourceSet install the driver in.
+	defaultCRSNamespace = "kube-system"
+
 	//nolint:gosec // Does not contain hard coded credentials.
 	defaultCredentialsSecretName = "nutanix-csi-credentials"
 )
 
-var defaultStorageClassParameters = map[string]string{
-	"storageType":                                           "NutanixVolumes",
-	"csi.storage.k8s.io/fstype":                             "xfs",
-	"csi.storage.k8s.io/provisioner-secret-name":            defaultCredentialsSecretName,
-	"csi.storage.k8s.io/provisioner-secret-namespace":       defaultStorageHelmReleaseNamespace,
-	"csi.storage.k8s.io/node-publish-secret-name":           defaultCredentialsSecretName,
-	"csi.storage.k8s.io/node-publish-secret-namespace":      defaultStorageHelmReleaseNamespace,
-	"csi.storage.k8s.io/controller-expand-secret-name":      defaultCredentialsSecretName,
-	"csi.storage.k8s.io/controller-expand-secret-namespace": defaultStorageHelmReleaseNamespace,
+// storageClassParameters returns the default StorageClass parameters referencing the credentials Secret in the
+// namespace the driver is deployed in.
+func storageClassParameters(credentialsNamespace string) map[string]string {
+	return map[string]string{
+		"storageType":                                           "NutanixVolumes",
+		"csi.storage.k8s.io/fstype":                             "xfs",
+		"csi.storage.k8s.io/provisioner-secret-name":            defaultCredentialsSecretName,
+		"csi.storage.k8s.io/provisioner-secret-namespace":       credentialsNamespace,
+		"csi.storage.k8s.io/node-publish-secret-name":           defaultCredentialsSecretName,
+		"csi.storage.k8s.io/node-publish-secret-namespace":      credentialsNamespace,
+		"csi.storage.k8s.io/controller-expand-secret-name":      defaultCredentialsSecretName,
+		"csi.storage.k8s.io/controller-expand-secret-namespace": credentialsNamespace,
+	}
 }
 
 type NutanixCSIConfig struct {
 	*options.GlobalOptions
 	defaultValuesTemplateConfigMapName string
+	defaultStorageConfigMapName        string
+	defaultSnapshotConfigMapName       string
 }
 
 func (n *NutanixCSIConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
+	flags.StringVar(
+		&n.defaultStorageConfigMapName,
+		prefix+".nutanix-storage-csi-configmap-name",
+		"nutanix-storage-csi",
+		"name of the ConfigMap used to deploy Nutanix CSI driver",
+	)
+	flags.StringVar(
+		&n.defaultSnapshotConfigMapName,
+		prefix+".nutanix-snapshot-csi-configmap-name",
+		"nutanix-snapshot-csi",
+		"name of the ConfigMap used to deploy Nutanix CSI snapshot controller",
+	)
 	flags.StringVar(
 		&n.defaultValuesTemplateConfigMapName,
 		prefix+".default-values-template-configmap-name",
@@ -82,16 +105,22 @@ func (n *NutanixCSI) Apply(
 	ctx context.Context,
 	provider v1alpha1.CSIProvider,
 	defaultStorageConfig *v1alpha1.DefaultStorage,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 ) error {
 	strategy := provider.Strategy
+	credentialsNamespace := defaultStorageHelmReleaseNamespace
 	switch strategy {
 	case v1alpha1.AddonStrategyHelmAddon:
-		err := n.handleHelmAddonApply(ctx, req)
+		err := n.handleHelmAddonApply(ctx, cluster, provider.Values)
 		if err != nil {
 			return err
 		}
 	case v1alpha1.AddonStrategyClusterResourceSet:
+		err := n.handleCRSApply(ctx, cluster)
+		if err != nil {
+			return err
+		}
+		credentialsNamespace = defaultCRSNamespace
 	default:
 		return fmt.Errorf("stategy %s not implemented", strategy)
 	}
@@ -99,14 +128,14 @@ func (n *NutanixCSI) Apply(
 	if provider.Credentials != nil {
 		key := ctrlclient.ObjectKey{
 			Name:      defaultCredentialsSecretName,
-			Namespace: defaultStorageHelmReleaseNamespace,
+			Namespace: credentialsNamespace,
 		}
 		err := lifecycleutils.CopySecretToRemoteCluster(
 			ctx,
 			n.client,
 			provider.Credentials.Name,
 			key,
-			&req.Cluster,
+			cluster,
 		)
 		if err != nil {
 			return fmt.Errorf(
@@ -119,8 +148,9 @@ func (n *NutanixCSI) Apply(
 	err := n.createStorageClasses(
 		ctx,
 		provider.StorageClassConfig,
-		&req.Cluster,
+		cluster,
 		defaultStorageConfig,
+		credentialsNamespace,
 	)
 	if err != nil {
 		return fmt.Errorf("error creating StorageClasses for the Nutanix CSI driver: %w", err)
@@ -129,9 +159,80 @@ func (n *NutanixCSI) Apply(
 	return nil
 }
 
+func (n *NutanixCSI) handleCRSApply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+) error {
+	configMapNames := []string{
+		n.config.defaultStorageConfigMapName,
+		n.config.defaultSnapshotConfigMapName,
+	}
+	configMaps := make([]runtime.Object, 0, len(configMapNames))
+	for _, configMapName := range configMapNames {
+		defaultConfigMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: n.config.DefaultsNamespace(),
+				Name:      configMapName,
+			},
+		}
+		defaultConfigMapObjName := ctrlclient.ObjectKeyFromObject(defaultConfigMap)
+		err := n.client.Get(ctx, defaultConfigMapObjName, defaultConfigMap)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to retrieve default Nutanix CSI manifests ConfigMap %q: %w",
+				defaultConfigMapObjName,
+				err,
+			)
+		}
+
+		cm := generateNutanixCSIConfigMap(defaultConfigMap, cluster)
+		if err := lifecycleutils.RewriteConfigMapImagesForMirror(cluster, cm); err != nil {
+			return fmt.Errorf("failed to rewrite Nutanix CSI manifests ConfigMap for registry mirror: %w", err)
+		}
+
+		if err := client.ServerSideApply(ctx, n.client, cm); err != nil {
+			return fmt.Errorf(
+				"failed to apply Nutanix CSI manifests ConfigMap %q: %w",
+				ctrlclient.ObjectKeyFromObject(cm),
+				err,
+			)
+		}
+		configMaps = append(configMaps, cm)
+	}
+
+	err := lifecycleutils.EnsureCRSForClusterFromObjects(
+		ctx,
+		crsName(cluster),
+		n.client,
+		cluster,
+		lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
+		configMaps...,
+	)
+	if err != nil {
+		return err
+	}
+
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet. The objects the Helm releases installed to the ntnx-system namespace are deleted once the
+	// ClusterResourceSet has applied their replacements to the kube-system namespace.
+	for _, hcpName := range []string{"nutanix-csi-" + cluster.Name, "nutanix-csi-snapshot-" + cluster.Name} {
+		err = lifecycleutils.MigrateHelmChartProxyToClusterResourceSet(
+			ctx,
+			n.client,
+			ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: hcpName},
+			ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: crsName(cluster)},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to migrate Nutanix CSI installation from HelmChartProxy: %w", err)
+		}
+	}
+	return nil
+}
+
 func (n *NutanixCSI) handleHelmAddonApply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
+	valuesOverride *v1alpha1.AddonValues,
 ) error {
 	valuesTemplateConfigMap, err := lifecycleutils.RetrieveValuesTemplateConfigMap(ctx,
 		n.client,
@@ -143,50 +244,90 @@ func (n *NutanixCSI) handleHelmAddonApply(
 			err,
 		)
 	}
-	values := valuesTemplateConfigMap.Data["values.yaml"]
+	// The values override only applies to the Nutanix CSI driver chart, not to the snapshot controller chart.
+	values, err := lifecycleutils.ApplyValuesOverride(
+		ctx,
+		n.client,
+		cluster,
+		valuesTemplateConfigMap.Data[lifecycleutils.ValuesConfigMapKey],
+		valuesOverride,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply Nutanix CSI installation values override: %w", err)
+	}
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
-		ctrlclient.ObjectKeyFromObject(&req.Cluster),
+		ctrlclient.ObjectKeyFromObject(cluster),
+	)
+	helmChart, err := n.helmChartInfoGetter.For(
+		ctx,
+		log,
+		config.NutanixStorageCSI,
+		cluster.Spec.Topology.Version,
 	)
-	helmChart, err := n.helmChartInfoGetter.For(ctx, log, config.NutanixStorageCSI)
 	if err != nil {
 		return fmt.Errorf("failed to get values for nutanix-csi-config %w", err)
 	}
 
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon. The
+	// namespaced objects the ClusterResourceSet applied to the kube-system namespace are replaced by those of the
+	// Helm releases, and only deleted once the Helm releases are ready.
+	err = lifecycleutils.MigrateClusterResourceSetToHelmRelease(
+		ctx,
+		n.client,
+		ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: crsName(cluster)},
+		helmReleaseFor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate Nutanix CSI installation from ClusterResourceSet: %w", err)
+	}
+
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: caaphv1.GroupVersion.String(),
 			Kind:       "HelmChartProxy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: req.Cluster.Namespace,
-			Name:      "nutanix-csi-" + req.Cluster.Name,
+			Namespace: cluster.Namespace,
+			Name:      "nutanix-csi-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   helmChart.Repository,
 			ChartName: helmChart.Name,
 			ClusterSelector: metav1.LabelSelector{
-				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: req.Cluster.Name},
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
 			},
 			ReleaseNamespace: defaultStorageHelmReleaseNamespace,
 			ReleaseName:      defaultStorageHelmReleaseName,
 			Version:          helmChart.Version,
+			Credentials:      helmChart.Credentials(),
+			TLSConfig:        helmChart.TLSConfig(),
 			ValuesTemplate:   values,
 		},
 	}
 
-	if err = controllerutil.SetOwnerReference(&req.Cluster, hcp, n.client.Scheme()); err != nil {
+	if err = controllerutil.SetOwnerReference(cluster, hcp, n.client.Scheme()); err != nil {
 		return fmt.Errorf(
 			"failed to set owner reference on nutanix-csi installation HelmChartProxy: %w",
 			err,
 		)
 	}
 
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, n.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite nutanix-csi installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err = client.ServerSideApply(ctx, n.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply nutanix-csi installation HelmChartProxy: %w", err)
 	}
 
-	snapshotHelmChart, err := n.helmChartInfoGetter.For(ctx, log, config.NutanixSnapshotCSI)
+	snapshotHelmChart, err := n.helmChartInfoGetter.For(
+		ctx,
+		log,
+		config.NutanixSnapshotCSI,
+		cluster.Spec.Topology.Version,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to get values for nutanix-csi-config %w", err)
 	}
@@ -197,21 +338,28 @@ func (n *NutanixCSI) handleHelmAddonApply(
 			Kind:       "HelmChartProxy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: req.Cluster.Namespace,
-			Name:      "nutanix-csi-snapshot-" + req.Cluster.Name,
+			Namespace: cluster.Namespace,
+			Name:      "nutanix-csi-snapshot-" + cluster.Name,
+			Labels:    lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   snapshotHelmChart.Repository,
 			ChartName: snapshotHelmChart.Name,
 			ClusterSelector: metav1.LabelSelector{
-				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: req.Cluster.Name},
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
 			},
 			ReleaseNamespace: defaultSnapshotHelmReleaseNamespace,
 			ReleaseName:      defaultSnapshotHelmReleaseName,
 			Version:          snapshotHelmChart.Version,
+			Credentials:      snapshotHelmChart.Credentials(),
+			TLSConfig:        snapshotHelmChart.TLSConfig(),
 		},
 	}
 
+	if err = lifecycleutils.RewriteHelmChartProxyForMirror(ctx, n.client, cluster, snapshotChart); err != nil {
+		return fmt.Errorf("failed to rewrite nutanix-csi-snapshot installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err = client.ServerSideApply(ctx, n.client, snapshotChart); err != nil {
 		return fmt.Errorf(
 			"failed to apply nutanix-csi-snapshot installation HelmChartProxy: %w",
@@ -227,6 +375,7 @@ func (n *NutanixCSI) createStorageClasses(
 	configs []v1alpha1.StorageClassConfig,
 	cluster *clusterv1.Cluster,
 	defaultStorageConfig *v1alpha1.DefaultStorage,
+	credentialsNamespace string,
 ) error {
 	allStorageClasses := make([]runtime.Object, 0, len(configs))
 	for _, config := range configs {
@@ -236,7 +385,7 @@ func (n *NutanixCSI) createStorageClasses(
 			config,
 			v1alpha1.NutanixProvisioner,
 			setAsDefault,
-			defaultStorageClassParameters,
+			storageClassParameters(credentialsNamespace),
 		))
 	}
 	cm, err := lifecycleutils.CreateConfigMapForCRS(
@@ -256,6 +405,43 @@ func (n *NutanixCSI) createStorageClasses(
 		"nutanix-storageclass-crs",
 		n.client,
 		cluster,
+		lifecycleutils.AddonLabels(cluster, lifecycleutils.AddonCSI),
 		cm,
 	)
 }
+
+func crsName(cluster *clusterv1.Cluster) string {
+	return "nutanix-csi-" + cluster.Name
+}
+
+// helmReleaseFor returns the Helm release that adopts the object applied by the ClusterResourceSet: the snapshot
+// controller and its CRDs belong to the snapshot chart, everything else to the storage chart.
+func helmReleaseFor(obj *unstructured.Unstructured) lifecycleutils.HelmRelease {
+	if strings.Contains(obj.GetName(), "snapshot") {
+		return lifecycleutils.HelmRelease{
+			Name:      defaultSnapshotHelmReleaseName,
+			Namespace: defaultSnapshotHelmReleaseNamespace,
+		}
+	}
+	return lifecycleutils.HelmRelease{
+		Name:      defaultStorageHelmReleaseName,
+		Namespace: defaultStorageHelmReleaseNamespace,
+	}
+}
+
+func generateNutanixCSIConfigMap(
+	defaultNutanixCSIConfigMap *corev1.ConfigMap, cluster *clusterv1.Cluster,
+) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      fmt.Sprintf("%s-%s", defaultNutanixCSIConfigMap.Name, cluster.Name),
+		},
+		Data:       defaultNutanixCSIConfigMap.Data,
+		BinaryData: defaultNutanixCSIConfigMap.BinaryData,
+	}
+}
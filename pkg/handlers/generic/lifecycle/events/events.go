@@ -0,0 +1,116 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package events provides helpers to record Events on the Cluster for the outcomes of the lifecycle handlers, so that
+// `kubectl describe cluster` shows which addons were deployed or skipped and which cleanup steps were run.
+//
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+package events
+
+import (
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// RecorderName is the name of the component the Events are reported by.
+const RecorderName = "cluster-api-runtime-extensions-nutanix"
+
+const (
+	ReasonAddonDeployed             = "AddonDeployed"
+	ReasonAddonSkipped              = "AddonSkipped"
+	ReasonAddonDeploymentFailed     = "AddonDeploymentFailed"
+	ReasonDefaultsConfigMapNotFound = "DefaultsConfigMapNotFound"
+	ReasonAddonRemoved              = "AddonRemoved"
+	ReasonAddonRemovalFailed        = "AddonRemovalFailed"
+
+	ReasonCleanupSkipped    = "CleanupSkipped"
+	ReasonCleanupInProgress = "CleanupInProgress"
+	ReasonCleanupFailed     = "CleanupFailed"
+	ReasonCleanupCompleted  = "CleanupCompleted"
+)
+
+// AddonDeployed records a Normal Event for the successful deployment of the addon.
+func AddonDeployed(recorder record.EventRecorder, cluster *clusterv1.Cluster, addon string) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonAddonDeployed, "Deployed %s addon", addon)
+}
+
+// AddonSkipped records a Normal Event for the decision not to deploy the addon.
+func AddonSkipped(recorder record.EventRecorder, cluster *clusterv1.Cluster, addon, why string) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonAddonSkipped, "Skipped %s addon: %s", addon, why)
+}
+
+// AddonDeploymentFailed records a Warning Event for the failed deployment of the addon, with a dedicated reason if the
+// deployment failed because a defaults ConfigMap does not exist.
+func AddonDeploymentFailed(recorder record.EventRecorder, cluster *clusterv1.Cluster, addon string, err error) {
+	if isConfigMapNotFound(err) {
+		recorder.Eventf(
+			cluster,
+			corev1.EventTypeWarning,
+			ReasonDefaultsConfigMapNotFound,
+			"Failed to deploy %s addon, defaults ConfigMap not found: %v",
+			addon,
+			err,
+		)
+		return
+	}
+
+	recorder.Eventf(
+		cluster,
+		corev1.EventTypeWarning,
+		ReasonAddonDeploymentFailed,
+		"Failed to deploy %s addon: %v",
+		addon,
+		err,
+	)
+}
+
+// AddonRemoved records a Normal Event for the removal of an addon that is no longer enabled for the cluster.
+func AddonRemoved(recorder record.EventRecorder, cluster *clusterv1.Cluster, addon string) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonAddonRemoved, "Removed %s addon", addon)
+}
+
+// AddonRemovalFailed records a Warning Event for the failed removal of an addon that is no longer enabled for the
+// cluster.
+func AddonRemovalFailed(recorder record.EventRecorder, cluster *clusterv1.Cluster, addon string, err error) {
+	recorder.Eventf(
+		cluster,
+		corev1.EventTypeWarning,
+		ReasonAddonRemovalFailed,
+		"Failed to remove %s addon: %v",
+		addon,
+		err,
+	)
+}
+
+// CleanupSkipped records a Normal Event for the decision not to run the cleanup step before deleting the cluster.
+func CleanupSkipped(recorder record.EventRecorder, cluster *clusterv1.Cluster, step, why string) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonCleanupSkipped, "Skipped %s: %s", step, why)
+}
+
+// CleanupInProgress records a Normal Event while waiting for the cleanup step to complete.
+func CleanupInProgress(recorder record.EventRecorder, cluster *clusterv1.Cluster, step string, err error) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonCleanupInProgress, "Running %s: %v", step, err)
+}
+
+// CleanupFailed records a Warning Event for the failure of the cleanup step.
+func CleanupFailed(recorder record.EventRecorder, cluster *clusterv1.Cluster, step string, err error) {
+	recorder.Eventf(cluster, corev1.EventTypeWarning, ReasonCleanupFailed, "Failed %s: %v", step, err)
+}
+
+// CleanupCompleted records a Normal Event for the completion of the cleanup step.
+func CleanupCompleted(recorder record.EventRecorder, cluster *clusterv1.Cluster, step string) {
+	recorder.Eventf(cluster, corev1.EventTypeNormal, ReasonCleanupCompleted, "Completed %s", step)
+}
+
+func isConfigMapNotFound(err error) bool {
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) || !apierrors.IsNotFound(err) {
+		return false
+	}
+	details := apiStatus.Status().Details
+	return details != nil && details.Kind == "configmaps"
+}
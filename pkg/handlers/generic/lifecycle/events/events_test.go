@@ -0,0 +1,85 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestAddonDeploymentFailed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		err           error
+		expectedEvent string
+	}{{
+		name: "defaults ConfigMap not found",
+		err: fmt.Errorf(
+			"failed to get helm chart config: %w",
+			apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "default-helm-addons-config"),
+		),
+		expectedEvent: corev1.EventTypeWarning + " " + ReasonDefaultsConfigMapNotFound + " " +
+			"Failed to deploy Calico CNI addon, defaults ConfigMap not found: failed to get helm chart config: " +
+			`configmaps "default-helm-addons-config" not found`,
+	}, {
+		name: "other resource not found",
+		err: apierrors.NewNotFound(
+			schema.GroupResource{Group: "addons.cluster.x-k8s.io", Resource: "helmchartproxies"},
+			"calico",
+		),
+		expectedEvent: corev1.EventTypeWarning + " " + ReasonAddonDeploymentFailed + " " +
+			`Failed to deploy Calico CNI addon: helmchartproxies.addons.cluster.x-k8s.io "calico" not found`,
+	}, {
+		name: "other error",
+		err:  errors.New("boom"),
+		expectedEvent: corev1.EventTypeWarning + " " + ReasonAddonDeploymentFailed + " " +
+			"Failed to deploy Calico CNI addon: boom",
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := record.NewFakeRecorder(1)
+			AddonDeploymentFailed(recorder, &clusterv1.Cluster{}, "Calico CNI", tt.err)
+
+			require.Len(t, recorder.Events, 1)
+			assert.Equal(t, tt.expectedEvent, <-recorder.Events)
+		})
+	}
+}
+
+func TestCleanupEvents(t *testing.T) {
+	t.Parallel()
+
+	recorder := record.NewFakeRecorder(4)
+	cluster := &clusterv1.Cluster{}
+	const step = "PersistentVolumes cleanup"
+
+	CleanupSkipped(recorder, cluster, step, "not annotated")
+	CleanupInProgress(recorder, cluster, step, errors.New("waiting"))
+	CleanupFailed(recorder, cluster, step, errors.New("boom"))
+	CleanupCompleted(recorder, cluster, step)
+
+	require.Len(t, recorder.Events, 4)
+	assert.Equal(
+		t,
+		"Normal CleanupSkipped Skipped PersistentVolumes cleanup: not annotated",
+		<-recorder.Events,
+	)
+	assert.Equal(t, "Normal CleanupInProgress Running PersistentVolumes cleanup: waiting", <-recorder.Events)
+	assert.Equal(t, "Warning CleanupFailed Failed PersistentVolumes cleanup: boom", <-recorder.Events)
+	assert.Equal(t, "Normal CleanupCompleted Completed PersistentVolumes cleanup", <-recorder.Events)
+}
@@ -4,23 +4,33 @@
 package lifecycle
 
 import (
+	"fmt"
+
 	"github.com/spf13/pflag"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/addonqueue"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/ccm"
 	awsccm "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/ccm/aws"
 	nutanixccm "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/ccm/nutanix"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/clusterautoscaler"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/cni/calico"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/cni/cilium"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/cni/custom"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/compatibility"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/csi"
 	awsebs "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/csi/aws-ebs"
 	nutanixcsi "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/csi/nutanix-csi"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/helmcharts"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/nfd"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/persistentvolumegc"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/servicelbgc"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/options"
 )
 
@@ -54,33 +64,55 @@ func New(
 	}
 }
 
-func (h *Handlers) AllHandlers(mgr manager.Manager) []handlers.Named {
+func (h *Handlers) AllHandlers(mgr manager.Manager) ([]handlers.Named, error) {
+	// Create a span for every Kubernetes API call made by the lifecycle handlers.
+	client := tracing.WrapClient(mgr.GetClient())
+
+	// Addons are installed asynchronously by the addon queue, so that the hooks do not block on slow remote API
+	// servers or fail while, for example, a defaults ConfigMap does not exist yet.
+	addonQueue := addonqueue.New(client, addonqueue.NewClusterAddonsHealthCheck(client))
+	if err := mgr.Add(addonQueue); err != nil {
+		return nil, fmt.Errorf("failed to add addon queue to manager: %w", err)
+	}
+
+	// Events about addon deployments and cleanup steps are recorded on the Cluster object.
+	recorder := mgr.GetEventRecorderFor(events.RecorderName)
+
 	helmChartInfoGetter := config.NewHelmChartGetterFromConfigMap(
 		h.globalOptions.HelmAddonsConfigMapName(),
 		h.globalOptions.DefaultsNamespace(),
-		mgr.GetClient(),
+		client,
 	)
 	csiHandlers := map[string]csi.CSIProvider{
-		v1alpha1.CSIProviderAWSEBS: awsebs.New(mgr.GetClient(), h.ebsConfig),
+		v1alpha1.CSIProviderAWSEBS: awsebs.New(client, h.ebsConfig, helmChartInfoGetter),
 		v1alpha1.CSIProviderNutanix: nutanixcsi.New(
-			mgr.GetClient(),
+			client,
 			h.nutnaixCSIConfig,
 			helmChartInfoGetter,
 		),
 	}
 	ccmHandlers := map[string]ccm.CCMProvider{
-		v1alpha1.CCMProviderAWS:     awsccm.New(mgr.GetClient(), h.awsccmConfig),
-		v1alpha1.CCMProviderNutanix: nutanixccm.New(mgr.GetClient(), h.nutanixCCMConfig, helmChartInfoGetter),
+		v1alpha1.CCMProviderAWS:     awsccm.New(client, h.awsccmConfig, helmChartInfoGetter),
+		v1alpha1.CCMProviderNutanix: nutanixccm.New(client, h.nutanixCCMConfig, helmChartInfoGetter),
 	}
 	return []handlers.Named{
-		calico.New(mgr.GetClient(), h.calicoCNIConfig, helmChartInfoGetter),
-		cilium.New(mgr.GetClient(), h.ciliumCNIConfig, helmChartInfoGetter),
-		nfd.New(mgr.GetClient(), h.nfdConfig, helmChartInfoGetter),
-		clusterautoscaler.New(mgr.GetClient(), h.clusterAutoscalerConfig, helmChartInfoGetter),
-		servicelbgc.New(mgr.GetClient()),
-		csi.New(mgr.GetClient(), csiHandlers),
-		ccm.New(mgr.GetClient(), ccmHandlers),
-	}
+		addonQueue,
+		addonqueue.Async(addonQueue, utils.AddonCNI, calico.New(client, recorder, h.calicoCNIConfig, helmChartInfoGetter)),
+		addonqueue.Async(addonQueue, utils.AddonCNI, cilium.New(client, recorder, h.ciliumCNIConfig, helmChartInfoGetter)),
+		addonqueue.Async(addonQueue, utils.AddonCNI, custom.New(client, recorder)),
+		addonqueue.Async(addonQueue, utils.AddonNFD, nfd.New(client, recorder, h.nfdConfig, helmChartInfoGetter)),
+		addonqueue.Async(
+			addonQueue,
+			utils.AddonClusterAutoscaler,
+			clusterautoscaler.New(client, recorder, h.clusterAutoscalerConfig, helmChartInfoGetter),
+		),
+		servicelbgc.New(client, recorder),
+		persistentvolumegc.New(client, recorder),
+		addonqueue.Async(addonQueue, utils.AddonCSI, csi.New(client, recorder, csiHandlers)),
+		addonqueue.Async(addonQueue, utils.AddonCCM, ccm.New(client, recorder, ccmHandlers)),
+		addonqueue.Async(addonQueue, utils.AddonHelmCharts, helmcharts.New(client, recorder)),
+		compatibility.New(client, helmChartInfoGetter, h.awsccmConfig),
+	}, nil
 }
 
 func (h *Handlers) AddFlags(flagSet *pflag.FlagSet) {
@@ -0,0 +1,9 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package helmcharts provides a handler that installs the additional Helm charts declared in the helmCharts addon of
+// the clusterConfig variable, creating a HelmChartProxy targeting the cluster for each of them.
+//
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmchartproxies,verbs=watch;list;get;create;patch;update;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=watch;list;get
+package helmcharts
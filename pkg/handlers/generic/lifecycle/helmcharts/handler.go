@@ -0,0 +1,192 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package helmcharts
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	commonhandlers "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+type HelmCharts struct {
+	client   ctrlclient.Client
+	recorder record.EventRecorder
+
+	variableName string   // points to the global config variable
+	variablePath []string // path of this variable on the global config variable
+}
+
+var (
+	_ commonhandlers.Named                   = &HelmCharts{}
+	_ lifecycle.AfterControlPlaneInitialized = &HelmCharts{}
+	_ lifecycle.AfterControlPlaneUpgrade     = &HelmCharts{}
+)
+
+func New(
+	c ctrlclient.Client,
+	recorder record.EventRecorder,
+) *HelmCharts {
+	return &HelmCharts{
+		client:       c,
+		recorder:     recorder,
+		variableName: clusterconfig.MetaVariableName,
+		variablePath: []string{"addons", v1alpha1.HelmChartsVariableName},
+	}
+}
+
+func (h *HelmCharts) Name() string {
+	return "HelmChartsHandler"
+}
+
+func (h *HelmCharts) AfterControlPlaneInitialized(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	resp *runtimehooksv1.AfterControlPlaneInitializedResponse,
+) {
+	h.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (h *HelmCharts) AfterControlPlaneUpgrade(
+	ctx context.Context,
+	req *runtimehooksv1.AfterControlPlaneUpgradeRequest,
+	resp *runtimehooksv1.AfterControlPlaneUpgradeResponse,
+) {
+	h.apply(ctx, &req.Cluster, &resp.CommonResponse)
+}
+
+func (h *HelmCharts) apply(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	resp *runtimehooksv1.CommonResponse,
+) {
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
+
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		clusterKey,
+	)
+
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
+
+	helmCharts, found, err := variables.Get[[]v1alpha1.HelmChart](varMap, h.variableName, h.variablePath...)
+	if err != nil {
+		log.Error(
+			err,
+			"failed to read Helm charts from cluster definition",
+		)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(
+			fmt.Sprintf("failed to read Helm charts from cluster definition: %v",
+				err,
+			),
+		)
+		return
+	}
+	if !found || len(helmCharts) == 0 {
+		log.Info("Skipping Helm charts handler, cluster does not specify additional Helm charts")
+		return
+	}
+
+	for i := range helmCharts {
+		helmChart := &helmCharts[i]
+		addon := "Helm chart " + helmChart.Name
+
+		if err := h.applyHelmChart(ctx, cluster, helmChart); err != nil {
+			log.Error(err, "failed to apply Helm chart", "helmChart", helmChart.Name)
+			events.AddonDeploymentFailed(h.recorder, cluster, addon, err)
+			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+			resp.SetMessage(err.Error())
+			return
+		}
+
+		events.AddonDeployed(h.recorder, cluster, addon)
+	}
+
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+}
+
+func (h *HelmCharts) applyHelmChart(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	helmChart *v1alpha1.HelmChart,
+) error {
+	valuesTemplate, err := h.valuesTemplate(ctx, cluster, helmChart.ValuesTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to get values template of Helm chart %q: %w", helmChart.Name, err)
+	}
+
+	hcp := helmChartProxy(cluster, helmChart, valuesTemplate)
+	if err := controllerutil.SetOwnerReference(cluster, hcp, h.client.Scheme()); err != nil {
+		return fmt.Errorf(
+			"failed to set owner reference on Helm chart %q HelmChartProxy: %w",
+			helmChart.Name,
+			err,
+		)
+	}
+
+	if err := client.ServerSideApply(ctx, h.client, hcp); err != nil {
+		return fmt.Errorf("failed to apply Helm chart %q HelmChartProxy: %w", helmChart.Name, err)
+	}
+
+	return nil
+}
+
+// valuesTemplate returns the values template of the Helm chart, see utils.HelmChartValuesTemplate.
+func (h *HelmCharts) valuesTemplate(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	valuesTemplate *v1alpha1.HelmChartValuesTemplate,
+) (string, error) {
+	return utils.HelmChartValuesTemplate(ctx, h.client, cluster, valuesTemplate)
+}
+
+func helmChartProxy(
+	cluster *clusterv1.Cluster,
+	helmChart *v1alpha1.HelmChart,
+	valuesTemplate string,
+) *caaphv1.HelmChartProxy {
+	labels := utils.AddonLabels(cluster, utils.AddonHelmCharts)
+	labels[utils.AddonHelmChartLabel] = helmChart.Name
+
+	return &caaphv1.HelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: caaphv1.GroupVersion.String(),
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			// Prefixed so that the names cannot clash with those of the HelmChartProxies of the other addons.
+			Name:   "helm-chart-" + helmChart.Name + "-" + cluster.Name,
+			Labels: labels,
+		},
+		Spec: caaphv1.HelmChartProxySpec{
+			RepoURL:   helmChart.RepoURL,
+			ChartName: helmChart.ChartName,
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			ReleaseNamespace: helmChart.ReleaseNamespace,
+			ReleaseName:      helmChart.Name,
+			Version:          helmChart.Version,
+			ValuesTemplate:   valuesTemplate,
+		},
+	}
+}
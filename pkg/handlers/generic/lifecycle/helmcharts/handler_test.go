@@ -0,0 +1,167 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package helmcharts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
+)
+
+func testCluster(t *testing.T, helmCharts []v1alpha1.HelmChart) *clusterv1.Cluster {
+	t.Helper()
+
+	v := capitest.VariableWithValue(clusterconfig.MetaVariableName, helmCharts, "addons", "helmCharts")
+	return &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster", UID: "test-uid"},
+		Spec: clusterv1.ClusterSpec{
+			Topology: &clusterv1.Topology{
+				Variables: []clusterv1.ClusterVariable{{Name: v.Name, Value: v.Value}},
+			},
+		},
+	}
+}
+
+func testClient(t *testing.T, objs ...ctrlclient.Object) ctrlclient.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, clusterv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestHelmChartProxy(t *testing.T) {
+	t.Parallel()
+
+	helmChart := &v1alpha1.HelmChart{
+		Name:             "ingress-nginx",
+		RepoURL:          "https://kubernetes.github.io/ingress-nginx",
+		ChartName:        "ingress-nginx",
+		Version:          "4.10.0",
+		ReleaseNamespace: "ingress-nginx",
+	}
+	cluster := testCluster(t, []v1alpha1.HelmChart{*helmChart})
+
+	hcp := helmChartProxy(cluster, helmChart, "controller:\n  replicaCount: 2\n")
+
+	assert.Equal(t, metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "helm-chart-ingress-nginx-test-cluster",
+		Labels: map[string]string{
+			utils.AddonLabel:                 utils.AddonHelmCharts,
+			utils.AddonClusterNamespaceLabel: "default",
+			utils.AddonHelmChartLabel:        "ingress-nginx",
+			clusterv1.ClusterNameLabel:       "test-cluster",
+		},
+	}, hcp.ObjectMeta)
+	assert.Equal(t, caaphv1.HelmChartProxySpec{
+		ClusterSelector: metav1.LabelSelector{
+			MatchLabels: map[string]string{clusterv1.ClusterNameLabel: "test-cluster"},
+		},
+		RepoURL:          "https://kubernetes.github.io/ingress-nginx",
+		ChartName:        "ingress-nginx",
+		Version:          "4.10.0",
+		ReleaseNamespace: "ingress-nginx",
+		ReleaseName:      "ingress-nginx",
+		ValuesTemplate:   "controller:\n  replicaCount: 2\n",
+	}, hcp.Spec)
+}
+
+func TestValuesTemplate(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, nil)
+	client := testClient(t, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kyverno-values"},
+		Data:       map[string]string{utils.ValuesConfigMapKey: "replicaCount: {{ .Cluster.metadata.name }}\n"},
+	})
+
+	testCases := []struct {
+		name           string
+		valuesTemplate *v1alpha1.HelmChartValuesTemplate
+		expected       string
+		expectedErr    string
+	}{{
+		name: "no values template",
+	}, {
+		name:           "inline",
+		valuesTemplate: &v1alpha1.HelmChartValuesTemplate{Inline: "replicaCount: 2\n"},
+		expected:       "replicaCount: 2\n",
+	}, {
+		name: "ConfigMap reference",
+		valuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "kyverno-values"},
+		},
+		expected: "replicaCount: {{ .Cluster.metadata.name }}\n",
+	}, {
+		name:           "neither inline nor ConfigMap reference",
+		valuesTemplate: &v1alpha1.HelmChartValuesTemplate{},
+		expectedErr:    "exactly one of inline or configMapRef must be set in the values template",
+	}}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := New(client, record.NewFakeRecorder(10)).valuesTemplate(
+				context.Background(),
+				cluster,
+				tt.valuesTemplate,
+			)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestAfterControlPlaneInitializedMissingValuesConfigMap(t *testing.T) {
+	t.Parallel()
+
+	cluster := testCluster(t, []v1alpha1.HelmChart{{
+		Name:             "kyverno",
+		RepoURL:          "https://kyverno.github.io/kyverno",
+		ChartName:        "kyverno",
+		Version:          "3.1.4",
+		ReleaseNamespace: "kyverno",
+		ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "kyverno-values"},
+		},
+	}})
+	client := testClient(t, cluster)
+	recorder := record.NewFakeRecorder(10)
+
+	resp := &runtimehooksv1.AfterControlPlaneInitializedResponse{}
+	New(client, recorder).AfterControlPlaneInitialized(
+		context.Background(),
+		&runtimehooksv1.AfterControlPlaneInitializedRequest{Cluster: *cluster},
+		resp,
+	)
+	assert.Equal(t, runtimehooksv1.ResponseStatusFailure, resp.Status)
+	assert.Contains(t, resp.Message, `failed to retrieve values template ConfigMap "default/kyverno-values"`)
+	assert.Contains(t, <-recorder.Events, "Warning")
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package helmcharts
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+func TestVariableValidation(t *testing.T) {
+	validHelmChart := v1alpha1.HelmChart{
+		Name:             "ingress-nginx",
+		RepoURL:          "https://kubernetes.github.io/ingress-nginx",
+		ChartName:        "ingress-nginx",
+		Version:          "4.10.0",
+		ReleaseNamespace: "ingress-nginx",
+		ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+			Inline: "controller:\n  replicaCount: 2\n",
+		},
+	}
+	invalidName := validHelmChart
+	invalidName.Name = "Ingress_NGINX"
+	missingVersion := validHelmChart
+	missingVersion.Version = ""
+
+	capitest.ValidateDiscoverVariables(
+		t,
+		clusterconfig.MetaVariableName,
+		ptr.To(v1alpha1.GenericClusterConfig{}.VariableSchema()),
+		false,
+		clusterconfig.NewVariable,
+		capitest.VariableTestDef{
+			Name: "valid Helm chart",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					HelmCharts: []v1alpha1.HelmChart{validHelmChart},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "invalid release name",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					HelmCharts: []v1alpha1.HelmChart{invalidName},
+				},
+			},
+			ExpectError: true,
+		},
+		capitest.VariableTestDef{
+			Name: "missing version",
+			Vals: v1alpha1.GenericClusterConfig{
+				Addons: &v1alpha1.Addons{
+					HelmCharts: []v1alpha1.HelmChart{missingVersion},
+				},
+			},
+			ExpectError: true,
+		},
+	)
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
@@ -31,6 +31,11 @@ func (c *crsConfig) AddFlags(prefix string, flags *pflag.FlagSet) {
 	)
 }
 
+// crsName returns the name of the ConfigMap and ClusterResourceSet installing NFD on the cluster.
+func (c *crsConfig) crsName(cluster *clusterv1.Cluster) string {
+	return c.defaultNFDConfigMap + "-" + cluster.Name
+}
+
 type crsStrategy struct {
 	config crsConfig
 
@@ -39,7 +44,7 @@ type crsStrategy struct {
 
 func (s crsStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *clusterv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -65,8 +70,6 @@ func (s crsStrategy) apply(
 
 	log.Info("Ensuring NFD ConfigMap exists for cluster")
 
-	cluster := &req.Cluster
-
 	cm := &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: corev1.SchemeGroupVersion.String(),
@@ -74,12 +77,16 @@ func (s crsStrategy) apply(
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
-			Name:      defaultCM.Name + "-" + cluster.Name,
+			Name:      s.config.crsName(cluster),
 		},
 		Data:       defaultCM.Data,
 		BinaryData: defaultCM.BinaryData,
 	}
 
+	if err := utils.RewriteConfigMapImagesForMirror(cluster, cm); err != nil {
+		return fmt.Errorf("failed to rewrite NFD installation ConfigMap for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, cm); err != nil {
 		return fmt.Errorf(
 			"failed to apply NFD installation ConfigMap: %w",
@@ -87,12 +94,33 @@ func (s crsStrategy) apply(
 		)
 	}
 
-	if err := utils.EnsureCRSForClusterFromObjects(ctx, cm.Name, s.client, cluster, cm); err != nil {
+	if err := utils.EnsureCRSForClusterFromObjects(
+		ctx,
+		cm.Name,
+		s.client,
+		cluster,
+		utils.AddonLabels(cluster, utils.AddonNFD),
+		cm,
+	); err != nil {
 		return fmt.Errorf(
 			"failed to apply NFD installation ClusterResourceSet: %w",
 			err,
 		)
 	}
 
+	// The ClusterResourceSet takes over the objects installed by Helm if the strategy is switched to
+	// ClusterResourceSet.
+	if err := utils.MigrateAddonHelmChartProxiesToClusterResourceSet(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonNFD,
+		cluster.Namespace,
+		helmChartProxyName(cluster),
+		ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: cm.Name},
+	); err != nil {
+		return fmt.Errorf("failed to migrate NFD installation from HelmChartProxy: %w", err)
+	}
+
 	return nil
 }
@@ -12,13 +12,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/config"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/utils"
 )
 
 const (
@@ -44,11 +45,15 @@ type helmAddonStrategy struct {
 
 	client    ctrlclient.Client
 	helmChart *config.HelmChart
+	values    *v1alpha1.AddonValues
+
+	// crsName is the name of the ClusterResourceSet installing the addon with the ClusterResourceSet strategy.
+	crsName string
 }
 
 func (s helmAddonStrategy) apply(
 	ctx context.Context,
-	req *runtimehooksv1.AfterControlPlaneInitializedRequest,
+	cluster *capiv1.Cluster,
 	defaultsNamespace string,
 	log logr.Logger,
 ) error {
@@ -61,41 +66,66 @@ func (s helmAddonStrategy) apply(
 		)
 	}
 
-	values := valuesTemplateConfigMap.Data["values.yaml"]
+	values := valuesTemplateConfigMap.Data[utils.ValuesConfigMapKey]
 	values += fmt.Sprintf(`
 image:
   tag: v%s-minimal
 `, s.helmChart.Version)
 
+	values, err = utils.ApplyValuesOverride(ctx, s.client, cluster, values, s.values)
+	if err != nil {
+		return fmt.Errorf("failed to apply NFD installation values override: %w", err)
+	}
+
+	// Helm adopts the objects applied by the ClusterResourceSet if the strategy is switched to HelmAddon.
+	if err := utils.MigrateAddonClusterResourceSetsToHelmRelease(
+		ctx,
+		s.client,
+		cluster,
+		utils.AddonNFD,
+		cluster.Namespace,
+		s.crsName,
+		utils.HelmRelease{Name: defaultHelmReleaseName, Namespace: defaultHelmReleaseNamespace},
+	); err != nil {
+		return fmt.Errorf("failed to migrate NFD installation from ClusterResourceSet: %w", err)
+	}
+
 	hcp := &caaphv1.HelmChartProxy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: caaphv1.GroupVersion.String(),
 			Kind:       "HelmChartProxy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: req.Cluster.Namespace,
-			Name:      "node-feature-discovery-" + req.Cluster.Name,
+			Namespace: cluster.Namespace,
+			Name:      helmChartProxyName(cluster),
+			Labels:    utils.AddonLabels(cluster, utils.AddonNFD),
 		},
 		Spec: caaphv1.HelmChartProxySpec{
 			RepoURL:   s.helmChart.Repository,
 			ChartName: s.helmChart.Name,
 			ClusterSelector: metav1.LabelSelector{
-				MatchLabels: map[string]string{capiv1.ClusterNameLabel: req.Cluster.Name},
+				MatchLabels: map[string]string{capiv1.ClusterNameLabel: cluster.Name},
 			},
 			ReleaseNamespace: defaultHelmReleaseNamespace,
 			ReleaseName:      defaultHelmReleaseName,
 			Version:          s.helmChart.Version,
+			Credentials:      s.helmChart.Credentials(),
+			TLSConfig:        s.helmChart.TLSConfig(),
 			ValuesTemplate:   values,
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(&req.Cluster, hcp, s.client.Scheme()); err != nil {
+	if err := controllerutil.SetOwnerReference(cluster, hcp, s.client.Scheme()); err != nil {
 		return fmt.Errorf(
 			"failed to set owner reference on NFD installation HelmChartProxy: %w",
 			err,
 		)
 	}
 
+	if err := utils.RewriteHelmChartProxyForMirror(ctx, s.client, cluster, hcp); err != nil {
+		return fmt.Errorf("failed to rewrite NFD installation HelmChartProxy for registry mirror: %w", err)
+	}
+
 	if err := client.ServerSideApply(ctx, s.client, hcp); err != nil {
 		return fmt.Errorf("failed to apply NFD installation HelmChartProxy: %w", err)
 	}
@@ -127,3 +157,8 @@ func (s helmAddonStrategy) retrieveValuesTemplateConfigMap(
 
 	return configMap, nil
 }
+
+// helmChartProxyName returns the name of the HelmChartProxy installing NFD on the cluster.
+func helmChartProxyName(cluster *capiv1.Cluster) string {
+	return "node-feature-discovery-" + cluster.Name
+}
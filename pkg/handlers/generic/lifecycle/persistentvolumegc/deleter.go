@@ -0,0 +1,233 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package persistentvolumegc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers"
+)
+
+const (
+	PersistentVolumeGCAnnotation = handlers.MetadataDomain + "/persistentvolume-gc"
+
+	// provisionedByAnnotation is set by the external-provisioner on dynamically provisioned PersistentVolumes.
+	provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+)
+
+var (
+	ErrFailedToDeleteVolumes = errors.New("failed to delete persistent volumes")
+	ErrVolumesStillExist     = errors.New("waiting for persistent volumes to be fully deleted")
+)
+
+// deletePersistentVolumes deletes all PersistentVolumeClaims and ensures that all dynamically provisioned CSI
+// PersistentVolumes are deleted, rather than retained, by the CSI drivers. It returns ErrVolumesStillExist until
+// all of these PersistentVolumeClaims and PersistentVolumes are fully deleted.
+func deletePersistentVolumes(
+	ctx context.Context,
+	c ctrlclient.Client,
+	log logr.Logger,
+) error {
+	log.Info("Listing PersistentVolumeClaims")
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs); err != nil {
+		return fmt.Errorf("error listing PersistentVolumeClaims: %w", err)
+	}
+
+	var (
+		objsFailedToBeDeleted []ctrlclient.ObjectKey
+		objsStillExisting     []ctrlclient.ObjectKey
+	)
+
+	pvcsStillExisting := sets.New[ctrlclient.ObjectKey]()
+	for idx := range pvcs.Items {
+		pvc := &pvcs.Items[idx]
+		pvcKey := ctrlclient.ObjectKeyFromObject(pvc)
+		objsStillExisting = append(objsStillExisting, pvcKey)
+		pvcsStillExisting.Insert(pvcKey)
+
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+
+		log.Info(fmt.Sprintf("Deleting PersistentVolumeClaim %s", pvcKey))
+		if err := c.Delete(ctx, pvc); ctrlclient.IgnoreNotFound(err) != nil {
+			log.Error(err, fmt.Sprintf("Error deleting PersistentVolumeClaim %s", pvcKey))
+			objsFailedToBeDeleted = append(objsFailedToBeDeleted, pvcKey)
+		}
+	}
+
+	// PersistentVolumeClaims are protected from deletion while they are used by scheduled Pods. Delete these Pods to
+	// release the PersistentVolumeClaims. Any recreated Pods will not be scheduled as the PersistentVolumeClaims are
+	// being deleted.
+	if pvcsStillExisting.Len() > 0 {
+		podKeys, err := deletePodsUsingPersistentVolumeClaims(ctx, c, pvcsStillExisting, log)
+		if err != nil {
+			return err
+		}
+		objsFailedToBeDeleted = append(objsFailedToBeDeleted, podKeys...)
+	}
+
+	log.Info("Listing PersistentVolumes")
+	pvs := &corev1.PersistentVolumeList{}
+	if err := c.List(ctx, pvs); err != nil {
+		return fmt.Errorf("error listing PersistentVolumes: %w", err)
+	}
+	for idx := range pvs.Items {
+		pv := &pvs.Items[idx]
+		if !needsDelete(pv) {
+			continue
+		}
+		pvKey := ctrlclient.ObjectKeyFromObject(pv)
+		objsStillExisting = append(objsStillExisting, pvKey)
+
+		if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimDelete {
+			continue
+		}
+
+		// Change the reclaim policy so that the CSI driver deletes the backing volume once the PersistentVolume is
+		// released, instead of orphaning it.
+		log.Info(fmt.Sprintf("Setting reclaim policy of PersistentVolume %s to Delete", pvKey))
+		patch := ctrlclient.MergeFrom(pv.DeepCopy())
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimDelete
+		if err := c.Patch(ctx, pv, patch); ctrlclient.IgnoreNotFound(err) != nil {
+			log.Error(err, fmt.Sprintf("Error setting reclaim policy of PersistentVolume %s", pvKey))
+			objsFailedToBeDeleted = append(objsFailedToBeDeleted, pvKey)
+		}
+	}
+
+	if len(objsFailedToBeDeleted) > 0 {
+		return failedToDeleteVolumesError(objsFailedToBeDeleted)
+	}
+	if len(objsStillExisting) > 0 {
+		return volumesStillExistError(objsStillExisting)
+	}
+
+	return nil
+}
+
+func deletePodsUsingPersistentVolumeClaims(
+	ctx context.Context,
+	c ctrlclient.Client,
+	pvcs sets.Set[ctrlclient.ObjectKey],
+	log logr.Logger,
+) ([]ctrlclient.ObjectKey, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods); err != nil {
+		return nil, fmt.Errorf("error listing Pods: %w", err)
+	}
+
+	var podsFailedToBeDeleted []ctrlclient.ObjectKey
+	for idx := range pods.Items {
+		pod := &pods.Items[idx]
+		if pod.DeletionTimestamp != nil || pod.Spec.NodeName == "" || !usesAnyPersistentVolumeClaim(pod, pvcs) {
+			continue
+		}
+
+		podKey := ctrlclient.ObjectKeyFromObject(pod)
+		log.Info(fmt.Sprintf("Deleting Pod %s using PersistentVolumeClaims", podKey))
+		if err := c.Delete(ctx, pod); ctrlclient.IgnoreNotFound(err) != nil {
+			log.Error(err, fmt.Sprintf("Error deleting Pod %s", podKey))
+			podsFailedToBeDeleted = append(podsFailedToBeDeleted, podKey)
+		}
+	}
+
+	return podsFailedToBeDeleted, nil
+}
+
+func usesAnyPersistentVolumeClaim(pod *corev1.Pod, pvcs sets.Set[ctrlclient.ObjectKey]) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		if pvcs.Has(ctrlclient.ObjectKey{Namespace: pod.Namespace, Name: volume.PersistentVolumeClaim.ClaimName}) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsDelete will return true if the PersistentVolume has been dynamically provisioned by a CSI driver and needs to
+// be deleted to allow for cluster cleanup.
+func needsDelete(pv *corev1.PersistentVolume) bool {
+	if pv.Spec.CSI == nil {
+		return false
+	}
+	_, provisioned := pv.GetAnnotations()[provisionedByAnnotation]
+	return provisioned
+}
+
+// toStringSlice formats the object keys, omitting the empty namespace of cluster scoped PersistentVolumes.
+func toStringSlice(keys []ctrlclient.ObjectKey) []string {
+	strs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.Namespace == "" {
+			strs = append(strs, k.Name)
+			continue
+		}
+		strs = append(strs, k.String())
+	}
+
+	return strs
+}
+
+func failedToDeleteVolumesError(objsFailedToBeDeleted []ctrlclient.ObjectKey) error {
+	return fmt.Errorf("%w: the following objects could not be deleted "+
+		"and must cleaned up manually before deleting the cluster: %s",
+		ErrFailedToDeleteVolumes,
+		strings.Join(toStringSlice(objsFailedToBeDeleted), ", "),
+	)
+}
+
+func volumesStillExistError(objsStillExisting []ctrlclient.ObjectKey) error {
+	return fmt.Errorf("%w: waiting for the following PersistentVolumeClaims "+
+		"and PersistentVolumes to be fully deleted: %s",
+		ErrVolumesStillExist,
+		strings.Join(toStringSlice(objsStillExisting), ","),
+	)
+}
+
+func shouldDeletePersistentVolumes(cluster *v1beta1.Cluster) (bool, error) {
+	// Use the Cluster annotations to skip deleting
+	val, found := cluster.GetAnnotations()[PersistentVolumeGCAnnotation]
+	if !found {
+		val = "true"
+	}
+	shouldDeleteBasedOnAnnotation, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to convert value %s of annotation %s to bool: %w",
+			val,
+			PersistentVolumeGCAnnotation,
+			err,
+		)
+	}
+
+	// Use the Cluster phase to determine if it's safe to skip deleting:
+	//
+	// - when ClusterPhasePending or ClusterPhaseProvisioning Kubernetes API has not been created
+	// and the user would not have been able to create any PersistentVolumeClaims
+	//
+	// - when ClusterPhaseDeleting it's too late to try to cleanup.
+	phase := cluster.Status.GetTypedPhase()
+	skipDeleteBasedOnPhase := phase == v1beta1.ClusterPhasePending ||
+		phase == v1beta1.ClusterPhaseProvisioning ||
+		phase == v1beta1.ClusterPhaseDeleting
+
+	// use the Cluster conditions to determine if the API server is even reachable
+	controlPlaneReachable := conditions.IsTrue(cluster, v1beta1.ControlPlaneInitializedCondition)
+
+	return shouldDeleteBasedOnAnnotation && controlPlaneReachable && !skipDeleteBasedOnPhase, nil
+}
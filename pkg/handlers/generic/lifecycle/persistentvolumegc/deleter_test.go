@@ -0,0 +1,276 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package persistentvolumegc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+//nolint:funlen // Long tests are OK
+func Test_shouldDeletePersistentVolumes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		cluster      *v1beta1.Cluster
+		shouldDelete bool
+	}{{
+		name: "should delete",
+		cluster: &v1beta1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-should-delete",
+			},
+			Status: v1beta1.ClusterStatus{
+				Conditions: v1beta1.Conditions{{
+					Type:   v1beta1.ControlPlaneInitializedCondition,
+					Status: corev1.ConditionTrue,
+				}},
+				Phase: string(v1beta1.ClusterPhaseProvisioned),
+			},
+		},
+		shouldDelete: true,
+	}, {
+		name: "should not delete: annotation is set to false",
+		cluster: &v1beta1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-should-delete",
+				Annotations: map[string]string{
+					PersistentVolumeGCAnnotation: "false",
+				},
+			},
+			Status: v1beta1.ClusterStatus{
+				Conditions: v1beta1.Conditions{{
+					Type:   v1beta1.ControlPlaneInitializedCondition,
+					Status: corev1.ConditionTrue,
+				}},
+				Phase: string(v1beta1.ClusterPhaseProvisioned),
+			},
+		},
+		shouldDelete: false,
+	}, {
+		name: "should not delete: phase is Deleting",
+		cluster: &v1beta1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-should-delete",
+			},
+			Status: v1beta1.ClusterStatus{
+				Conditions: v1beta1.Conditions{{
+					Type:   v1beta1.ControlPlaneInitializedCondition,
+					Status: corev1.ConditionTrue,
+				}},
+				Phase: string(v1beta1.ClusterPhaseDeleting),
+			},
+		},
+		shouldDelete: false,
+	}, {
+		name: "should not delete: ControlPlaneInitialized condition is False",
+		cluster: &v1beta1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-should-delete",
+			},
+			Status: v1beta1.ClusterStatus{
+				Conditions: v1beta1.Conditions{{
+					Type:   v1beta1.ControlPlaneInitializedCondition,
+					Status: corev1.ConditionFalse,
+				}},
+			},
+		},
+		shouldDelete: false,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			shouldDelete, err := shouldDeletePersistentVolumes(tt.cluster)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.shouldDelete, shouldDelete)
+		})
+	}
+}
+
+func Test_shouldDeletePersistentVolumes_invalidAnnotation(t *testing.T) {
+	t.Parallel()
+
+	cluster := &v1beta1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+			Annotations: map[string]string{
+				PersistentVolumeGCAnnotation: "not-a-bool",
+			},
+		},
+	}
+	_, err := shouldDeletePersistentVolumes(cluster)
+	assert.Error(t, err)
+}
+
+//nolint:funlen // Long tests are OK
+func Test_deletePersistentVolumes(t *testing.T) {
+	t.Parallel()
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: "ns-1",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			VolumeName: "pvc-1234",
+		},
+	}
+	scheduledPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-0",
+			Namespace: "ns-1",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "data",
+					},
+				},
+			}},
+		},
+	}
+	unrelatedPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other",
+			Namespace: "ns-2",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+		},
+	}
+	provisionedPV := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pvc-1234",
+			Annotations: map[string]string{
+				provisionedByAnnotation: "ebs.csi.aws.com",
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:       "ebs.csi.aws.com",
+					VolumeHandle: "vol-1234",
+				},
+			},
+		},
+	}
+	staticPV := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "static",
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/data"},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(pvc, scheduledPod, unrelatedPod, provisionedPV, staticPV).
+		Build()
+
+	err := deletePersistentVolumes(context.Background(), fakeClient, logr.Discard())
+	require.ErrorIs(t, err, ErrVolumesStillExist)
+	assert.ErrorContains(t, err, "ns-1/data")
+	assert.ErrorContains(t, err, "pvc-1234")
+	assert.NotContains(t, err.Error(), "static")
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	require.NoError(t, fakeClient.List(context.Background(), pvcs))
+	assert.Empty(t, pvcs.Items)
+
+	pods := &corev1.PodList{}
+	require.NoError(t, fakeClient.List(context.Background(), pods))
+	require.Len(t, pods.Items, 1)
+	assert.Equal(t, "other", pods.Items[0].Name)
+
+	pv := &corev1.PersistentVolume{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(provisionedPV), pv))
+	assert.Equal(t, corev1.PersistentVolumeReclaimDelete, pv.Spec.PersistentVolumeReclaimPolicy)
+
+	pv = &corev1.PersistentVolume{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(staticPV), pv))
+	assert.Equal(t, corev1.PersistentVolumeReclaimRetain, pv.Spec.PersistentVolumeReclaimPolicy)
+
+	// Simulate the CSI driver deleting the released volume.
+	require.NoError(t, fakeClient.Delete(context.Background(), provisionedPV))
+	require.NoError(t, deletePersistentVolumes(context.Background(), fakeClient, logr.Discard()))
+}
+
+func Test_needsDelete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		pv           *corev1.PersistentVolume
+		shouldDelete bool
+	}{{
+		name: "shouldDelete",
+		pv: &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{provisionedByAnnotation: "csi.nutanix.com"},
+			},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: "csi.nutanix.com"},
+				},
+			},
+		},
+		shouldDelete: true,
+	}, {
+		name: "false: statically provisioned CSI volume",
+		pv: &corev1.PersistentVolume{
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: "csi.nutanix.com"},
+				},
+			},
+		},
+		shouldDelete: false,
+	}, {
+		name: "false: not a CSI volume",
+		pv: &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{provisionedByAnnotation: "kubernetes.io/aws-ebs"},
+			},
+		},
+		shouldDelete: false,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			del := needsDelete(tt.pv)
+			assert.Equal(t, tt.shouldDelete, del)
+		})
+	}
+}
+
+// this test is mainly here to visually show what the error will look like.
+func Test_volumesStillExistError(t *testing.T) {
+	objs := []client.ObjectKey{
+		{Namespace: "ns-1", Name: "data-1"},
+		{Namespace: "ns-2", Name: "data-2"},
+		{Name: "pvc-1234"},
+	}
+	//nolint:lll // want to show the full error in one line
+	expectedErrString := "waiting for persistent volumes to be fully deleted: waiting for the following PersistentVolumeClaims and PersistentVolumes to be fully deleted: ns-1/data-1,ns-2/data-2,pvc-1234"
+	assert.EqualError(t, volumesStillExistError(objs), expectedErrString)
+}
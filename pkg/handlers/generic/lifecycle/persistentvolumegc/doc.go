@@ -0,0 +1,9 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package persistentvolumegc provides a BeforeClusterDelete handler that deletes all PersistentVolumeClaims in a
+// cluster and waits for the CSI drivers to delete the dynamically provisioned volumes, so that no volumes are
+// orphaned in the infrastructure provider after the cluster has been deleted.
+//
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=watch;list;get
+package persistentvolumegc
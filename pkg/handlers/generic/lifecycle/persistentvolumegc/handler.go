@@ -0,0 +1,115 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package persistentvolumegc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/tools/record"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
+)
+
+type PersistentVolumeGC struct {
+	client   ctrlclient.Client
+	recorder record.EventRecorder
+}
+
+// cleanupStep is the name of the cleanup step reported in Events on the Cluster.
+const cleanupStep = "PersistentVolumes cleanup"
+
+var (
+	_ handlers.Named                = &PersistentVolumeGC{}
+	_ lifecycle.BeforeClusterDelete = &PersistentVolumeGC{}
+)
+
+func New(client ctrlclient.Client, recorder record.EventRecorder) *PersistentVolumeGC {
+	return &PersistentVolumeGC{client: client, recorder: recorder}
+}
+
+func (s *PersistentVolumeGC) Name() string {
+	return "PersistentVolumeGC"
+}
+
+func (s *PersistentVolumeGC) BeforeClusterDelete(
+	ctx context.Context,
+	req *runtimehooksv1.BeforeClusterDeleteRequest,
+	resp *runtimehooksv1.BeforeClusterDeleteResponse,
+) {
+	cluster := &req.Cluster
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
+
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"cluster",
+		clusterKey,
+	)
+
+	shouldDelete, err := shouldDeletePersistentVolumes(cluster)
+	if err != nil {
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
+		resp.Status = runtimehooksv1.ResponseStatusFailure
+		resp.Message = fmt.Sprintf(
+			"error determining if PersistentVolumes should be deleted: %v",
+			err,
+		)
+		return
+	}
+
+	if !shouldDelete {
+		events.CleanupSkipped(
+			s.recorder,
+			cluster,
+			cleanupStep,
+			"cluster is not annotated for deletion of PersistentVolumes",
+		)
+		return
+	}
+
+	log.Info("Will attempt to delete PersistentVolumeClaims and dynamically provisioned PersistentVolumes")
+	remoteClient, err := tracing.NewClusterClient(
+		ctx,
+		"",
+		s.client,
+		clusterKey,
+	)
+	if err != nil {
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
+		resp.Status = runtimehooksv1.ResponseStatusFailure
+		resp.Message = fmt.Sprintf(
+			"error creating remote cluster client: %v",
+			err,
+		)
+		return
+	}
+
+	err = deletePersistentVolumes(ctx, remoteClient, log)
+	switch {
+	case errors.Is(err, ErrFailedToDeleteVolumes):
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(err.Error())
+		resp.SetRetryAfterSeconds(5)
+	case errors.Is(err, ErrVolumesStillExist):
+		events.CleanupInProgress(s.recorder, cluster, cleanupStep, err)
+		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+		resp.SetMessage(err.Error())
+		resp.SetRetryAfterSeconds(5)
+	case err != nil:
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(err.Error())
+		resp.SetRetryAfterSeconds(5)
+	default:
+		events.CleanupCompleted(s.recorder, cluster, cleanupStep)
+		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+	}
+}
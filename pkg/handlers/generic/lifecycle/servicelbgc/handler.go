@@ -8,26 +8,32 @@ import (
 	"errors"
 	"fmt"
 
-	"sigs.k8s.io/cluster-api/controllers/remote"
+	"k8s.io/client-go/tools/record"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/lifecycle"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/lifecycle/events"
 )
 
 type ServiceLoadBalancerGC struct {
-	client ctrlclient.Client
+	client   ctrlclient.Client
+	recorder record.EventRecorder
 }
 
+// cleanupStep is the name of the cleanup step reported in Events on the Cluster.
+const cleanupStep = "LoadBalancer Services cleanup"
+
 var (
 	_ handlers.Named                = &ServiceLoadBalancerGC{}
 	_ lifecycle.BeforeClusterDelete = &ServiceLoadBalancerGC{}
 )
 
-func New(client ctrlclient.Client) *ServiceLoadBalancerGC {
-	return &ServiceLoadBalancerGC{client: client}
+func New(client ctrlclient.Client, recorder record.EventRecorder) *ServiceLoadBalancerGC {
+	return &ServiceLoadBalancerGC{client: client, recorder: recorder}
 }
 
 func (s *ServiceLoadBalancerGC) Name() string {
@@ -39,15 +45,17 @@ func (s *ServiceLoadBalancerGC) BeforeClusterDelete(
 	req *runtimehooksv1.BeforeClusterDeleteRequest,
 	resp *runtimehooksv1.BeforeClusterDeleteResponse,
 ) {
-	clusterKey := ctrlclient.ObjectKeyFromObject(&req.Cluster)
+	cluster := &req.Cluster
+	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
 
 	log := ctrl.LoggerFrom(ctx).WithValues(
 		"cluster",
 		clusterKey,
 	)
 
-	shouldDelete, err := shouldDeleteServicesWithLoadBalancer(&req.Cluster)
+	shouldDelete, err := shouldDeleteServicesWithLoadBalancer(cluster)
 	if err != nil {
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
 		resp.Status = runtimehooksv1.ResponseStatusFailure
 		resp.Message = fmt.Sprintf(
 			"error determining if Services of type LoadBalancer should be deleted: %v",
@@ -57,17 +65,24 @@ func (s *ServiceLoadBalancerGC) BeforeClusterDelete(
 	}
 
 	if !shouldDelete {
+		events.CleanupSkipped(
+			s.recorder,
+			cluster,
+			cleanupStep,
+			"cluster is not annotated for deletion of Services of type LoadBalancer",
+		)
 		return
 	}
 
 	log.Info("Will attempt to delete Services with type LoadBalancer")
-	remoteClient, err := remote.NewClusterClient(
+	remoteClient, err := tracing.NewClusterClient(
 		ctx,
 		"",
 		s.client,
 		clusterKey,
 	)
 	if err != nil {
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
 		resp.Status = runtimehooksv1.ResponseStatusFailure
 		resp.Message = fmt.Sprintf(
 			"error creating remote cluster client: %v",
@@ -79,14 +94,17 @@ func (s *ServiceLoadBalancerGC) BeforeClusterDelete(
 	err = deleteServicesWithLoadBalancer(ctx, remoteClient, log)
 	switch {
 	case errors.Is(err, ErrFailedToDeleteService):
+		events.CleanupFailed(s.recorder, cluster, cleanupStep, err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
 		resp.SetMessage(err.Error())
 		resp.SetRetryAfterSeconds(5)
 	case errors.Is(err, ErrServicesStillExist):
+		events.CleanupInProgress(s.recorder, cluster, cleanupStep, err)
 		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 		resp.SetMessage(err.Error())
 		resp.SetRetryAfterSeconds(5)
 	default:
+		events.CleanupCompleted(s.recorder, cluster, cleanupStep)
 		resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
 	}
 }
@@ -0,0 +1,442 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
+)
+
+const (
+	helmManagedByLabel             = "app.kubernetes.io/managed-by"
+	helmManagedByLabelValue        = "Helm"
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// remoteClusterClient returns a client for the cluster with the given key. It is a variable to allow tests to replace
+// it with a fake client.
+var remoteClusterClient = func(
+	ctx context.Context,
+	c ctrlclient.Client,
+	clusterKey ctrlclient.ObjectKey,
+) (ctrlclient.Client, error) {
+	return tracing.NewClusterClient(ctx, "", c, clusterKey)
+}
+
+// HelmRelease identifies the Helm release that adopts an object applied by a ClusterResourceSet.
+type HelmRelease struct {
+	Name      string
+	Namespace string
+}
+
+// ClusterResourceSetObjects returns the objects applied by the ClusterResourceSet, together with its resources.
+// Resources that no longer exist are ignored.
+func ClusterResourceSetObjects(
+	ctx context.Context,
+	c ctrlclient.Client,
+	crs *crsv1.ClusterResourceSet,
+) ([]unstructured.Unstructured, []ctrlclient.Object, error) {
+	var (
+		objs      []unstructured.Unstructured
+		resources []ctrlclient.Object
+	)
+	for _, ref := range crs.Spec.Resources {
+		key := ctrlclient.ObjectKey{Namespace: crs.Namespace, Name: ref.Name}
+
+		var (
+			resource ctrlclient.Object
+			data     [][]byte
+		)
+		switch crsv1.ClusterResourceSetResourceKind(ref.Kind) {
+		case crsv1.ConfigMapClusterResourceSetResourceKind:
+			cm := &corev1.ConfigMap{}
+			if err := c.Get(ctx, key, cm); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, nil, fmt.Errorf("failed to get ConfigMap %s: %w", key, err)
+			}
+			for _, v := range cm.Data {
+				data = append(data, []byte(v))
+			}
+			resource = cm
+		case crsv1.SecretClusterResourceSetResourceKind:
+			secret := &corev1.Secret{}
+			if err := c.Get(ctx, key, secret); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, nil, fmt.Errorf("failed to get Secret %s: %w", key, err)
+			}
+			for _, v := range secret.Data {
+				data = append(data, v)
+			}
+			resource = secret
+		default:
+			continue
+		}
+
+		for _, d := range data {
+			parsed, err := utilyaml.ToUnstructured(d)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse objects of %s %s: %w", ref.Kind, key, err)
+			}
+			objs = append(objs, parsed...)
+		}
+		resources = append(resources, resource)
+	}
+	return objs, resources, nil
+}
+
+// ClusterResourceSetBoundClusters returns the keys of the clusters the ClusterResourceSet has been applied to.
+func ClusterResourceSetBoundClusters(
+	ctx context.Context,
+	c ctrlclient.Client,
+	crs *crsv1.ClusterResourceSet,
+) ([]ctrlclient.ObjectKey, error) {
+	bindings := &crsv1.ClusterResourceSetBindingList{}
+	if err := c.List(ctx, bindings, ctrlclient.InNamespace(crs.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterResourceSetBindings: %w", err)
+	}
+
+	var clusterKeys []ctrlclient.ObjectKey
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if !isBoundTo(binding, crs) {
+			continue
+		}
+		clusterName := binding.Spec.ClusterName
+		if clusterName == "" {
+			clusterName = binding.Name
+		}
+		clusterKeys = append(clusterKeys, ctrlclient.ObjectKey{Namespace: binding.Namespace, Name: clusterName})
+	}
+	return clusterKeys, nil
+}
+
+func isBoundTo(binding *crsv1.ClusterResourceSetBinding, crs *crsv1.ClusterResourceSet) bool {
+	for _, b := range binding.Spec.Bindings {
+		if b.ClusterResourceSetName == crs.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteClusterResourceSet deletes the ClusterResourceSet and those of its resources that are not referenced by any
+// other ClusterResourceSet in its namespace. The objects applied by the ClusterResourceSet are left untouched.
+func DeleteClusterResourceSet(
+	ctx context.Context,
+	c ctrlclient.Client,
+	crs *crsv1.ClusterResourceSet,
+	resources []ctrlclient.Object,
+) error {
+	if err := c.Delete(ctx, crs); ctrlclient.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete ClusterResourceSet %s: %w", crs.Name, err)
+	}
+
+	crss := &crsv1.ClusterResourceSetList{}
+	if err := c.List(ctx, crss, ctrlclient.InNamespace(crs.Namespace)); err != nil {
+		return fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+	}
+	referenced := sets.New[crsv1.ResourceRef]()
+	for i := range crss.Items {
+		if crss.Items[i].Name == crs.Name {
+			continue
+		}
+		referenced.Insert(crss.Items[i].Spec.Resources...)
+	}
+
+	for _, resource := range resources {
+		kind := crsv1.ConfigMapClusterResourceSetResourceKind
+		if _, ok := resource.(*corev1.Secret); ok {
+			kind = crsv1.SecretClusterResourceSetResourceKind
+		}
+		if referenced.Has(crsv1.ResourceRef{Name: resource.GetName(), Kind: string(kind)}) {
+			continue
+		}
+		if err := c.Delete(ctx, resource); ctrlclient.IgnoreNotFound(err) != nil {
+			return fmt.Errorf(
+				"failed to delete %s %s: %w",
+				kind,
+				ctrlclient.ObjectKeyFromObject(resource),
+				err,
+			)
+		}
+	}
+	return nil
+}
+
+// MigrateAddonClusterResourceSetsToHelmRelease migrates the addon installed for the cluster by ClusterResourceSets in
+// the namespace to the Helm release, see MigrateClusterResourceSetToHelmRelease. The ClusterResourceSets are found by
+// their addon labels, which are first set on the ClusterResourceSet named crsName, the name used to install the addon
+// before the addon labels were introduced.
+func MigrateAddonClusterResourceSetsToHelmRelease(
+	ctx context.Context,
+	c ctrlclient.Client,
+	cluster *clusterv1.Cluster,
+	addon string,
+	namespace string,
+	crsName string,
+	release HelmRelease,
+) error {
+	if err := LabelAddonObject(
+		ctx,
+		c,
+		ctrlclient.ObjectKey{Namespace: namespace, Name: crsName},
+		&crsv1.ClusterResourceSet{},
+		cluster,
+		addon,
+	); err != nil {
+		return fmt.Errorf("failed to label ClusterResourceSet %s: %w", crsName, err)
+	}
+
+	crss := &crsv1.ClusterResourceSetList{}
+	if err := c.List(
+		ctx,
+		crss,
+		ctrlclient.InNamespace(namespace),
+		ctrlclient.MatchingLabels(AddonLabels(cluster, addon)),
+	); err != nil {
+		return fmt.Errorf("failed to list ClusterResourceSets: %w", err)
+	}
+
+	for i := range crss.Items {
+		err := migrateClusterResourceSetToHelmRelease(
+			ctx,
+			c,
+			&crss.Items[i],
+			func(*unstructured.Unstructured) HelmRelease { return release },
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateClusterResourceSetToHelmRelease migrates the objects applied by the ClusterResourceSet to the Helm releases
+// returned by releaseFor, allowing an addon to switch from the ClusterResourceSet to the HelmAddon strategy without
+// downtime. It must be called before the HelmChartProxy installing the releases is created, and again until the
+// ClusterResourceSet is deleted.
+//
+// The objects are labelled and annotated on the clusters the ClusterResourceSet is bound to, so that Helm adopts them
+// when installing the release. Namespaced objects outside the namespace of their release are replaced by the copies
+// the release installs: they are only deleted once the HelmReleaseProxies of the releases are ready on the cluster, so
+// that running workloads are not removed before their replacements are installed. The ClusterResourceSet is then
+// deleted without deleting the objects. Until then, the ClusterResourceSet is annotated with AddonMigratingAnnotation.
+// The migration is a no-op if the ClusterResourceSet does not exist.
+func MigrateClusterResourceSetToHelmRelease(
+	ctx context.Context,
+	c ctrlclient.Client,
+	crsKey ctrlclient.ObjectKey,
+	releaseFor func(obj *unstructured.Unstructured) HelmRelease,
+) error {
+	crs := &crsv1.ClusterResourceSet{}
+	if err := c.Get(ctx, crsKey, crs); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get ClusterResourceSet %s: %w", crsKey, err)
+	}
+
+	return migrateClusterResourceSetToHelmRelease(ctx, c, crs, releaseFor)
+}
+
+func migrateClusterResourceSetToHelmRelease(
+	ctx context.Context,
+	c ctrlclient.Client,
+	crs *crsv1.ClusterResourceSet,
+	releaseFor func(obj *unstructured.Unstructured) HelmRelease,
+) error {
+	if err := markMigrating(ctx, c, crs); err != nil {
+		return fmt.Errorf("failed to mark ClusterResourceSet %s as migrating: %w", crs.Name, err)
+	}
+
+	objs, resources, err := ClusterResourceSetObjects(ctx, c, crs)
+	if err != nil {
+		return err
+	}
+	releases := sets.New[HelmRelease]()
+	for i := range objs {
+		releases.Insert(releaseFor(&objs[i]))
+	}
+
+	clusterKeys, err := ClusterResourceSetBoundClusters(ctx, c, crs)
+	if err != nil {
+		return err
+	}
+	migrated := true
+	for _, clusterKey := range clusterKeys {
+		ready, err := helmReleasesReady(ctx, c, clusterKey, releases)
+		if err != nil {
+			return err
+		}
+		if err := adoptObjects(ctx, c, clusterKey, objs, releaseFor, ready); err != nil {
+			return fmt.Errorf(
+				"failed to migrate resources of ClusterResourceSet %s on cluster %s: %w",
+				crs.Name,
+				clusterKey,
+				err,
+			)
+		}
+		migrated = migrated && ready
+	}
+	if !migrated {
+		return nil
+	}
+
+	return DeleteClusterResourceSet(ctx, c, crs, resources)
+}
+
+// helmReleasesReady returns whether the HelmReleaseProxies installing all the releases on the cluster are ready.
+func helmReleasesReady(
+	ctx context.Context,
+	c ctrlclient.Client,
+	clusterKey ctrlclient.ObjectKey,
+	releases sets.Set[HelmRelease],
+) (bool, error) {
+	hrps := &caaphv1.HelmReleaseProxyList{}
+	if err := c.List(
+		ctx,
+		hrps,
+		ctrlclient.InNamespace(clusterKey.Namespace),
+		ctrlclient.MatchingLabels{clusterv1.ClusterNameLabel: clusterKey.Name},
+	); err != nil {
+		return false, fmt.Errorf("failed to list HelmReleaseProxies: %w", err)
+	}
+
+	ready := sets.New[HelmRelease]()
+	for i := range hrps.Items {
+		hrp := &hrps.Items[i]
+		if conditions.IsTrue(hrp, clusterv1.ReadyCondition) {
+			ready.Insert(HelmRelease{Name: hrp.Spec.ReleaseName, Namespace: hrp.Spec.ReleaseNamespace})
+		}
+	}
+	return ready.IsSuperset(releases), nil
+}
+
+// adoptObjects labels and annotates the objects on the cluster so that Helm adopts them when installing their
+// releases. The namespaced objects outside the namespace of their release are deleted instead if deleteReplaced is
+// true, and left untouched otherwise.
+func adoptObjects(
+	ctx context.Context,
+	c ctrlclient.Client,
+	clusterKey ctrlclient.ObjectKey,
+	objs []unstructured.Unstructured,
+	releaseFor func(obj *unstructured.Unstructured) HelmRelease,
+	deleteReplaced bool,
+) error {
+	remoteClient, err := remoteClusterClient(ctx, c, clusterKey)
+	if err != nil {
+		return fmt.Errorf("error creating remote cluster client: %w", err)
+	}
+
+	for i := range objs {
+		obj := &objs[i]
+		release := releaseFor(obj)
+
+		if obj.GetNamespace() != "" && obj.GetNamespace() != release.Namespace {
+			if !deleteReplaced {
+				continue
+			}
+			err := remoteClient.Delete(ctx, obj)
+			if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				return fmt.Errorf(
+					"failed to delete %s %s: %w",
+					obj.GetKind(),
+					ctrlclient.ObjectKeyFromObject(obj),
+					err,
+				)
+			}
+			continue
+		}
+
+		if err := patchHelmRelease(ctx, remoteClient, obj, &release); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchHelmRelease sets the label and annotations marking the object as managed by the Helm release on the live
+// object, or removes them if release is nil. Objects that do not exist are ignored.
+func patchHelmRelease(
+	ctx context.Context,
+	remoteClient ctrlclient.Client,
+	obj *unstructured.Unstructured,
+	release *HelmRelease,
+) error {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := remoteClient.Get(ctx, ctrlclient.ObjectKeyFromObject(obj), live); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get %s %s: %w", obj.GetKind(), ctrlclient.ObjectKeyFromObject(obj), err)
+	}
+
+	patch := ctrlclient.MergeFrom(live.DeepCopy())
+	objLabels := live.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	annotations := live.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if release != nil {
+		objLabels[helmManagedByLabel] = helmManagedByLabelValue
+		annotations[helmReleaseNameAnnotation] = release.Name
+		annotations[helmReleaseNamespaceAnnotation] = release.Namespace
+	} else {
+		if objLabels[helmManagedByLabel] == helmManagedByLabelValue {
+			delete(objLabels, helmManagedByLabel)
+		}
+		delete(annotations, helmReleaseNameAnnotation)
+		delete(annotations, helmReleaseNamespaceAnnotation)
+	}
+	live.SetLabels(objLabels)
+	live.SetAnnotations(annotations)
+	if err := remoteClient.Patch(ctx, live, patch); err != nil {
+		return fmt.Errorf(
+			"failed to patch %s %s: %w",
+			obj.GetKind(),
+			ctrlclient.ObjectKeyFromObject(obj),
+			err,
+		)
+	}
+	return nil
+}
+
+// markMigrating annotates the object with AddonMigratingAnnotation.
+func markMigrating(ctx context.Context, c ctrlclient.Client, obj ctrlclient.Object) error {
+	if _, ok := obj.GetAnnotations()[AddonMigratingAnnotation]; ok {
+		return nil
+	}
+
+	patch := ctrlclient.MergeFrom(obj.DeepCopyObject().(ctrlclient.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AddonMigratingAnnotation] = ""
+	obj.SetAnnotations(annotations)
+	return c.Patch(ctx, obj, patch)
+}
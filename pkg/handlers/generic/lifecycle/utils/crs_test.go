@@ -0,0 +1,213 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+const migratedManifests = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: node-feature-discovery
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-master
+  namespace: node-feature-discovery
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-gc
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: not-applied
+  namespace: node-feature-discovery
+`
+
+func TestMigrateAddonClusterResourceSetsToHelmRelease(t *testing.T) {
+	testMigrateAddonClusterResourceSetsToHelmRelease(t, true)
+}
+
+// TestMigrateLegacyAddonClusterResourceSetToHelmRelease checks that a ClusterResourceSet created before the addon
+// labels were introduced is found by its name and migrated.
+func TestMigrateLegacyAddonClusterResourceSetToHelmRelease(t *testing.T) {
+	testMigrateAddonClusterResourceSetsToHelmRelease(t, false)
+}
+
+//nolint:funlen // Long tests are OK
+func testMigrateAddonClusterResourceSetsToHelmRelease(t *testing.T, labelled bool) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "node-feature-discovery-test-cluster"},
+		Data:       map[string]string{defaultCRSConfigMapKey: migratedManifests},
+	}
+	crs := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "node-feature-discovery-test-cluster",
+		},
+		Spec: crsv1.ClusterResourceSetSpec{
+			Resources: []crsv1.ResourceRef{
+				{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: cm.Name},
+			},
+			Strategy: string(crsv1.ClusterResourceSetStrategyReconcile),
+			ClusterSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+		},
+	}
+	if labelled {
+		crs.Labels = AddonLabels(cluster, AddonNFD)
+	}
+	// The ClusterResourceSet of another addon must not be migrated.
+	otherCRS := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "aws-ebs-csi-test-cluster",
+			Labels:    AddonLabels(cluster, AddonCSI),
+		},
+	}
+	binding := &crsv1.ClusterResourceSetBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: cluster.Name},
+		Spec: crsv1.ClusterResourceSetBindingSpec{
+			ClusterName: cluster.Name,
+			Bindings:    []*crsv1.ResourceSetBinding{{ClusterResourceSetName: crs.Name}},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm, crs, otherCRS, binding).Build()
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "node-feature-discovery"}}
+	master := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace.Name,
+			Name:      "nfd-master",
+			Labels:    map[string]string{"app": "nfd-master"},
+		},
+	}
+	gc := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "nfd-gc"}}
+	remoteClient := fake.NewClientBuilder().WithObjects(namespace, master, gc).Build()
+
+	originalRemoteClusterClient := remoteClusterClient
+	t.Cleanup(func() { remoteClusterClient = originalRemoteClusterClient })
+	remoteClusterClient = func(
+		_ context.Context,
+		_ ctrlclient.Client,
+		clusterKey ctrlclient.ObjectKey,
+	) (ctrlclient.Client, error) {
+		assert.Equal(t, ctrlclient.ObjectKeyFromObject(cluster), clusterKey)
+		return remoteClient, nil
+	}
+
+	migrate := func() {
+		t.Helper()
+		require.NoError(t, MigrateAddonClusterResourceSetsToHelmRelease(
+			context.Background(),
+			client,
+			cluster,
+			AddonNFD,
+			cluster.Namespace,
+			crs.Name,
+			HelmRelease{Name: "node-feature-discovery", Namespace: "node-feature-discovery"},
+		))
+	}
+
+	// The objects are adopted, but nothing is deleted until the Helm release is ready.
+	migrate()
+	require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(gc), gc))
+	require.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(crs), crs))
+	assert.Contains(t, crs.Annotations, AddonMigratingAnnotation)
+	assert.Equal(t, AddonNFD, crs.Labels[AddonLabel])
+
+	hrp := &caaphv1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "node-feature-discovery-test-cluster-abcde",
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+		},
+		Spec: caaphv1.HelmReleaseProxySpec{
+			ReleaseName:      "node-feature-discovery",
+			ReleaseNamespace: "node-feature-discovery",
+		},
+		Status: caaphv1.HelmReleaseProxyStatus{
+			Conditions: clusterv1.Conditions{{Type: clusterv1.ReadyCondition, Status: corev1.ConditionFalse}},
+		},
+	}
+	require.NoError(t, client.Create(context.Background(), hrp))
+	migrate()
+	require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(gc), gc))
+
+	hrp.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, client.Update(context.Background(), hrp))
+	migrate()
+
+	for _, obj := range []ctrlclient.Object{namespace, master} {
+		require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj))
+		assert.Equal(t, "Helm", obj.GetLabels()["app.kubernetes.io/managed-by"])
+		assert.Equal(t, map[string]string{
+			"meta.helm.sh/release-name":      "node-feature-discovery",
+			"meta.helm.sh/release-namespace": "node-feature-discovery",
+		}, obj.GetAnnotations())
+	}
+	assert.Equal(t, "nfd-master", master.Labels["app"])
+	assert.True(t, apierrors.IsNotFound(
+		remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(gc), gc),
+	))
+
+	for _, obj := range []ctrlclient.Object{crs, cm} {
+		assert.True(
+			t,
+			apierrors.IsNotFound(client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj)),
+			"expected %T %s to be deleted",
+			obj,
+			obj.GetName(),
+		)
+	}
+	assert.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(otherCRS), otherCRS))
+}
+
+func TestMigrateClusterResourceSetToHelmReleaseNotFound(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	assert.NoError(t, MigrateClusterResourceSetToHelmRelease(
+		context.Background(),
+		client,
+		ctrlclient.ObjectKey{Namespace: "default", Name: "missing"},
+		nil,
+	))
+}
@@ -0,0 +1,11 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package utils provides the helpers shared by the addon lifecycle handlers to install addons with
+// ClusterResourceSets and HelmChartProxies, and to migrate addons between the two. The ControlPlane and InfraCluster
+// objects of a cluster are read to render the values templates of the Helm charts.
+//
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=*,verbs=get
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=*,verbs=get
+// +kubebuilder:rbac:groups=addons.cluster.x-k8s.io,resources=helmreleaseproxies,verbs=watch;list;get;patch;delete
+package utils
@@ -0,0 +1,328 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+const (
+	helmReleaseOwnerLabel      = "owner"
+	helmReleaseOwnerLabelValue = "helm"
+	helmReleaseNameLabel       = "name"
+	helmReleaseVersionLabel    = "version"
+	helmReleaseSecretKey       = "release"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// MigrateAddonHelmChartProxiesToClusterResourceSet migrates the addon installed for the cluster by HelmChartProxies in
+// the namespace to the ClusterResourceSet, see MigrateHelmChartProxyToClusterResourceSet. The HelmChartProxies are
+// found by their addon labels, which are first set on the HelmChartProxy named hcpName, the name used to install the
+// addon before the addon labels were introduced.
+func MigrateAddonHelmChartProxiesToClusterResourceSet(
+	ctx context.Context,
+	c ctrlclient.Client,
+	cluster *clusterv1.Cluster,
+	addon string,
+	namespace string,
+	hcpName string,
+	crsKey ctrlclient.ObjectKey,
+) error {
+	if err := LabelAddonObject(
+		ctx,
+		c,
+		ctrlclient.ObjectKey{Namespace: namespace, Name: hcpName},
+		&caaphv1.HelmChartProxy{},
+		cluster,
+		addon,
+	); err != nil {
+		return fmt.Errorf("failed to label HelmChartProxy %s: %w", hcpName, err)
+	}
+
+	hcps := &caaphv1.HelmChartProxyList{}
+	if err := c.List(
+		ctx,
+		hcps,
+		ctrlclient.InNamespace(namespace),
+		ctrlclient.MatchingLabels(AddonLabels(cluster, addon)),
+	); err != nil {
+		return fmt.Errorf("failed to list HelmChartProxies: %w", err)
+	}
+
+	for i := range hcps.Items {
+		if err := migrateHelmChartProxyToClusterResourceSet(ctx, c, &hcps.Items[i], crsKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateHelmChartProxyToClusterResourceSet migrates the Helm releases installed by the HelmChartProxy to the
+// ClusterResourceSet, allowing an addon to switch from the HelmAddon to the ClusterResourceSet strategy without
+// downtime. It must be called after the ClusterResourceSet is created, and again until the HelmChartProxy is deleted.
+//
+// Nothing is changed on the clusters until the ClusterResourceSet has applied its resources, so that the objects it
+// replaces keep running until then. The releases are then orphaned: the HelmReleaseProxies are paused, the objects of
+// the releases that the ClusterResourceSet did not apply are deleted, the others are no longer marked as managed by
+// Helm, and the releases are deleted from the Helm storage of the clusters. The HelmChartProxy and its
+// HelmReleaseProxies are finally deleted, which no longer uninstalls anything. Until then, the HelmChartProxy is
+// annotated with AddonMigratingAnnotation. As with Helm, CustomResourceDefinitions and Namespaces are not deleted. The
+// migration is a no-op if the HelmChartProxy does not exist.
+func MigrateHelmChartProxyToClusterResourceSet(
+	ctx context.Context,
+	c ctrlclient.Client,
+	hcpKey ctrlclient.ObjectKey,
+	crsKey ctrlclient.ObjectKey,
+) error {
+	hcp := &caaphv1.HelmChartProxy{}
+	if err := c.Get(ctx, hcpKey, hcp); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get HelmChartProxy %s: %w", hcpKey, err)
+	}
+
+	return migrateHelmChartProxyToClusterResourceSet(ctx, c, hcp, crsKey)
+}
+
+func migrateHelmChartProxyToClusterResourceSet(
+	ctx context.Context,
+	c ctrlclient.Client,
+	hcp *caaphv1.HelmChartProxy,
+	crsKey ctrlclient.ObjectKey,
+) error {
+	if err := markMigrating(ctx, c, hcp); err != nil {
+		return fmt.Errorf("failed to mark HelmChartProxy %s as migrating: %w", hcp.Name, err)
+	}
+
+	crs := &crsv1.ClusterResourceSet{}
+	if err := c.Get(ctx, crsKey, crs); err != nil {
+		return fmt.Errorf("failed to get ClusterResourceSet %s: %w", crsKey, err)
+	}
+	if !conditions.IsTrue(crs, crsv1.ResourcesAppliedCondition) {
+		return nil
+	}
+	objs, _, err := ClusterResourceSetObjects(ctx, c, crs)
+	if err != nil {
+		return err
+	}
+	applied := sets.New[objectKey]()
+	for i := range objs {
+		applied.Insert(objectKeyOf(&objs[i]))
+	}
+
+	hrps := &caaphv1.HelmReleaseProxyList{}
+	if err := c.List(
+		ctx,
+		hrps,
+		ctrlclient.InNamespace(hcp.Namespace),
+		ctrlclient.MatchingLabels{caaphv1.HelmChartProxyLabelName: hcp.Name},
+	); err != nil {
+		return fmt.Errorf("failed to list HelmReleaseProxies: %w", err)
+	}
+	for i := range hrps.Items {
+		if err := orphanHelmRelease(ctx, c, &hrps.Items[i], applied); err != nil {
+			return fmt.Errorf(
+				"failed to migrate Helm release of HelmReleaseProxy %s to ClusterResourceSet %s: %w",
+				hrps.Items[i].Name,
+				crs.Name,
+				err,
+			)
+		}
+	}
+
+	// The HelmChartProxy is deleted first so that it does not recreate the HelmReleaseProxies.
+	if err := c.Delete(ctx, hcp); ctrlclient.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete HelmChartProxy %s: %w", hcp.Name, err)
+	}
+	for i := range hrps.Items {
+		hrp := &hrps.Items[i]
+		if err := c.Delete(ctx, hrp); ctrlclient.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete HelmReleaseProxy %s: %w", hrp.Name, err)
+		}
+		if !controllerutil.ContainsFinalizer(hrp, caaphv1.HelmReleaseProxyFinalizer) {
+			continue
+		}
+		patch := ctrlclient.MergeFrom(hrp.DeepCopy())
+		controllerutil.RemoveFinalizer(hrp, caaphv1.HelmReleaseProxyFinalizer)
+		if err := c.Patch(ctx, hrp, patch); ctrlclient.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to remove finalizer from HelmReleaseProxy %s: %w", hrp.Name, err)
+		}
+	}
+	return nil
+}
+
+// orphanHelmRelease pauses the HelmReleaseProxy and removes the Helm release it installed from its cluster, leaving
+// the objects with the given keys in place.
+func orphanHelmRelease(
+	ctx context.Context,
+	c ctrlclient.Client,
+	hrp *caaphv1.HelmReleaseProxy,
+	keep sets.Set[objectKey],
+) error {
+	if !annotations.HasPaused(hrp) {
+		patch := ctrlclient.MergeFrom(hrp.DeepCopy())
+		annotations.AddAnnotations(hrp, map[string]string{clusterv1.PausedAnnotation: ""})
+		if err := c.Patch(ctx, hrp, patch); err != nil {
+			return fmt.Errorf("failed to pause HelmReleaseProxy: %w", err)
+		}
+	}
+	if hrp.Spec.ReleaseName == "" {
+		return nil
+	}
+
+	clusterKey := ctrlclient.ObjectKey{Namespace: hrp.Spec.ClusterRef.Namespace, Name: hrp.Spec.ClusterRef.Name}
+	if clusterKey.Namespace == "" {
+		clusterKey.Namespace = hrp.Namespace
+	}
+	remoteClient, err := remoteClusterClient(ctx, c, clusterKey)
+	if err != nil {
+		return fmt.Errorf("error creating remote cluster client: %w", err)
+	}
+
+	release := HelmRelease{Name: hrp.Spec.ReleaseName, Namespace: hrp.Spec.ReleaseNamespace}
+	secrets, err := helmReleaseSecrets(ctx, remoteClient, release)
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	objs, err := helmReleaseObjects(&secrets[len(secrets)-1])
+	if err != nil {
+		return err
+	}
+
+	for i := range objs {
+		obj := &objs[i]
+		namespaced, err := remoteClient.IsObjectNamespaced(obj)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get scope of %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if namespaced && obj.GetNamespace() == "" {
+			obj.SetNamespace(release.Namespace)
+		}
+
+		if keep.Has(objectKeyOf(obj)) {
+			if err := patchHelmRelease(ctx, remoteClient, obj, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		switch obj.GroupVersionKind().GroupKind().String() {
+		case "CustomResourceDefinition.apiextensions.k8s.io", "Namespace":
+			continue
+		}
+		err = remoteClient.Delete(ctx, obj)
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", obj.GetKind(), ctrlclient.ObjectKeyFromObject(obj), err)
+		}
+	}
+
+	for i := range secrets {
+		if err := remoteClient.Delete(ctx, &secrets[i]); ctrlclient.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete Helm release Secret %s: %w", secrets[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// helmReleaseSecrets returns the Secrets storing the revisions of the Helm release, ordered by revision.
+func helmReleaseSecrets(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	release HelmRelease,
+) ([]corev1.Secret, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(
+		ctx,
+		secrets,
+		ctrlclient.InNamespace(release.Namespace),
+		ctrlclient.MatchingLabels{
+			helmReleaseOwnerLabel: helmReleaseOwnerLabelValue,
+			helmReleaseNameLabel:  release.Name,
+		},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list Secrets of Helm release %s/%s: %w", release.Namespace, release.Name, err)
+	}
+
+	revision := func(s *corev1.Secret) int {
+		v, _ := strconv.Atoi(s.Labels[helmReleaseVersionLabel])
+		return v
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		return revision(&secrets.Items[i]) < revision(&secrets.Items[j])
+	})
+	return secrets.Items, nil
+}
+
+// helmReleaseObjects returns the objects in the manifest of the Helm release revision stored in the Secret.
+func helmReleaseObjects(secret *corev1.Secret) ([]unstructured.Unstructured, error) {
+	data, err := base64.StdEncoding.DecodeString(string(secret.Data[helmReleaseSecretKey]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Helm release Secret %s: %w", secret.Name, err)
+	}
+	if bytes.HasPrefix(data, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress Helm release Secret %s: %w", secret.Name, err)
+		}
+		defer r.Close()
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("failed to decompress Helm release Secret %s: %w", secret.Name, err)
+		}
+	}
+
+	var release struct {
+		Manifest string `json:"manifest"`
+	}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Helm release Secret %s: %w", secret.Name, err)
+	}
+	objs, err := utilyaml.ToUnstructured([]byte(release.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of Helm release Secret %s: %w", secret.Name, err)
+	}
+	return objs, nil
+}
+
+type objectKey struct {
+	groupKind schema.GroupKind
+	ctrlclient.ObjectKey
+}
+
+func objectKeyOf(obj *unstructured.Unstructured) objectKey {
+	return objectKey{
+		groupKind: obj.GroupVersionKind().GroupKind(),
+		ObjectKey: ctrlclient.ObjectKeyFromObject(obj),
+	}
+}
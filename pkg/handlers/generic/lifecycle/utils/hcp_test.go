@@ -0,0 +1,257 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crsv1 "sigs.k8s.io/cluster-api/exp/addons/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+)
+
+const crsManifests = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: node-feature-discovery
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-master
+  namespace: node-feature-discovery
+`
+
+const helmReleaseManifest = `---
+# Source: node-feature-discovery/templates/master.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-master
+---
+# Source: node-feature-discovery/templates/gc.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nfd-gc
+`
+
+func helmReleaseSecret(t *testing.T, release HelmRelease, version, manifest string) *corev1.Secret {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{"name": release.Name, "manifest": manifest})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: release.Namespace,
+			Name:      "sh.helm.release.v1." + release.Name + ".v" + version,
+			Labels: map[string]string{
+				"owner":   "helm",
+				"name":    release.Name,
+				"version": version,
+			},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+	}
+}
+
+//nolint:funlen // Long tests are OK
+func TestMigrateAddonHelmChartProxiesToClusterResourceSet(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crsv1.AddToScheme(scheme))
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-cluster"},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "node-feature-discovery-test-cluster"},
+		Data:       map[string]string{defaultCRSConfigMapKey: crsManifests},
+	}
+	crs := &crsv1.ClusterResourceSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "node-feature-discovery-test-cluster",
+			Labels:    AddonLabels(cluster, AddonNFD),
+		},
+		Spec: crsv1.ClusterResourceSetSpec{
+			Resources: []crsv1.ResourceRef{
+				{Kind: string(crsv1.ConfigMapClusterResourceSetResourceKind), Name: cm.Name},
+			},
+		},
+		Status: crsv1.ClusterResourceSetStatus{
+			Conditions: clusterv1.Conditions{
+				{Type: crsv1.ResourcesAppliedCondition, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+	hcp := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "node-feature-discovery-test-cluster",
+			Labels:    AddonLabels(cluster, AddonNFD),
+		},
+	}
+	// The HelmChartProxy of another addon must not be migrated.
+	otherHCP := &caaphv1.HelmChartProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "aws-ebs-csi-test-cluster",
+			Labels:    AddonLabels(cluster, AddonCSI),
+		},
+	}
+	hrp := &caaphv1.HelmReleaseProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "default",
+			Name:       "node-feature-discovery-test-cluster-abcde",
+			Labels:     map[string]string{caaphv1.HelmChartProxyLabelName: hcp.Name},
+			Finalizers: []string{caaphv1.HelmReleaseProxyFinalizer},
+		},
+		Spec: caaphv1.HelmReleaseProxySpec{
+			ClusterRef:       corev1.ObjectReference{Name: cluster.Name},
+			ReleaseName:      "node-feature-discovery",
+			ReleaseNamespace: "node-feature-discovery",
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm, crs, hcp, otherHCP, hrp).Build()
+
+	release := HelmRelease{Name: "node-feature-discovery", Namespace: "node-feature-discovery"}
+	helmLabels := map[string]string{"app.kubernetes.io/managed-by": "Helm"}
+	helmAnnotations := map[string]string{
+		"meta.helm.sh/release-name":      release.Name,
+		"meta.helm.sh/release-namespace": release.Namespace,
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: release.Namespace}}
+	master := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   release.Namespace,
+			Name:        "nfd-master",
+			Labels:      helmLabels,
+			Annotations: helmAnnotations,
+		},
+	}
+	gc := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   release.Namespace,
+			Name:        "nfd-gc",
+			Labels:      helmLabels,
+			Annotations: helmAnnotations,
+		},
+	}
+	secrets := []ctrlclient.Object{
+		helmReleaseSecret(t, release, "1", ""),
+		helmReleaseSecret(t, release, "2", helmReleaseManifest),
+	}
+	restMapper := meta.NewDefaultRESTMapper(
+		[]schema.GroupVersion{appsv1.SchemeGroupVersion, corev1.SchemeGroupVersion},
+	)
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	restMapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	remoteClient := fake.NewClientBuilder().
+		WithRESTMapper(restMapper).
+		WithObjects(append(secrets, namespace, master, gc)...).
+		Build()
+
+	originalRemoteClusterClient := remoteClusterClient
+	t.Cleanup(func() { remoteClusterClient = originalRemoteClusterClient })
+	remoteClusterClient = func(
+		_ context.Context,
+		_ ctrlclient.Client,
+		clusterKey ctrlclient.ObjectKey,
+	) (ctrlclient.Client, error) {
+		assert.Equal(t, ctrlclient.ObjectKeyFromObject(cluster), clusterKey)
+		return remoteClient, nil
+	}
+
+	migrate := func() {
+		t.Helper()
+		require.NoError(t, MigrateAddonHelmChartProxiesToClusterResourceSet(
+			context.Background(),
+			client,
+			cluster,
+			AddonNFD,
+			cluster.Namespace,
+			hcp.Name,
+			ctrlclient.ObjectKeyFromObject(crs),
+		))
+	}
+
+	// Nothing is changed until the ClusterResourceSet has applied its resources.
+	migrate()
+	require.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(hcp), hcp))
+	assert.Contains(t, hcp.Annotations, AddonMigratingAnnotation)
+	require.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(hrp), hrp))
+	require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(gc), gc))
+
+	crs.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, client.Update(context.Background(), crs))
+	migrate()
+
+	require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(master), master))
+	assert.Empty(t, master.Labels)
+	assert.Empty(t, master.Annotations)
+	require.NoError(t, remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(namespace), namespace))
+
+	for _, obj := range append(secrets, gc) {
+		assert.True(
+			t,
+			apierrors.IsNotFound(remoteClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj)),
+			"expected %T %s to be deleted",
+			obj,
+			obj.GetName(),
+		)
+	}
+	for _, obj := range []ctrlclient.Object{hcp, hrp} {
+		assert.True(
+			t,
+			apierrors.IsNotFound(client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(obj), obj)),
+			"expected %T %s to be deleted",
+			obj,
+			obj.GetName(),
+		)
+	}
+	assert.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(otherHCP), otherHCP))
+	assert.NoError(t, client.Get(context.Background(), ctrlclient.ObjectKeyFromObject(crs), crs))
+}
+
+func TestMigrateHelmChartProxyToClusterResourceSetNotFound(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, caaphv1.AddToScheme(scheme))
+	client := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	assert.NoError(t, MigrateHelmChartProxyToClusterResourceSet(
+		context.Background(),
+		client,
+		ctrlclient.ObjectKey{Namespace: "default", Name: "missing"},
+		ctrlclient.ObjectKey{Namespace: "default", Name: "missing"},
+	))
+}
@@ -0,0 +1,405 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	utilyaml "sigs.k8s.io/cluster-api/util/yaml"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+const tigeraInstallationKind = "Installation"
+
+// registryMirror holds the locations addon references are rewritten to.
+type registryMirror struct {
+	// registry is the host and path of the mirror, e.g. registry.example.com/mirror.
+	registry string
+	// chartRepository is the OCI repository the addon Helm charts are pulled from.
+	chartRepository string
+}
+
+// registryMirrorForCluster returns the registry mirror addon references are rewritten to, or nil if the cluster does
+// not enable rewriting addon references in its global image registry mirror.
+func registryMirrorForCluster(cluster *clusterv1.Cluster) (*registryMirror, error) {
+	if cluster.Spec.Topology == nil {
+		return nil, nil
+	}
+
+	varMap := variables.ClusterVariablesToVariablesMap(cluster.Spec.Topology.Variables)
+	globalMirror, found, err := variables.Get[v1alpha1.GlobalImageRegistryMirror](
+		varMap,
+		clusterconfig.MetaVariableName,
+		"globalImageRegistryMirror",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global image registry mirror from cluster definition: %w", err)
+	}
+	if !found || !globalMirror.RewriteAddonReferences {
+		return nil, nil
+	}
+
+	mirrorURL, err := url.ParseRequestURI(globalMirror.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse global image registry mirror URL: %w", err)
+	}
+	mirror := &registryMirror{
+		registry:        mirrorURL.Host + strings.TrimSuffix(mirrorURL.Path, "/"),
+		chartRepository: globalMirror.AddonChartRepository,
+	}
+	if mirror.chartRepository == "" {
+		mirror.chartRepository = "oci://" + mirror.registry
+	}
+	return mirror, nil
+}
+
+// chartImageValue is a value of an addon Helm chart that sets where one of its images is pulled from.
+type chartImageValue struct {
+	// path is the path of the value in the chart values.
+	path []string
+	// repository is the default image repository of the chart for the value, which is rewritten to the mirror. For
+	// charts whose values set the full image reference, it is the default image of the chart version, including its
+	// tag. The value is set to the mirror registry itself if repository is empty.
+	repository string
+	// suffix is appended to the value, e.g. the trailing slash the Tigera operator expects for registries.
+	suffix string
+}
+
+// chartImageValues are the values setting the images of the addon Helm charts, by chart name. Charts that are not
+// listed are only pulled from the addon chart repository of the mirror, their images are left unchanged.
+var chartImageValues = map[string][]chartImageValue{
+	"tigera-operator": {
+		{path: []string{"installation", "registry"}, suffix: "/"},
+		{path: []string{"tigeraOperator", "registry"}},
+	},
+	"cilium": {
+		{path: []string{"image", "repository"}, repository: "quay.io/cilium/cilium"},
+		{path: []string{"operator", "image", "repository"}, repository: "quay.io/cilium/operator"},
+		{path: []string{"envoy", "image", "repository"}, repository: "quay.io/cilium/cilium-envoy"},
+		{path: []string{"hubble", "relay", "image", "repository"}, repository: "quay.io/cilium/hubble-relay"},
+		{path: []string{"hubble", "ui", "backend", "image", "repository"}, repository: "quay.io/cilium/hubble-ui-backend"},
+		{path: []string{"hubble", "ui", "frontend", "image", "repository"}, repository: "quay.io/cilium/hubble-ui"},
+		{path: []string{"certgen", "image", "repository"}, repository: "quay.io/cilium/certgen"},
+		{
+			path:       []string{"clustermesh", "apiserver", "image", "repository"},
+			repository: "quay.io/cilium/clustermesh-apiserver",
+		},
+		{path: []string{"nodeinit", "image", "repository"}, repository: "quay.io/cilium/startup-script"},
+		{path: []string{"preflight", "image", "repository"}, repository: "quay.io/cilium/cilium"},
+	},
+	"node-feature-discovery": {
+		{path: []string{"image", "repository"}, repository: "registry.k8s.io/nfd/node-feature-discovery"},
+	},
+	"cluster-autoscaler": {
+		{path: []string{"image", "repository"}, repository: "registry.k8s.io/autoscaling/cluster-autoscaler"},
+	},
+	"aws-cloud-controller-manager": {
+		{path: []string{"image", "repository"}, repository: "registry.k8s.io/provider-aws/cloud-controller-manager"},
+	},
+	"aws-ebs-csi-driver": {
+		{path: []string{"image", "repository"}, repository: "public.ecr.aws/ebs-csi-driver/aws-ebs-csi-driver"},
+		{
+			path:       []string{"sidecars", "provisioner", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/external-provisioner",
+		},
+		{
+			path:       []string{"sidecars", "attacher", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/external-attacher",
+		},
+		{
+			path:       []string{"sidecars", "snapshotter", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/external-snapshotter/csi-snapshotter",
+		},
+		{
+			path:       []string{"sidecars", "livenessProbe", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/livenessprobe",
+		},
+		{
+			path:       []string{"sidecars", "resizer", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/external-resizer",
+		},
+		{
+			path:       []string{"sidecars", "nodeDriverRegistrar", "image", "repository"},
+			repository: "public.ecr.aws/eks-distro/kubernetes-csi/node-driver-registrar",
+		},
+		{
+			path:       []string{"sidecars", "volumemodifier", "image", "repository"},
+			repository: "public.ecr.aws/ebs-csi-driver/volume-modifier-for-k8s",
+		},
+	},
+	// The Nutanix CSI charts set full image references, the defaults of nutanix-csi-storage v2.6.6 and
+	// nutanix-csi-snapshot v6.3.2 must be updated with the chart versions.
+	"nutanix-csi-storage": {
+		{path: []string{"provisioner", "image"}, repository: "quay.io/karbon/ntnx-csi:v2.6.6"},
+		{
+			path:       []string{"sidecars", "registrar", "image"},
+			repository: "registry.k8s.io/sig-storage/csi-node-driver-registrar:v2.9.1",
+		},
+		{
+			path:       []string{"sidecars", "provisioner", "image"},
+			repository: "registry.k8s.io/sig-storage/csi-provisioner:v3.6.2",
+		},
+		{
+			path:       []string{"sidecars", "snapshotter", "image"},
+			repository: "registry.k8s.io/sig-storage/csi-snapshotter:v6.3.2",
+		},
+		{
+			path:       []string{"sidecars", "snapshotter", "imageBeta"},
+			repository: "registry.k8s.io/sig-storage/csi-snapshotter:v3.0.3",
+		},
+		{
+			path:       []string{"sidecars", "resizer", "image"},
+			repository: "registry.k8s.io/sig-storage/csi-resizer:v1.9.2",
+		},
+		{
+			path:       []string{"sidecars", "livenessprobe", "image"},
+			repository: "registry.k8s.io/sig-storage/livenessprobe:v2.11.0",
+		},
+	},
+	"nutanix-csi-snapshot": {
+		{
+			path:       []string{"controller", "image"},
+			repository: "registry.k8s.io/sig-storage/snapshot-controller:v6.3.2",
+		},
+		{
+			path:       []string{"webhook", "image"},
+			repository: "registry.k8s.io/sig-storage/snapshot-validation-webhook:v6.3.2",
+		},
+	},
+	"nutanix-cloud-provider": {
+		{
+			path:       []string{"image", "repository"},
+			repository: "ghcr.io/nutanix-cloud-native/cloud-provider-nutanix/controller",
+		},
+	},
+}
+
+// RewriteHelmChartProxyForMirror rewrites the HelmChartProxy of an addon to pull its chart from the addon chart
+// repository of the global image registry mirror of the cluster, and sets the values of the chart that configure where
+// its images are pulled from to the mirror, unless already set, see chartImageValues. The HelmChartProxy is left
+// unchanged if the cluster does not enable rewriting addon references. As when applying a values override, the values
+// template is rendered first, see RenderValuesTemplate.
+func RewriteHelmChartProxyForMirror(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	hcp *caaphv1.HelmChartProxy,
+) error {
+	mirror, err := registryMirrorForCluster(cluster)
+	if err != nil || mirror == nil {
+		return err
+	}
+
+	hcp.Spec.RepoURL = mirror.chartRepository
+
+	imageValues, ok := chartImageValues[hcp.Spec.ChartName]
+	if !ok {
+		return nil
+	}
+
+	rendered, err := RenderValuesTemplate(ctx, c, cluster, hcp.Spec.ValuesTemplate)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(rendered), &values); err != nil {
+		return fmt.Errorf("failed to parse values: %w", err)
+	}
+	for _, v := range imageValues {
+		if _, found, _ := unstructured.NestedFieldNoCopy(values, v.path...); found {
+			continue
+		}
+		value := mirror.registry
+		if v.repository != "" {
+			if value, err = rewriteImage(v.repository, mirror.registry); err != nil {
+				return err
+			}
+		}
+		if err := unstructured.SetNestedField(values, value+v.suffix, v.path...); err != nil {
+			return fmt.Errorf("failed to set %s in values: %w", strings.Join(v.path, "."), err)
+		}
+	}
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal values: %w", err)
+	}
+	hcp.Spec.ValuesTemplate = string(b)
+
+	return nil
+}
+
+// RewriteConfigMapImagesForMirror rewrites the container images in the manifests of a ClusterResourceSet ConfigMap to
+// the global image registry mirror of the cluster, keeping the repository path of each image as containerd does when
+// pulling through the mirror. The registry of Tigera Installations is set to the mirror unless already set. The
+// ConfigMap is left unchanged if the cluster does not enable rewriting addon references.
+func RewriteConfigMapImagesForMirror(cluster *clusterv1.Cluster, cm *corev1.ConfigMap) error {
+	mirror, err := registryMirrorForCluster(cluster)
+	if err != nil || mirror == nil {
+		return err
+	}
+
+	data := make(map[string]string, len(cm.Data))
+	for k, v := range cm.Data {
+		objs, isJSONList, err := ParseManifests(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse manifests in key %s of ConfigMap %s: %w", k, cm.Name, err)
+		}
+
+		changed := false
+		for i := range objs {
+			objChanged, err := rewriteObjectImages(&objs[i], mirror.registry)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite images in key %s of ConfigMap %s: %w", k, cm.Name, err)
+			}
+			changed = changed || objChanged
+		}
+		if !changed {
+			data[k] = v
+			continue
+		}
+
+		b, err := SerializeManifests(objs, isJSONList)
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifests in key %s of ConfigMap %s: %w", k, cm.Name, err)
+		}
+		data[k] = string(b)
+	}
+	cm.Data = data
+
+	return nil
+}
+
+// ParseManifests parses the manifests of a ClusterResourceSet ConfigMap key, which are either YAML documents or, as
+// also supported by ClusterResourceSets, a JSON list of objects.
+func ParseManifests(data string) (objs []unstructured.Unstructured, isJSONList bool, err error) {
+	if !strings.HasPrefix(strings.TrimSpace(data), "[") {
+		objs, err = utilyaml.ToUnstructured([]byte(data))
+		return objs, false, err
+	}
+
+	var list []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		return nil, true, err
+	}
+	objs = make([]unstructured.Unstructured, 0, len(list))
+	for _, obj := range list {
+		objs = append(objs, unstructured.Unstructured{Object: obj})
+	}
+	return objs, true, nil
+}
+
+// SerializeManifests serializes the manifests in the same format they were parsed from.
+func SerializeManifests(objs []unstructured.Unstructured, isJSONList bool) ([]byte, error) {
+	if !isJSONList {
+		return utilyaml.FromUnstructured(objs)
+	}
+
+	list := make([]map[string]interface{}, 0, len(objs))
+	for i := range objs {
+		list = append(list, objs[i].Object)
+	}
+	return json.Marshal(list)
+}
+
+func rewriteObjectImages(obj *unstructured.Unstructured, registry string) (bool, error) {
+	if obj.GetKind() == tigeraInstallationKind && strings.HasPrefix(obj.GetAPIVersion(), "operator.tigera.io/") {
+		if _, found, _ := unstructured.NestedString(obj.Object, "spec", "registry"); !found {
+			// The Tigera operator expects the registry to end with a slash.
+			if err := unstructured.SetNestedField(obj.Object, registry+"/", "spec", "registry"); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		return false, nil
+	}
+
+	return rewriteContainerImages(obj.Object, registry)
+}
+
+// rewriteContainerImages rewrites the images of all containers found in the object, e.g. in the Pod template of a
+// Deployment or in the Job template of a CronJob.
+func rewriteContainerImages(obj interface{}, registry string) (bool, error) {
+	changed := false
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			switch k {
+			case "containers", "initContainers", "ephemeralContainers":
+				containers, ok := v.([]interface{})
+				if !ok {
+					continue
+				}
+				for _, c := range containers {
+					container, ok := c.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					image, ok := container["image"].(string)
+					if !ok {
+						continue
+					}
+					rewritten, err := rewriteImage(image, registry)
+					if err != nil {
+						return false, err
+					}
+					if rewritten != image {
+						container["image"] = rewritten
+						changed = true
+					}
+				}
+			default:
+				nestedChanged, err := rewriteContainerImages(v, registry)
+				if err != nil {
+					return false, err
+				}
+				changed = changed || nestedChanged
+			}
+		}
+	case []interface{}:
+		for _, v := range o {
+			nestedChanged, err := rewriteContainerImages(v, registry)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || nestedChanged
+		}
+	}
+	return changed, nil
+}
+
+// rewriteImage replaces the registry of the image with the mirror, e.g. docker.io/calico/node:v3.27.2 is rewritten to
+// registry.example.com/mirror/calico/node:v3.27.2. Images already pulled from the mirror are left unchanged.
+func rewriteImage(image, registry string) (string, error) {
+	if strings.HasPrefix(image, registry+"/") {
+		return image, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image %q: %w", image, err)
+	}
+
+	rewritten := registry + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		rewritten += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		rewritten += "@" + digested.Digest().String()
+	}
+	return rewritten, nil
+}
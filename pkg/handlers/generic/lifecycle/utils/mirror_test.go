@@ -0,0 +1,314 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	caaphv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/sigs.k8s.io/cluster-api-addon-provider-helm/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+const (
+	testManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: tigera-operator
+  namespace: tigera-operator
+spec:
+  template:
+    spec:
+      containers:
+      - image: quay.io/tigera/operator:v1.32.5
+        name: tigera-operator
+      initContainers:
+      - image: busybox:1.36
+        name: init
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+  namespace: kube-system
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: registry.example.com/mirror/cleanup:v1.0.0
+            name: cleanup
+---
+apiVersion: operator.tigera.io/v1
+kind: Installation
+metadata:
+  name: default
+spec:
+  cni:
+    type: Calico
+`
+
+	expectedManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: tigera-operator
+  namespace: tigera-operator
+spec:
+  template:
+    spec:
+      containers:
+      - image: registry.example.com/mirror/tigera/operator:v1.32.5
+        name: tigera-operator
+      initContainers:
+      - image: registry.example.com/mirror/library/busybox:1.36
+        name: init
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+  namespace: kube-system
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: registry.example.com/mirror/cleanup:v1.0.0
+            name: cleanup
+---
+apiVersion: operator.tigera.io/v1
+kind: Installation
+metadata:
+  name: default
+spec:
+  cni:
+    type: Calico
+  registry: registry.example.com/mirror/`
+)
+
+func testMirrorCluster(t *testing.T, mirror *v1alpha1.GlobalImageRegistryMirror) *clusterv1.Cluster {
+	t.Helper()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+		Spec:       clusterv1.ClusterSpec{Topology: &clusterv1.Topology{}},
+	}
+	if mirror != nil {
+		v := capitest.VariableWithValue(clusterconfig.MetaVariableName, mirror, "globalImageRegistryMirror")
+		cluster.Spec.Topology.Variables = []clusterv1.ClusterVariable{{Name: v.Name, Value: v.Value}}
+	}
+	return cluster
+}
+
+//nolint:funlen // Long tests are OK
+func TestRewriteHelmChartProxyForMirror(t *testing.T) {
+	t.Parallel()
+
+	rewrite := &v1alpha1.GlobalImageRegistryMirror{
+		URL:                    "https://registry.example.com/mirror/",
+		RewriteAddonReferences: true,
+	}
+
+	tests := []struct {
+		name                   string
+		mirror                 *v1alpha1.GlobalImageRegistryMirror
+		chartName              string
+		valuesTemplate         string
+		expectedRepoURL        string
+		expectedValuesTemplate string
+	}{{
+		name:                   "no mirror",
+		chartName:              "cilium",
+		valuesTemplate:         "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+		expectedRepoURL:        "https://charts.example.com/",
+		expectedValuesTemplate: "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+	}, {
+		name:                   "mirror without rewriting addon references",
+		mirror:                 &v1alpha1.GlobalImageRegistryMirror{URL: "https://registry.example.com/mirror"},
+		chartName:              "cilium",
+		valuesTemplate:         "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+		expectedRepoURL:        "https://charts.example.com/",
+		expectedValuesTemplate: "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+	}, {
+		name:            "Tigera operator registries",
+		mirror:          rewrite,
+		chartName:       "tigera-operator",
+		valuesTemplate:  "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+		expectedRepoURL: "oci://registry.example.com/mirror",
+		expectedValuesTemplate: `fullnameOverride: test-cluster
+installation:
+  registry: registry.example.com/mirror/
+tigeraOperator:
+  registry: registry.example.com/mirror
+`,
+	}, {
+		name:      "Cilium image repositories not already set",
+		mirror:    rewrite,
+		chartName: "cilium",
+		valuesTemplate: `image:
+  repository: registry.example.com/other/cilium
+`,
+		expectedRepoURL: "oci://registry.example.com/mirror",
+		expectedValuesTemplate: `certgen:
+  image:
+    repository: registry.example.com/mirror/cilium/certgen
+clustermesh:
+  apiserver:
+    image:
+      repository: registry.example.com/mirror/cilium/clustermesh-apiserver
+envoy:
+  image:
+    repository: registry.example.com/mirror/cilium/cilium-envoy
+hubble:
+  relay:
+    image:
+      repository: registry.example.com/mirror/cilium/hubble-relay
+  ui:
+    backend:
+      image:
+        repository: registry.example.com/mirror/cilium/hubble-ui-backend
+    frontend:
+      image:
+        repository: registry.example.com/mirror/cilium/hubble-ui
+image:
+  repository: registry.example.com/other/cilium
+nodeinit:
+  image:
+    repository: registry.example.com/mirror/cilium/startup-script
+operator:
+  image:
+    repository: registry.example.com/mirror/cilium/operator
+preflight:
+  image:
+    repository: registry.example.com/mirror/cilium/cilium
+`,
+	}, {
+		name: "chart repository",
+		mirror: &v1alpha1.GlobalImageRegistryMirror{
+			URL:                    "https://registry.example.com/mirror",
+			RewriteAddonReferences: true,
+			AddonChartRepository:   "oci://registry.example.com/charts",
+		},
+		chartName:              "node-feature-discovery",
+		expectedRepoURL:        "oci://registry.example.com/charts",
+		expectedValuesTemplate: "image:\n  repository: registry.example.com/mirror/nfd/node-feature-discovery\n",
+	}, {
+		name:      "Nutanix CSI full image references",
+		mirror:    rewrite,
+		chartName: "nutanix-csi-snapshot",
+		valuesTemplate: `webhook:
+  image: registry.example.com/other/snapshot-validation-webhook:v6.3.2
+`,
+		expectedRepoURL: "oci://registry.example.com/mirror",
+		expectedValuesTemplate: `controller:
+  image: registry.example.com/mirror/sig-storage/snapshot-controller:v6.3.2
+webhook:
+  image: registry.example.com/other/snapshot-validation-webhook:v6.3.2
+`,
+	}, {
+		name:                   "chart without known image values",
+		mirror:                 rewrite,
+		chartName:              "example-chart",
+		valuesTemplate:         "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+		expectedRepoURL:        "oci://registry.example.com/mirror",
+		expectedValuesTemplate: "fullnameOverride: {{ .Cluster.metadata.name }}\n",
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hcp := &caaphv1.HelmChartProxy{
+				Spec: caaphv1.HelmChartProxySpec{
+					RepoURL:        "https://charts.example.com/",
+					ChartName:      tt.chartName,
+					ValuesTemplate: tt.valuesTemplate,
+				},
+			}
+			require.NoError(t, RewriteHelmChartProxyForMirror(
+				context.Background(),
+				fake.NewFakeClient(),
+				testMirrorCluster(t, tt.mirror),
+				hcp,
+			))
+			assert.Equal(t, tt.expectedRepoURL, hcp.Spec.RepoURL)
+			assert.Equal(t, tt.expectedValuesTemplate, hcp.Spec.ValuesTemplate)
+		})
+	}
+}
+
+func TestRewriteConfigMapImagesForMirror(t *testing.T) {
+	t.Parallel()
+
+	cluster := testMirrorCluster(t, &v1alpha1.GlobalImageRegistryMirror{
+		URL:                    "https://registry.example.com/mirror",
+		RewriteAddonReferences: true,
+	})
+	defaultData := map[string]string{
+		"custom-resources.yaml": testManifests,
+		"storageclass.yaml":     "apiVersion: storage.k8s.io/v1\nkind: StorageClass\n",
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "tigera-operator-test-cluster"},
+		Data:       defaultData,
+	}
+
+	require.NoError(t, RewriteConfigMapImagesForMirror(cluster, cm))
+	assert.Equal(t, map[string]string{
+		"custom-resources.yaml": expectedManifests,
+		"storageclass.yaml":     "apiVersion: storage.k8s.io/v1\nkind: StorageClass\n",
+	}, cm.Data)
+	assert.Equal(t, testManifests, defaultData["custom-resources.yaml"], "the default data must not be modified")
+}
+
+func TestRewriteConfigMapImagesForMirrorJSONList(t *testing.T) {
+	t.Parallel()
+
+	cluster := testMirrorCluster(t, &v1alpha1.GlobalImageRegistryMirror{
+		URL:                    "https://registry.example.com/mirror",
+		RewriteAddonReferences: true,
+	})
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "cilium"},
+		Data: map[string]string{
+			"cilium.json": `[{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"cilium"}},` +
+				`{"apiVersion":"v1","kind":"Pod","spec":{"containers":[{"image":"quay.io/cilium/cilium:v1.15.0"}]}}]`,
+		},
+	}
+
+	require.NoError(t, RewriteConfigMapImagesForMirror(cluster, cm))
+	assert.Equal(t, map[string]string{
+		"cilium.json": `[{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"cilium"}},` +
+			`{"apiVersion":"v1","kind":"Pod","spec":{"containers":` +
+			`[{"image":"registry.example.com/mirror/cilium/cilium:v1.15.0"}]}}]`,
+	}, cm.Data)
+}
+
+func TestRewriteConfigMapImagesForMirrorInvalidImage(t *testing.T) {
+	t.Parallel()
+
+	cluster := testMirrorCluster(t, &v1alpha1.GlobalImageRegistryMirror{
+		URL:                    "https://registry.example.com/mirror",
+		RewriteAddonReferences: true,
+	})
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "invalid"},
+		Data: map[string]string{
+			"pod.yaml": "apiVersion: v1\nkind: Pod\nspec:\n  containers:\n  - image: Invalid:Image:Ref\n",
+		},
+	}
+
+	assert.ErrorContains(t, RewriteConfigMapImagesForMirror(cluster, cm), `failed to parse image "Invalid:Image:Ref"`)
+}
@@ -10,10 +10,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	"sigs.k8s.io/cluster-api/controllers/remote"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/tracing"
 )
 
 // CopySecretToRemoteCluster will get the Secret from srcSecretName
@@ -54,7 +54,7 @@ func CopySecretToRemoteCluster(
 	}
 
 	clusterKey := ctrlclient.ObjectKeyFromObject(cluster)
-	remoteClient, err := remote.NewClusterClient(ctx, "", cl, clusterKey)
+	remoteClient, err := tracing.NewClusterClient(ctx, "", cl, clusterKey)
 	if err != nil {
 		return fmt.Errorf("error creating client for remote cluster: %w", err)
 	}
@@ -18,12 +18,76 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/k8s/client"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers"
 )
 
 const (
 	defaultCRSConfigMapKey = "custom-resources.yaml"
 )
 
+const (
+	// AddonLabel is set on the HelmChartProxies and ClusterResourceSets created to install addons, identifying the
+	// kind of addon they install.
+	AddonLabel = handlers.MetadataDomain + "/addon"
+	// AddonClusterNamespaceLabel is set on the HelmChartProxies and ClusterResourceSets created to install addons,
+	// together with the cluster name label, identifying the cluster the addon is installed for. This is not
+	// necessarily the namespace of the objects, e.g. when the addon is deployed to the management cluster.
+	AddonClusterNamespaceLabel = handlers.MetadataDomain + "/cluster-namespace"
+	// AddonHelmChartLabel is set on the HelmChartProxies created to install the additional Helm charts of a cluster,
+	// identifying the Helm chart in the helmCharts addon they install.
+	AddonHelmChartLabel = handlers.MetadataDomain + "/helm-chart"
+	// AddonMigratingAnnotation is set on the ClusterResourceSets and HelmChartProxies of an addon whose objects are
+	// being migrated to another strategy. The addon is not ready until the migration completes and the annotated
+	// object is deleted.
+	AddonMigratingAnnotation = handlers.MetadataDomain + "/migrating"
+
+	AddonCNI               = "cni"
+	AddonCSI               = "csi"
+	AddonCCM               = "ccm"
+	AddonClusterAutoscaler = "cluster-autoscaler"
+	AddonNFD               = "nfd"
+	AddonHelmCharts        = "helm-charts"
+)
+
+// AddonLabels returns the labels to set on the objects created to install the addon for the cluster.
+func AddonLabels(cluster *clusterv1.Cluster, addon string) map[string]string {
+	return map[string]string{
+		AddonLabel:                 addon,
+		AddonClusterNamespaceLabel: cluster.Namespace,
+		clusterv1.ClusterNameLabel: cluster.Name,
+	}
+}
+
+// LabelAddonObject sets the addon labels on the object with the key, if it exists and is not labelled yet, so that an
+// object created to install the addon for the cluster before the addon labels were introduced is found by its labels.
+// The object is read into obj.
+func LabelAddonObject(
+	ctx context.Context,
+	c ctrlclient.Client,
+	key ctrlclient.ObjectKey,
+	obj ctrlclient.Object,
+	cluster *clusterv1.Cluster,
+	addon string,
+) error {
+	if err := c.Get(ctx, key, obj); err != nil {
+		return ctrlclient.IgnoreNotFound(err)
+	}
+	if _, ok := obj.GetLabels()[AddonLabel]; ok {
+		return nil
+	}
+
+	patch := ctrlclient.MergeFrom(obj.DeepCopyObject().(ctrlclient.Object))
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range AddonLabels(cluster, addon) {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+	return c.Patch(ctx, obj, patch)
+}
+
 var (
 	defaultStorageClassKey = "storageclass.kubernetes.io/is-default-class"
 	defaultStorageClassMap = map[string]string{
@@ -31,11 +95,13 @@ var (
 	}
 )
 
+// EnsureCRSForClusterFromObjects creates a ClusterResourceSet with the labels, applying the objects to the cluster.
 func EnsureCRSForClusterFromObjects(
 	ctx context.Context,
 	crsName string,
 	c ctrlclient.Client,
 	cluster *clusterv1.Cluster,
+	labels map[string]string,
 	objects ...runtime.Object,
 ) error {
 	resources := make([]crsv1.ResourceRef, 0, len(objects))
@@ -71,6 +137,7 @@ func EnsureCRSForClusterFromObjects(
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: cluster.Namespace,
 			Name:      crsName,
+			Labels:    labels,
 		},
 		Spec: crsv1.ClusterResourceSetSpec{
 			Resources: resources,
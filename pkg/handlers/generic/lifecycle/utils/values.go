@@ -0,0 +1,220 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+)
+
+// ValuesConfigMapKey is the key holding the Helm values in both the default values template ConfigMaps and the
+// ConfigMaps referenced by addon values overrides.
+const ValuesConfigMapKey = "values.yaml"
+
+var ErrInvalidValuesOverride = errors.New("exactly one of inline or configMapRef must be set in addon values")
+
+// ApplyValuesOverride returns the values template with the addon values override deep-merged over it. The values
+// template is returned unchanged if there is no override. Otherwise the template is rendered first, see
+// RenderValuesTemplate.
+func ApplyValuesOverride(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	valuesTemplate string,
+	override *v1alpha1.AddonValues,
+) (string, error) {
+	if override == nil {
+		return valuesTemplate, nil
+	}
+
+	values, err := RenderValuesTemplate(ctx, c, cluster, valuesTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	return MergeValuesOverride(ctx, c, cluster, values, override)
+}
+
+// MergeValuesOverride returns the values, which must already be rendered, with the addon values override
+// deep-merged over them. Maps are merged recursively, any other value in the override replaces the default value and
+// a null value in the override removes the default value.
+func MergeValuesOverride(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	values string,
+	override *v1alpha1.AddonValues,
+) (string, error) {
+	if override == nil {
+		return values, nil
+	}
+
+	overrideValues, err := retrieveValuesOverride(ctx, c, cluster, override)
+	if err != nil {
+		return "", err
+	}
+
+	return MergeValues(values, overrideValues)
+}
+
+func retrieveValuesOverride(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	override *v1alpha1.AddonValues,
+) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	switch {
+	case override.Inline != nil && override.ConfigMapRef == nil:
+		if err := json.Unmarshal(override.Inline.Raw, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse inline addon values: %w", err)
+		}
+	case override.Inline == nil && override.ConfigMapRef != nil:
+		configMap := &corev1.ConfigMap{}
+		configMapKey := ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: override.ConfigMapRef.Name}
+		if err := c.Get(ctx, configMapKey, configMap); err != nil {
+			return nil, fmt.Errorf("failed to retrieve addon values ConfigMap %q: %w", configMapKey, err)
+		}
+		data, ok := configMap.Data[ValuesConfigMapKey]
+		if !ok {
+			return nil, fmt.Errorf(
+				"addon values ConfigMap %q does not have the %q key",
+				configMapKey,
+				ValuesConfigMapKey,
+			)
+		}
+		if err := yaml.Unmarshal([]byte(data), &values); err != nil {
+			return nil, fmt.Errorf("failed to parse addon values from ConfigMap %q: %w", configMapKey, err)
+		}
+	default:
+		return nil, ErrInvalidValuesOverride
+	}
+
+	return values, nil
+}
+
+// HelmChartValuesTemplate returns the values template of a Helm chart, either inline or from the referenced ConfigMap
+// in the cluster namespace. The template is rendered by the Cluster API Addon Provider for Helm.
+func HelmChartValuesTemplate(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	valuesTemplate *v1alpha1.HelmChartValuesTemplate,
+) (string, error) {
+	switch {
+	case valuesTemplate == nil:
+		return "", nil
+	case valuesTemplate.Inline != "" && valuesTemplate.ConfigMapRef == nil:
+		return valuesTemplate.Inline, nil
+	case valuesTemplate.Inline == "" && valuesTemplate.ConfigMapRef != nil:
+		configMap := &corev1.ConfigMap{}
+		configMapKey := ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: valuesTemplate.ConfigMapRef.Name}
+		if err := c.Get(ctx, configMapKey, configMap); err != nil {
+			return "", fmt.Errorf("failed to retrieve values template ConfigMap %q: %w", configMapKey, err)
+		}
+		data, ok := configMap.Data[ValuesConfigMapKey]
+		if !ok {
+			return "", fmt.Errorf(
+				"values template ConfigMap %q does not have the %q key",
+				configMapKey,
+				ValuesConfigMapKey,
+			)
+		}
+		return data, nil
+	default:
+		return "", errors.New("exactly one of inline or configMapRef must be set in the values template")
+	}
+}
+
+// RenderValuesTemplate renders the values template for the cluster the same way the Cluster API Addon Provider for
+// Helm does, so that the rendered values can be parsed and merged. The Cluster, ControlPlane and InfraCluster builtins
+// are available to the template. As the rendered values no longer follow changes to these objects, they are rendered
+// again whenever the addon is applied.
+func RenderValuesTemplate(
+	ctx context.Context,
+	c ctrlclient.Reader,
+	cluster *clusterv1.Cluster,
+	valuesTemplate string,
+) (string, error) {
+	unstructuredCluster, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert cluster to unstructured: %w", err)
+	}
+	builtins := map[string]interface{}{"Cluster": unstructuredCluster}
+
+	references := map[string]*corev1.ObjectReference{
+		"ControlPlane": cluster.Spec.ControlPlaneRef,
+		"InfraCluster": cluster.Spec.InfrastructureRef,
+	}
+	for name, ref := range references {
+		if ref == nil {
+			continue
+		}
+		obj, err := external.Get(ctx, c, ref, cluster.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s %q for values template: %w", ref.Kind, ref.Name, err)
+		}
+		builtins[name] = obj.Object
+	}
+
+	tmpl, err := template.New("values").Funcs(sprig.TxtFuncMap()).Parse(valuesTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse values template: %w", err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, builtins); err != nil {
+		return "", fmt.Errorf("failed to render values template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// MergeValues returns the values, which must already be rendered, with the given values deep-merged over them in the
+// same way as an addon values override.
+func MergeValues(values string, override map[string]interface{}) (string, error) {
+	defaultValues := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(values), &defaultValues); err != nil {
+		return "", fmt.Errorf("failed to parse default values: %w", err)
+	}
+
+	merged, err := yaml.Marshal(mergeValues(defaultValues, override))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal merged values: %w", err)
+	}
+	return string(merged), nil
+}
+
+func mergeValues(dst, src map[string]interface{}) map[string]interface{} {
+	for k, srcValue := range src {
+		if srcValue == nil {
+			delete(dst, k)
+			continue
+		}
+
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeValues(dstMap, srcMap)
+			continue
+		}
+
+		dst[k] = srcValue
+	}
+	return dst
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+)
+
+const testValuesTemplate = `---
+fullnameOverride: "cluster-autoscaler-{{ .Cluster.metadata.name }}"
+extraArgs:
+  enforce-node-group-min-size: true
+  scale-down-delay-after-add: 10m
+tolerations:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/control-plane
+rbac:
+  clusterScoped: false
+`
+
+//nolint:funlen // Long tests are OK
+func TestApplyValuesOverride(t *testing.T) {
+	t.Parallel()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+	}
+	valuesConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: cluster.Namespace},
+		Data: map[string]string{
+			ValuesConfigMapKey: "replicaCount: 2\nextraArgs:\n  scale-down-delay-after-add: 5m\n",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		override       *v1alpha1.AddonValues
+		expectedValues string
+		expectedErr    string
+	}{{
+		name:           "no override",
+		expectedValues: testValuesTemplate,
+	}, {
+		name: "inline override",
+		override: &v1alpha1.AddonValues{
+			Inline: &apiextensionsv1.JSON{
+				Raw: []byte(`{"extraArgs":{"scale-down-delay-after-add":"5m"},"tolerations":[],"rbac":null}`),
+			},
+		},
+		expectedValues: `extraArgs:
+  enforce-node-group-min-size: true
+  scale-down-delay-after-add: 5m
+fullnameOverride: cluster-autoscaler-test-cluster
+tolerations: []
+`,
+	}, {
+		name: "ConfigMap override",
+		override: &v1alpha1.AddonValues{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: valuesConfigMap.Name},
+		},
+		expectedValues: `extraArgs:
+  enforce-node-group-min-size: true
+  scale-down-delay-after-add: 5m
+fullnameOverride: cluster-autoscaler-test-cluster
+rbac:
+  clusterScoped: false
+replicaCount: 2
+tolerations:
+- effect: NoSchedule
+  key: node-role.kubernetes.io/control-plane
+`,
+	}, {
+		name: "missing ConfigMap",
+		override: &v1alpha1.AddonValues{
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "missing"},
+		},
+		expectedErr: `failed to retrieve addon values ConfigMap "test-namespace/missing"`,
+	}, {
+		name:        "neither inline nor ConfigMap override",
+		override:    &v1alpha1.AddonValues{},
+		expectedErr: ErrInvalidValuesOverride.Error(),
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := fake.NewClientBuilder().WithObjects(valuesConfigMap).Build()
+
+			values, err := ApplyValuesOverride(context.Background(), c, cluster, testValuesTemplate, tt.override)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, values)
+		})
+	}
+}
+
+func TestRenderValuesTemplate(t *testing.T) {
+	t.Parallel()
+
+	controlPlane := &controlplanev1.KubeadmControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-control-plane", Namespace: "test-namespace"},
+		Spec:       controlplanev1.KubeadmControlPlaneSpec{Version: "v1.29.2"},
+	}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster", Namespace: "test-namespace"},
+		Spec: clusterv1.ClusterSpec{
+			ControlPlaneRef: &corev1.ObjectReference{
+				APIVersion: controlplanev1.GroupVersion.String(),
+				Kind:       "KubeadmControlPlane",
+				Name:       controlPlane.Name,
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		objs           []ctrlclient.Object
+		expectedValues string
+		expectedErr    string
+	}{{
+		name:           "builtins available",
+		objs:           []ctrlclient.Object{controlPlane},
+		expectedValues: "name: test-cluster\nversion: v1.29.2\n",
+	}, {
+		name:        "missing control plane",
+		expectedErr: `failed to get KubeadmControlPlane "test-cluster-control-plane" for values template`,
+	}}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, controlplanev1.AddToScheme(scheme))
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+
+			values, err := RenderValuesTemplate(
+				context.Background(),
+				c,
+				cluster,
+				"name: {{ .Cluster.metadata.name }}\nversion: {{ .ControlPlane.spec.version }}\n",
+			)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, values)
+		})
+	}
+}
+
+// TestRenderValuesTemplateRBAC checks that the ClusterRole of the chart allows getting the ControlPlane and
+// InfraCluster objects read by RenderValuesTemplate, which the fake client used by the other tests does not enforce.
+func TestRenderValuesTemplateRBAC(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join(
+		"..", "..", "..", "..", "..",
+		"charts", "cluster-api-runtime-extensions-nutanix", "templates", "role.yaml",
+	))
+	require.NoError(t, err)
+	// The role name is a Helm template expression, which is not valid YAML.
+	data = regexp.MustCompile(`\{\{.*?\}\}`).ReplaceAll(data, []byte("chart"))
+
+	role := &rbacv1.ClusterRole{}
+	require.NoError(t, yaml.Unmarshal(data, role))
+
+	cluster := &clusterv1.Cluster{
+		Spec: clusterv1.ClusterSpec{
+			ControlPlaneRef: &corev1.ObjectReference{
+				APIVersion: controlplanev1.GroupVersion.String(),
+				Kind:       "KubeadmControlPlane",
+			},
+			InfrastructureRef: &corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+				Kind:       "AWSCluster",
+			},
+		},
+	}
+	for _, ref := range []*corev1.ObjectReference{cluster.Spec.ControlPlaneRef, cluster.Spec.InfrastructureRef} {
+		assert.True(t, allowsGet(role, ref.GroupVersionKind().Group), "role does not allow getting %s", ref.Kind)
+	}
+}
+
+func allowsGet(role *rbacv1.ClusterRole, group string) bool {
+	for _, rule := range role.Rules {
+		if slices.Contains(rule.APIGroups, group) && slices.Contains(rule.Resources, "*") &&
+			slices.Contains(rule.Verbs, "get") {
+			return true
+		}
+	}
+	return false
+}
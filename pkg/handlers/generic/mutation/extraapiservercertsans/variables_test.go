@@ -33,6 +33,12 @@ func TestVariableValidation(t *testing.T) {
 			},
 			ExpectError: true,
 		},
+		capitest.VariableTestDef{
+			Name: "valid IPv4 and IPv6 SANs",
+			Vals: v1alpha1.GenericClusterConfig{
+				ExtraAPIServerCertSANs: v1alpha1.ExtraAPIServerCertSANs{"10.20.100.10", "fd00:20:100::10"},
+			},
+		},
 		capitest.VariableTestDef{
 			Name: "duplicate valid SANs",
 			Vals: v1alpha1.GenericClusterConfig{
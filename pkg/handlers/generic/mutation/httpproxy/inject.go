@@ -6,10 +6,12 @@ package httpproxy
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	netutils "k8s.io/utils/net"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
@@ -31,6 +33,10 @@ const (
 	// instanceMetadataIP is the IPv4 address used to retrieve
 	// instance metadata in AWS, Azure, OpenStack, etc.
 	instanceMetadataIP = "169.254.169.254"
+
+	// awsInstanceMetadataIPv6 is the IPv6 address used to retrieve
+	// instance metadata in AWS.
+	awsInstanceMetadataIPv6 = "fd00:ec2::254"
 )
 
 type httpProxyPatchHandler struct {
@@ -145,20 +151,28 @@ func (h *httpProxyPatchHandler) detectNoProxy(
 // in any environment and are preventing the use of proxy for cluster internal
 // networking.
 func generateNoProxy(cluster *capiv1.Cluster) []string {
-	noProxy := []string{
-		"localhost",
-		"127.0.0.1",
-	}
-
+	var cidrs []string
 	if cluster.Spec.ClusterNetwork != nil &&
 		cluster.Spec.ClusterNetwork.Pods != nil {
-		noProxy = append(noProxy, cluster.Spec.ClusterNetwork.Pods.CIDRBlocks...)
+		cidrs = append(cidrs, cluster.Spec.ClusterNetwork.Pods.CIDRBlocks...)
 	}
 
 	if cluster.Spec.ClusterNetwork != nil &&
 		cluster.Spec.ClusterNetwork.Services != nil {
-		noProxy = append(noProxy, cluster.Spec.ClusterNetwork.Services.CIDRBlocks...)
+		cidrs = append(cidrs, cluster.Spec.ClusterNetwork.Services.CIDRBlocks...)
+	}
+
+	// IPv6 only and dual-stack clusters.
+	hasIPv6 := slices.ContainsFunc(cidrs, netutils.IsIPv6CIDRString)
+
+	noProxy := []string{
+		"localhost",
+		"127.0.0.1",
+	}
+	if hasIPv6 {
+		noProxy = append(noProxy, "::1")
 	}
+	noProxy = append(noProxy, cidrs...)
 
 	serviceDomain := "cluster.local"
 	if cluster.Spec.ClusterNetwork != nil &&
@@ -189,6 +203,10 @@ func generateNoProxy(cluster *capiv1.Cluster) []string {
 			// Exclude the control plane endpoint
 			".elb.amazonaws.com",
 		)
+		if hasIPv6 {
+			// Exclude the IPv6 instance metadata service
+			noProxy = append(noProxy, awsInstanceMetadataIPv6)
+		}
 	case "AzureCluster", "AzureManagedControlPlane":
 		noProxy = append(
 			noProxy,
@@ -156,6 +156,28 @@ func TestGenerateNoProxy(t *testing.T) {
 			"localhost", "127.0.0.1", "kubernetes", "kubernetes.default",
 			".svc", ".svc.foo.bar",
 		},
+	}, {
+		name: "dual-stack AWS cluster",
+		cluster: &capiv1.Cluster{
+			Spec: capiv1.ClusterSpec{
+				ClusterNetwork: &capiv1.ClusterNetwork{
+					Pods: &capiv1.NetworkRanges{
+						CIDRBlocks: []string{"10.10.0.0/16", "fd00:100:96::/48"},
+					},
+					Services: &capiv1.NetworkRanges{
+						CIDRBlocks: []string{"172.16.0.0/16", "fd00:100:64::/108"},
+					},
+				},
+				InfrastructureRef: &v1.ObjectReference{
+					Kind: "AWSCluster",
+				},
+			},
+		},
+		expectedNoProxy: []string{
+			"localhost", "127.0.0.1", "::1", "10.10.0.0/16", "fd00:100:96::/48", "172.16.0.0/16",
+			"fd00:100:64::/108", "kubernetes", "kubernetes.default", ".svc", ".svc.cluster.local",
+			"169.254.169.254", ".elb.amazonaws.com", "fd00:ec2::254",
+		},
 	}, {
 		name: "all options",
 		cluster: &capiv1.Cluster{
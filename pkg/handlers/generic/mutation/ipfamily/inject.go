@@ -0,0 +1,126 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ipfamily
+
+import (
+	"context"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches/selectors"
+	capiutils "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/utils"
+)
+
+const (
+	// ipv6UnspecifiedAddress makes kubeadm and the kubelet detect the IPv6 address of the node, instead of the IPv4
+	// address detected by default.
+	ipv6UnspecifiedAddress = "::"
+
+	nodeIPKubeletArg = "node-ip"
+)
+
+type ipFamilyPatchHandler struct {
+	client ctrlclient.Reader
+}
+
+func NewPatch(cl ctrlclient.Reader) *ipFamilyPatchHandler {
+	return &ipFamilyPatchHandler{
+		client: cl,
+	}
+}
+
+// Mutate sets the API server advertise address and the kubelet node IP of the kubeadm config specs to the IPv6
+// unspecified address for clusters whose primary IP family is IPv6, which is determined by the order of the network
+// CIDRs of the cluster. Addresses already set are not changed.
+func (h *ipFamilyPatchHandler) Mutate(
+	ctx context.Context,
+	obj *unstructured.Unstructured,
+	vars map[string]apiextensionsv1.JSON,
+	holderRef runtimehooksv1.HolderReference,
+	clusterKey ctrlclient.ObjectKey,
+) error {
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"holderRef", holderRef,
+	)
+
+	cluster := &capiv1.Cluster{}
+	if err := h.client.Get(ctx, clusterKey, cluster); err != nil {
+		return err
+	}
+	ipv6Primary, err := capiutils.IsIPv6Primary(cluster)
+	if err != nil {
+		return err
+	}
+	if !ipv6Primary {
+		log.V(5).Info("cluster primary IP family is not IPv6 - skipping")
+		return nil
+	}
+
+	if err := patches.MutateIfApplicable(
+		obj, vars, &holderRef, selectors.ControlPlane(), log,
+		func(obj *controlplanev1.KubeadmControlPlaneTemplate) error {
+			log.WithValues(
+				"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
+				"patchedObjectName", ctrlclient.ObjectKeyFromObject(obj),
+			).Info("setting IPv6 advertise address and node IP in control plane kubeadm config spec")
+
+			spec := &obj.Spec.Template.Spec.KubeadmConfigSpec
+			if spec.InitConfiguration == nil {
+				spec.InitConfiguration = &bootstrapv1.InitConfiguration{}
+			}
+			if spec.InitConfiguration.LocalAPIEndpoint.AdvertiseAddress == "" {
+				spec.InitConfiguration.LocalAPIEndpoint.AdvertiseAddress = ipv6UnspecifiedAddress
+			}
+			setNodeIP(&spec.InitConfiguration.NodeRegistration)
+
+			if spec.JoinConfiguration == nil {
+				spec.JoinConfiguration = &bootstrapv1.JoinConfiguration{}
+			}
+			if spec.JoinConfiguration.ControlPlane == nil {
+				spec.JoinConfiguration.ControlPlane = &bootstrapv1.JoinControlPlane{}
+			}
+			if spec.JoinConfiguration.ControlPlane.LocalAPIEndpoint.AdvertiseAddress == "" {
+				spec.JoinConfiguration.ControlPlane.LocalAPIEndpoint.AdvertiseAddress = ipv6UnspecifiedAddress
+			}
+			setNodeIP(&spec.JoinConfiguration.NodeRegistration)
+
+			return nil
+		}); err != nil {
+		return err
+	}
+
+	return patches.MutateIfApplicable(
+		obj, vars, &holderRef, selectors.WorkersKubeadmConfigTemplateSelector(), log,
+		func(obj *bootstrapv1.KubeadmConfigTemplate) error {
+			log.WithValues(
+				"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
+				"patchedObjectName", ctrlclient.ObjectKeyFromObject(obj),
+			).Info("setting IPv6 node IP in worker node kubeadm config template")
+
+			spec := &obj.Spec.Template.Spec
+			if spec.JoinConfiguration == nil {
+				spec.JoinConfiguration = &bootstrapv1.JoinConfiguration{}
+			}
+			setNodeIP(&spec.JoinConfiguration.NodeRegistration)
+
+			return nil
+		})
+}
+
+func setNodeIP(nodeRegistration *bootstrapv1.NodeRegistrationOptions) {
+	if nodeRegistration.KubeletExtraArgs == nil {
+		nodeRegistration.KubeletExtraArgs = map[string]string{}
+	}
+	if _, ok := nodeRegistration.KubeletExtraArgs[nodeIPKubeletArg]; !ok {
+		nodeRegistration.KubeletExtraArgs[nodeIPKubeletArg] = ipv6UnspecifiedAddress
+	}
+}
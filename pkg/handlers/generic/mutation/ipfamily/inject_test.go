@@ -0,0 +1,110 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ipfamily
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/mutation"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest/request"
+)
+
+func TestIPFamilyPatch(t *testing.T) {
+	gomega.RegisterFailHandler(Fail)
+	RunSpecs(t, "IP family mutator suite")
+}
+
+var _ = Describe("Generate IP family patches", func() {
+	patchGenerator := func() mutation.GeneratePatches {
+		scheme := runtime.NewScheme()
+		gomega.Expect(capiv1.AddToScheme(scheme)).To(gomega.Succeed())
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&capiv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: request.Namespace,
+				Name:      request.ClusterName,
+			},
+			Spec: capiv1.ClusterSpec{
+				ClusterNetwork: &capiv1.ClusterNetwork{
+					Pods: &capiv1.NetworkRanges{
+						CIDRBlocks: []string{"fd00:100:96::/48", "192.168.0.0/16"},
+					},
+					Services: &capiv1.NetworkRanges{
+						CIDRBlocks: []string{"fd00:100:64::/108", "10.128.0.0/12"},
+					},
+				},
+			},
+		}).Build()
+		return mutation.NewMetaGeneratePatchesHandler("", NewPatch(cl)).(mutation.GeneratePatches)
+	}
+
+	testDefs := []capitest.PatchTestDef{
+		{
+			Name:        "IPv6 advertise address and node IP set in control plane kubeadm config spec",
+			RequestItem: request.NewKubeadmControlPlaneTemplateRequestItem(""),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{
+				{
+					Operation:    "add",
+					Path:         "/spec/template/spec/kubeadmConfigSpec/initConfiguration/localAPIEndpoint/advertiseAddress",
+					ValueMatcher: gomega.Equal("::"),
+				},
+				{
+					Operation:    "add",
+					Path:         "/spec/template/spec/kubeadmConfigSpec/initConfiguration/nodeRegistration/kubeletExtraArgs",
+					ValueMatcher: gomega.HaveKeyWithValue("node-ip", "::"),
+				},
+				{
+					Operation: "add",
+					Path:      "/spec/template/spec/kubeadmConfigSpec/joinConfiguration/controlPlane",
+					ValueMatcher: gomega.HaveKeyWithValue(
+						"localAPIEndpoint",
+						gomega.HaveKeyWithValue("advertiseAddress", "::"),
+					),
+				},
+				{
+					Operation:    "add",
+					Path:         "/spec/template/spec/kubeadmConfigSpec/joinConfiguration/nodeRegistration/kubeletExtraArgs",
+					ValueMatcher: gomega.HaveKeyWithValue("node-ip", "::"),
+				},
+			},
+		},
+		{
+			Name: "IPv6 node IP set in worker node kubeadm config template",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					"builtin",
+					map[string]any{
+						"machineDeployment": map[string]any{
+							"class": "*",
+						},
+					},
+				),
+			},
+			RequestItem: request.NewKubeadmConfigTemplateRequestItem(""),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{
+				{
+					Operation:    "add",
+					Path:         "/spec/template/spec/joinConfiguration/nodeRegistration/kubeletExtraArgs",
+					ValueMatcher: gomega.HaveKeyWithValue("node-ip", "::"),
+				},
+			},
+		},
+	}
+
+	// create test node for each case
+	for testIdx := range testDefs {
+		tt := testDefs[testIdx]
+		It(tt.Name, func() {
+			capitest.AssertGeneratePatches(GinkgoT(), patchGenerator, &tt)
+		})
+	}
+})
@@ -0,0 +1,111 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeproxy
+
+import (
+	"context"
+	"slices"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/patches/selectors"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+const (
+	// kubeProxyPhase is the kubeadm init phase installing kube-proxy.
+	kubeProxyPhase = "addon/kube-proxy"
+)
+
+type kubeProxyPatchHandler struct {
+	variableName      string
+	variableFieldPath []string
+}
+
+func NewPatch() *kubeProxyPatchHandler {
+	return newKubeProxyPatchHandler(
+		clusterconfig.MetaVariableName,
+		"addons",
+		v1alpha1.CNIVariableName,
+	)
+}
+
+func newKubeProxyPatchHandler(
+	variableName string,
+	variableFieldPath ...string,
+) *kubeProxyPatchHandler {
+	return &kubeProxyPatchHandler{
+		variableName:      variableName,
+		variableFieldPath: variableFieldPath,
+	}
+}
+
+// Mutate skips the installation of kube-proxy by kubeadm when it is replaced by the CNI. Kubeadm control plane
+// upgrades do not reinstall kube-proxy if it is missing from the cluster.
+func (h *kubeProxyPatchHandler) Mutate(
+	ctx context.Context,
+	obj *unstructured.Unstructured,
+	vars map[string]apiextensionsv1.JSON,
+	holderRef runtimehooksv1.HolderReference,
+	_ client.ObjectKey,
+) error {
+	log := ctrl.LoggerFrom(ctx).WithValues(
+		"holderRef", holderRef,
+	)
+
+	cniVar, found, err := variables.Get[v1alpha1.CNI](
+		vars,
+		h.variableName,
+		h.variableFieldPath...,
+	)
+	if err != nil {
+		return err
+	}
+	if !found {
+		log.V(5).Info("cni variable not defined")
+		return nil
+	}
+	if cniVar.Provider != v1alpha1.CNIProviderCilium || cniVar.Cilium == nil || !cniVar.Cilium.KubeProxyReplacement {
+		log.V(5).Info("kube-proxy is not replaced by the CNI - skipping")
+		return nil
+	}
+
+	log = log.WithValues(
+		"variableName",
+		h.variableName,
+		"variableFieldPath",
+		h.variableFieldPath,
+		"variableValue",
+		cniVar,
+	)
+
+	return patches.MutateIfApplicable(
+		obj, vars, &holderRef, selectors.ControlPlane(), log,
+		func(obj *controlplanev1.KubeadmControlPlaneTemplate) error {
+			log.WithValues(
+				"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
+				"patchedObjectName", client.ObjectKeyFromObject(obj),
+			).Info("skipping kube-proxy installation in kubeadm init config spec")
+
+			if obj.Spec.Template.Spec.KubeadmConfigSpec.InitConfiguration == nil {
+				obj.Spec.Template.Spec.KubeadmConfigSpec.InitConfiguration = &bootstrapv1.InitConfiguration{}
+			}
+			initConfiguration := obj.Spec.Template.Spec.KubeadmConfigSpec.InitConfiguration
+			if !slices.Contains(initConfiguration.SkipPhases, kubeProxyPhase) {
+				initConfiguration.SkipPhases = append(initConfiguration.SkipPhases, kubeProxyPhase)
+			}
+
+			return nil
+		},
+	)
+}
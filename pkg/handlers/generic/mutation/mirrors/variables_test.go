@@ -42,6 +42,27 @@ func TestVariableValidation(t *testing.T) {
 				},
 			},
 		},
+		capitest.VariableTestDef{
+			Name: "rewriting addon references",
+			Vals: v1alpha1.GenericClusterConfig{
+				GlobalImageRegistryMirror: &v1alpha1.GlobalImageRegistryMirror{
+					URL:                    "https://a.b.c.example.com/mirror",
+					RewriteAddonReferences: true,
+					AddonChartRepository:   "oci://a.b.c.example.com/charts",
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "addon chart repository without oci scheme",
+			Vals: v1alpha1.GenericClusterConfig{
+				GlobalImageRegistryMirror: &v1alpha1.GlobalImageRegistryMirror{
+					URL:                    "https://a.b.c.example.com/mirror",
+					RewriteAddonReferences: true,
+					AddonChartRepository:   "https://a.b.c.example.com/charts",
+				},
+			},
+			ExpectError: true,
+		},
 		capitest.VariableTestDef{
 			Name: "invalid mirror registry URL",
 			Vals: v1alpha1.GenericClusterConfig{
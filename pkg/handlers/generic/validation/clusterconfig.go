@@ -0,0 +1,231 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+)
+
+// validateClusterConfig returns field errors for the clusterConfig variable. An error is only returned if the
+// variable cannot be decoded.
+func validateClusterConfig(vars map[string]apiextensionsv1.JSON) (field.ErrorList, error) {
+	clusterConfig, found, err := variables.Get[v1alpha1.ClusterConfigSpec](
+		vars,
+		clusterconfig.MetaVariableName,
+	)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	fldPath := field.NewPath(clusterconfig.MetaVariableName)
+
+	var allErrs field.ErrorList
+	if clusterConfig.Addons != nil {
+		allErrs = append(
+			allErrs,
+			validateAddons(&clusterConfig, clusterConfig.Addons, fldPath.Child("addons"))...,
+		)
+	}
+	if clusterConfig.ControlPlane != nil {
+		allErrs = append(
+			allErrs,
+			validateNodeConfig(clusterConfig.ControlPlane, fldPath.Child(clusterconfig.MetaControlPlaneConfigName))...,
+		)
+	}
+
+	return allErrs, nil
+}
+
+func validateAddons(
+	clusterConfig *v1alpha1.ClusterConfigSpec,
+	addons *v1alpha1.Addons,
+	fldPath *field.Path,
+) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if addons.CNI != nil {
+		allErrs = append(allErrs, validateCNI(addons.CNI, fldPath.Child("cni"))...)
+	}
+	if addons.NFD != nil {
+		allErrs = append(allErrs, validateAddonValues(addons.NFD.Values, fldPath.Child("nfd", "values"))...)
+	}
+	if addons.ClusterAutoscaler != nil {
+		allErrs = append(
+			allErrs,
+			validateAddonValues(addons.ClusterAutoscaler.Values, fldPath.Child("clusterAutoscaler", "values"))...,
+		)
+	}
+	if addons.CCM != nil {
+		allErrs = append(allErrs, validateAddonValues(addons.CCM.Values, fldPath.Child("ccm", "values"))...)
+	}
+	if addons.CSIProviders != nil {
+		allErrs = append(allErrs, validateCSI(addons.CSIProviders, fldPath.Child("csi"))...)
+	}
+	allErrs = append(allErrs, validateHelmCharts(addons.HelmCharts, fldPath.Child("helmCharts"))...)
+
+	// The Nutanix CCM cannot be deployed without Prism Central credentials. The CCM variable is shared across
+	// providers so this cannot be required in the variable schema.
+	if addons.CCM != nil && clusterConfig.Nutanix != nil &&
+		(addons.CCM.Credentials == nil || addons.CCM.Credentials.Name == "") {
+		allErrs = append(allErrs, field.Required(
+			fldPath.Child("ccm", "credentials"),
+			"credentials are required for the Nutanix CCM",
+		))
+	}
+	if addons.CCM != nil && clusterConfig.Nutanix != nil &&
+		addons.CCM.Strategy == v1alpha1.AddonStrategyClusterResourceSet {
+		allErrs = append(allErrs, field.NotSupported(
+			fldPath.Child("ccm", "strategy"),
+			addons.CCM.Strategy,
+			[]string{string(v1alpha1.AddonStrategyHelmAddon)},
+		))
+	}
+
+	return allErrs
+}
+
+// validateCNI checks that the Calico network configuration is only set for the Calico CNI provider, and that it does
+// not disable BGP with IPIP encapsulation, as Calico routes IPIP traffic with BGP.
+func validateCNI(cni *v1alpha1.CNI, fldPath *field.Path) field.ErrorList {
+	allErrs := validateAddonValues(cni.Values, fldPath.Child("values"))
+
+	if cni.Calico == nil {
+		return allErrs
+	}
+	calicoPath := fldPath.Child("calico")
+	if cni.Provider != v1alpha1.CNIProviderCalico {
+		return append(allErrs, field.Forbidden(calicoPath, "only valid if the CNI provider is Calico"))
+	}
+	switch cni.Calico.Encapsulation {
+	case v1alpha1.CalicoEncapsulationIPIP, v1alpha1.CalicoEncapsulationIPIPCrossSubnet:
+		if cni.Calico.BGP != nil && !*cni.Calico.BGP {
+			allErrs = append(allErrs, field.Invalid(
+				calicoPath.Child("encapsulation"),
+				cni.Calico.Encapsulation,
+				"IPIP encapsulation requires BGP to be enabled",
+			))
+		}
+	}
+
+	return allErrs
+}
+
+func validateCSI(csi *v1alpha1.CSI, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	providersPath := fldPath.Child("providers")
+	providers := make(map[string]*v1alpha1.CSIProvider, len(csi.Providers))
+	for i := range csi.Providers {
+		provider := &csi.Providers[i]
+		providerPath := providersPath.Index(i)
+
+		if _, ok := providers[provider.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(providerPath.Child("name"), provider.Name))
+			continue
+		}
+		providers[provider.Name] = provider
+
+		allErrs = append(allErrs, validateAddonValues(provider.Values, providerPath.Child("values"))...)
+
+		storageClassNames := sets.New[string]()
+		for j, sc := range provider.StorageClassConfig {
+			if storageClassNames.Has(sc.Name) {
+				allErrs = append(allErrs, field.Duplicate(
+					providerPath.Child("storageClassConfig").Index(j).Child("name"),
+					sc.Name,
+				))
+				continue
+			}
+			storageClassNames.Insert(sc.Name)
+		}
+	}
+
+	if csi.DefaultStorage == nil {
+		return allErrs
+	}
+
+	defaultStoragePath := fldPath.Child("defaultStorage")
+	provider, ok := providers[csi.DefaultStorage.ProviderName]
+	if !ok {
+		return append(allErrs, field.NotFound(
+			defaultStoragePath.Child("providerName"),
+			csi.DefaultStorage.ProviderName,
+		))
+	}
+
+	for _, sc := range provider.StorageClassConfig {
+		if sc.Name == csi.DefaultStorage.StorageClassConfigName {
+			return allErrs
+		}
+	}
+
+	return append(allErrs, field.NotFound(
+		defaultStoragePath.Child("storageClassConfigName"),
+		csi.DefaultStorage.StorageClassConfigName,
+	))
+}
+
+// validateHelmCharts checks that the names of the additional Helm charts are unique, and that their values templates
+// are given either inline or as a ConfigMap reference.
+func validateHelmCharts(helmCharts []v1alpha1.HelmChart, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := sets.New[string]()
+	for i := range helmCharts {
+		helmChart := &helmCharts[i]
+		helmChartPath := fldPath.Index(i)
+
+		if names.Has(helmChart.Name) {
+			allErrs = append(allErrs, field.Duplicate(helmChartPath.Child("name"), helmChart.Name))
+		}
+		names.Insert(helmChart.Name)
+
+		valuesTemplate := helmChart.ValuesTemplate
+		if valuesTemplate == nil {
+			continue
+		}
+		switch {
+		case valuesTemplate.Inline != "" && valuesTemplate.ConfigMapRef != nil:
+			allErrs = append(allErrs, field.Forbidden(
+				helmChartPath.Child("valuesTemplate"),
+				"only one of inline or configMapRef can be set",
+			))
+		case valuesTemplate.Inline == "" && valuesTemplate.ConfigMapRef == nil:
+			allErrs = append(allErrs, field.Required(
+				helmChartPath.Child("valuesTemplate"),
+				"one of inline or configMapRef must be set",
+			))
+		}
+	}
+
+	return allErrs
+}
+
+// validateAddonValues checks that the values override of an addon is given either inline or as a ConfigMap reference.
+func validateAddonValues(values *v1alpha1.AddonValues, fldPath *field.Path) field.ErrorList {
+	if values == nil {
+		return nil
+	}
+
+	switch {
+	case values.Inline != nil && values.ConfigMapRef != nil:
+		return field.ErrorList{field.Forbidden(
+			fldPath,
+			"only one of inline or configMapRef can be set",
+		)}
+	case values.Inline == nil && values.ConfigMapRef == nil:
+		return field.ErrorList{field.Required(
+			fldPath,
+			"one of inline or configMapRef must be set",
+		)}
+	default:
+		return nil
+	}
+}
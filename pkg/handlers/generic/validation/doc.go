@@ -0,0 +1,6 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package validation provides a ValidateTopology handler that performs cross-field validation of the
+// clusterConfig and workerConfig variables that cannot be expressed in the OpenAPI variable schemas.
+package validation
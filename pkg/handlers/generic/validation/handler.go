@@ -0,0 +1,94 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	commonhandlers "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/handlers/mutation"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/workerconfig"
+)
+
+const (
+	// HandlerName is the name of the topology validation handler.
+	HandlerName = "TopologyValidator"
+
+	machineDeploymentKind = "MachineDeployment"
+)
+
+var (
+	_ commonhandlers.Named      = &topologyValidator{}
+	_ mutation.ValidateTopology = &topologyValidator{}
+)
+
+type topologyValidator struct{}
+
+func New() *topologyValidator {
+	return &topologyValidator{}
+}
+
+func (h *topologyValidator) Name() string {
+	return HandlerName
+}
+
+func (h *topologyValidator) ValidateTopology(
+	ctx context.Context,
+	req *runtimehooksv1.ValidateTopologyRequest,
+	resp *runtimehooksv1.ValidateTopologyResponse,
+) {
+	log := ctrl.LoggerFrom(ctx)
+
+	globalVars := variables.HookVariablesToVariablesMap(req.Variables)
+
+	allErrs, err := validateClusterConfig(globalVars)
+	if err != nil {
+		log.Error(err, "failed to read clusterConfig variable")
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(fmt.Sprintf("failed to read clusterConfig variable: %v", err))
+		return
+	}
+
+	// Each MachineDeployment is referenced by multiple items (bootstrap and infrastructure templates) that
+	// share the same variables, so only validate the worker config once per MachineDeployment.
+	validatedMachineDeployments := make(map[string]struct{}, len(req.Items))
+	for _, item := range req.Items {
+		if item == nil || item.HolderReference.Kind != machineDeploymentKind {
+			continue
+		}
+		mdName := item.HolderReference.Name
+		if _, ok := validatedMachineDeployments[mdName]; ok {
+			continue
+		}
+		validatedMachineDeployments[mdName] = struct{}{}
+
+		mdErrs, err := validateWorkerConfig(
+			variables.HookVariablesToVariablesMap(req.Variables, item.Variables),
+			field.NewPath("machineDeployments").Key(mdName).Child(workerconfig.MetaVariableName),
+		)
+		if err != nil {
+			log.Error(err, "failed to read workerConfig variable", "machineDeployment", mdName)
+			resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+			resp.SetMessage(
+				fmt.Sprintf("failed to read workerConfig variable for MachineDeployment %q: %v", mdName, err),
+			)
+			return
+		}
+		allErrs = append(allErrs, mdErrs...)
+	}
+
+	if len(allErrs) > 0 {
+		resp.SetStatus(runtimehooksv1.ResponseStatusFailure)
+		resp.SetMessage(fmt.Sprintf("invalid cluster topology: %v", allErrs.ToAggregate()))
+		return
+	}
+
+	resp.SetStatus(runtimehooksv1.ResponseStatusSuccess)
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
+
+	capxv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/github.com/nutanix-cloud-native/cluster-api-provider-nutanix/api/v1beta1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/testutils/capitest"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/clusterconfig"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/workerconfig"
+)
+
+func validNutanixNodeSpec() *v1alpha1.NutanixNodeSpec {
+	return &v1alpha1.NutanixNodeSpec{
+		MachineDetails: v1alpha1.NutanixMachineDetails{
+			Image: v1alpha1.NutanixResourceIdentifier{
+				Type: capxv1.NutanixIdentifierName,
+				Name: ptr.To("image"),
+			},
+			Cluster: v1alpha1.NutanixResourceIdentifier{
+				Type: capxv1.NutanixIdentifierUUID,
+				UUID: ptr.To("00000000-0000-0000-0000-000000000000"),
+			},
+			Subnets: v1alpha1.NutanixResourceIdentifiers{{
+				Type: capxv1.NutanixIdentifierName,
+				Name: ptr.To("subnet"),
+			}},
+			SystemDiskSize: resource.MustParse("40Gi"),
+		},
+	}
+}
+
+func TestValidateTopology(t *testing.T) {
+	t.Parallel()
+
+	invalidNutanixNodeSpec := validNutanixNodeSpec()
+	invalidNutanixNodeSpec.MachineDetails.Subnets[0].Name = nil
+	invalidNutanixNodeSpec.MachineDetails.SystemDiskSize = resource.MustParse("10Gi")
+
+	testCases := []struct {
+		name             string
+		vars             []runtimehooksv1.Variable
+		items            []*runtimehooksv1.ValidateTopologyRequestItem
+		expectedStatus   runtimehooksv1.ResponseStatus
+		expectedMessages []string
+	}{{
+		name:           "no variables",
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "valid CSI default storage",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CSI{
+					Providers: []v1alpha1.CSIProvider{{
+						Name:               v1alpha1.CSIProviderAWSEBS,
+						StorageClassConfig: []v1alpha1.StorageClassConfig{{Name: "gp3"}},
+					}},
+					DefaultStorage: &v1alpha1.DefaultStorage{
+						ProviderName:           v1alpha1.CSIProviderAWSEBS,
+						StorageClassConfigName: "gp3",
+					},
+				},
+				"addons", "csi",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "CSI default storage references missing provider",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CSI{
+					Providers: []v1alpha1.CSIProvider{{
+						Name:               v1alpha1.CSIProviderAWSEBS,
+						StorageClassConfig: []v1alpha1.StorageClassConfig{{Name: "gp3"}},
+					}},
+					DefaultStorage: &v1alpha1.DefaultStorage{
+						ProviderName:           v1alpha1.CSIProviderNutanix,
+						StorageClassConfigName: "gp3",
+					},
+				},
+				"addons", "csi",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			`clusterConfig.addons.csi.defaultStorage.providerName: Not found: "nutanix"`,
+		},
+	}, {
+		name: "CSI default storage references missing storage class config",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CSI{
+					Providers: []v1alpha1.CSIProvider{{
+						Name:               v1alpha1.CSIProviderAWSEBS,
+						StorageClassConfig: []v1alpha1.StorageClassConfig{{Name: "gp3"}, {Name: "gp3"}},
+					}},
+					DefaultStorage: &v1alpha1.DefaultStorage{
+						ProviderName:           v1alpha1.CSIProviderAWSEBS,
+						StorageClassConfigName: "io2",
+					},
+				},
+				"addons", "csi",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			`clusterConfig.addons.csi.providers[0].storageClassConfig[1].name: Duplicate value: "gp3"`,
+			`clusterConfig.addons.csi.defaultStorage.storageClassConfigName: Not found: "io2"`,
+		},
+	}, {
+		name: "Nutanix CCM without credentials",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.ClusterConfigSpec{
+					Nutanix: &v1alpha1.NutanixSpec{},
+					GenericClusterConfig: v1alpha1.GenericClusterConfig{
+						Addons: &v1alpha1.Addons{CCM: &v1alpha1.CCM{}},
+					},
+				},
+			),
+		},
+		expectedStatus:   runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{"clusterConfig.addons.ccm.credentials: Required value"},
+	}, {
+		name: "Nutanix CCM with credentials",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.ClusterConfigSpec{
+					Nutanix: &v1alpha1.NutanixSpec{},
+					GenericClusterConfig: v1alpha1.GenericClusterConfig{
+						Addons: &v1alpha1.Addons{CCM: &v1alpha1.CCM{
+							Credentials: &corev1.LocalObjectReference{Name: "creds"},
+						}},
+					},
+				},
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "Nutanix CCM with ClusterResourceSet strategy",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.ClusterConfigSpec{
+					Nutanix: &v1alpha1.NutanixSpec{},
+					GenericClusterConfig: v1alpha1.GenericClusterConfig{
+						Addons: &v1alpha1.Addons{CCM: &v1alpha1.CCM{
+							Credentials: &corev1.LocalObjectReference{Name: "creds"},
+							Strategy:    v1alpha1.AddonStrategyClusterResourceSet,
+						}},
+					},
+				},
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			`clusterConfig.addons.ccm.strategy: Unsupported value: "ClusterResourceSet"`,
+		},
+	}, {
+		name: "AWS CCM without credentials",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.ClusterConfigSpec{
+					AWS: &v1alpha1.AWSSpec{},
+					GenericClusterConfig: v1alpha1.GenericClusterConfig{
+						Addons: &v1alpha1.Addons{CCM: &v1alpha1.CCM{}},
+					},
+				},
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "addon values given inline or as ConfigMap reference",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Values: &v1alpha1.AddonValues{
+							Inline: &apiextensionsv1.JSON{Raw: []byte(`{"replicas":2}`)},
+						},
+					},
+					NFD: &v1alpha1.NFD{
+						Values: &v1alpha1.AddonValues{
+							ConfigMapRef: &corev1.LocalObjectReference{Name: "nfd-values"},
+						},
+					},
+				},
+				"addons",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "invalid addon values",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.Addons{
+					CNI: &v1alpha1.CNI{
+						Values: &v1alpha1.AddonValues{
+							Inline:       &apiextensionsv1.JSON{Raw: []byte(`{"replicas":2}`)},
+							ConfigMapRef: &corev1.LocalObjectReference{Name: "cni-values"},
+						},
+					},
+					CSIProviders: &v1alpha1.CSI{
+						Providers: []v1alpha1.CSIProvider{{
+							Name:   v1alpha1.CSIProviderNutanix,
+							Values: &v1alpha1.AddonValues{},
+						}},
+					},
+				},
+				"addons",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			"clusterConfig.addons.cni.values: Forbidden: only one of inline or configMapRef can be set",
+			"clusterConfig.addons.csi.providers[0].values: Required value: one of inline or configMapRef must be set",
+		},
+	}, {
+		name: "Calico IPIP encapsulation with BGP",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CNI{
+					Provider: v1alpha1.CNIProviderCalico,
+					Calico: &v1alpha1.CalicoNetwork{
+						Encapsulation: v1alpha1.CalicoEncapsulationIPIP,
+						BGP:           ptr.To(true),
+					},
+				},
+				"addons", "cni",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusSuccess,
+	}, {
+		name: "Calico IPIP encapsulation without BGP",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CNI{
+					Provider: v1alpha1.CNIProviderCalico,
+					Calico: &v1alpha1.CalicoNetwork{
+						Encapsulation: v1alpha1.CalicoEncapsulationIPIPCrossSubnet,
+						BGP:           ptr.To(false),
+					},
+				},
+				"addons", "cni",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			`clusterConfig.addons.cni.calico.encapsulation: Invalid value: "IPIPCrossSubnet": ` +
+				"IPIP encapsulation requires BGP to be enabled",
+		},
+	}, {
+		name: "Calico network with another CNI provider",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.CNI{
+					Provider: v1alpha1.CNIProviderCilium,
+					Calico:   &v1alpha1.CalicoNetwork{MTU: ptr.To[int32](1440)},
+				},
+				"addons", "cni",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			"clusterConfig.addons.cni.calico: Forbidden: only valid if the CNI provider is Calico",
+		},
+	}, {
+		name: "invalid Helm charts",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				[]v1alpha1.HelmChart{{
+					Name: "ingress-nginx",
+					ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+						Inline: "controller:\n  replicaCount: 2\n",
+					},
+				}, {
+					Name: "ingress-nginx",
+					ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{
+						Inline:       "controller:\n  replicaCount: 2\n",
+						ConfigMapRef: &corev1.LocalObjectReference{Name: "ingress-nginx-values"},
+					},
+				}, {
+					Name:           "kyverno",
+					ValuesTemplate: &v1alpha1.HelmChartValuesTemplate{},
+				}},
+				"addons", "helmCharts",
+			),
+		},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			`clusterConfig.addons.helmCharts[1].name: Duplicate value: "ingress-nginx"`,
+			"clusterConfig.addons.helmCharts[1].valuesTemplate: Forbidden: only one of inline or configMapRef can be set",
+			"clusterConfig.addons.helmCharts[2].valuesTemplate: Required value: one of inline or configMapRef must be set",
+		},
+	}, {
+		name: "invalid Nutanix control plane and worker machine details",
+		vars: []runtimehooksv1.Variable{
+			capitest.VariableWithValue(
+				clusterconfig.MetaVariableName,
+				v1alpha1.NodeConfigSpec{Nutanix: invalidNutanixNodeSpec},
+				clusterconfig.MetaControlPlaneConfigName,
+			),
+			capitest.VariableWithValue(
+				workerconfig.MetaVariableName,
+				v1alpha1.NodeConfigSpec{Nutanix: validNutanixNodeSpec()},
+			),
+		},
+		items: []*runtimehooksv1.ValidateTopologyRequestItem{{
+			HolderReference: runtimehooksv1.HolderReference{Kind: "MachineDeployment", Name: "md-0"},
+			Variables: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					workerconfig.MetaVariableName,
+					v1alpha1.NodeConfigSpec{Nutanix: invalidNutanixNodeSpec},
+				),
+			},
+		}, {
+			HolderReference: runtimehooksv1.HolderReference{Kind: "MachineDeployment", Name: "md-0"},
+			Variables: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					workerconfig.MetaVariableName,
+					v1alpha1.NodeConfigSpec{Nutanix: invalidNutanixNodeSpec},
+				),
+			},
+		}, {
+			HolderReference: runtimehooksv1.HolderReference{Kind: "MachineDeployment", Name: "md-1"},
+		}},
+		expectedStatus: runtimehooksv1.ResponseStatusFailure,
+		expectedMessages: []string{
+			"clusterConfig.controlPlane.nutanix.machineDetails.subnets[0].name: Required value",
+			"clusterConfig.controlPlane.nutanix.machineDetails.systemDiskSize: Invalid value",
+			"machineDeployments[md-0].workerConfig.nutanix.machineDetails.subnets[0].name: Required value",
+			"machineDeployments[md-0].workerConfig.nutanix.machineDetails.systemDiskSize: Invalid value",
+		},
+	}}
+
+	for idx := range testCases {
+		tt := testCases[idx]
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := gomega.NewWithT(t)
+
+			resp := &runtimehooksv1.ValidateTopologyResponse{}
+			New().ValidateTopology(
+				context.Background(),
+				&runtimehooksv1.ValidateTopologyRequest{Variables: tt.vars, Items: tt.items},
+				resp,
+			)
+
+			g.Expect(resp.Status).To(gomega.Equal(tt.expectedStatus), resp.Message)
+			for _, msg := range tt.expectedMessages {
+				g.Expect(resp.Message).To(gomega.ContainSubstring(msg))
+			}
+			if len(tt.expectedMessages) > 0 {
+				g.Expect(resp.Message).NotTo(gomega.ContainSubstring("machineDeployments[md-1]"))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation
+
+import (
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	capxv1 "github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/external/github.com/nutanix-cloud-native/cluster-api-provider-nutanix/api/v1beta1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/api/v1alpha1"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/common/pkg/capi/clustertopology/variables"
+	"github.com/d2iq-labs/cluster-api-runtime-extensions-nutanix/pkg/handlers/generic/workerconfig"
+)
+
+// minNutanixSystemDiskSize is the minimum system disk size supported by CAPX.
+var minNutanixSystemDiskSize = resource.MustParse("20Gi")
+
+// validateWorkerConfig returns field errors for the workerConfig variable. An error is only returned if the
+// variable cannot be decoded.
+func validateWorkerConfig(
+	vars map[string]apiextensionsv1.JSON,
+	fldPath *field.Path,
+) (field.ErrorList, error) {
+	workerConfig, found, err := variables.Get[v1alpha1.NodeConfigSpec](
+		vars,
+		workerconfig.MetaVariableName,
+	)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return validateNodeConfig(&workerConfig, fldPath), nil
+}
+
+func validateNodeConfig(nodeConfig *v1alpha1.NodeConfigSpec, fldPath *field.Path) field.ErrorList {
+	if nodeConfig.Nutanix == nil {
+		return nil
+	}
+
+	machineDetails := &nodeConfig.Nutanix.MachineDetails
+	fldPath = fldPath.Child("nutanix", "machineDetails")
+
+	var allErrs field.ErrorList
+	allErrs = append(
+		allErrs,
+		validateNutanixResourceIdentifier(&machineDetails.Image, fldPath.Child("image"))...,
+	)
+	allErrs = append(
+		allErrs,
+		validateNutanixResourceIdentifier(&machineDetails.Cluster, fldPath.Child("cluster"))...,
+	)
+	for i := range machineDetails.Subnets {
+		allErrs = append(
+			allErrs,
+			validateNutanixResourceIdentifier(&machineDetails.Subnets[i], fldPath.Child("subnets").Index(i))...,
+		)
+	}
+
+	if machineDetails.SystemDiskSize.Cmp(minNutanixSystemDiskSize) < 0 {
+		allErrs = append(allErrs, field.Invalid(
+			fldPath.Child("systemDiskSize"),
+			machineDetails.SystemDiskSize.String(),
+			fmt.Sprintf("must be at least %s", minNutanixSystemDiskSize.String()),
+		))
+	}
+
+	return allErrs
+}
+
+func validateNutanixResourceIdentifier(
+	identifier *v1alpha1.NutanixResourceIdentifier,
+	fldPath *field.Path,
+) field.ErrorList {
+	switch identifier.Type {
+	case capxv1.NutanixIdentifierUUID:
+		if identifier.UUID == nil || *identifier.UUID == "" {
+			return field.ErrorList{field.Required(fldPath.Child("uuid"), "required when type is uuid")}
+		}
+	case capxv1.NutanixIdentifierName:
+		if identifier.Name == nil || *identifier.Name == "" {
+			return field.ErrorList{field.Required(fldPath.Child("name"), "required when type is name")}
+		}
+	}
+	return nil
+}
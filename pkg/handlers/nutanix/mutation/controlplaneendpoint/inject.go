@@ -9,6 +9,7 @@ import (
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	netutils "k8s.io/utils/net"
 	capiv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	controlplanev1 "sigs.k8s.io/cluster-api/controlplane/kubeadm/api/v1beta1"
 	runtimehooksv1 "sigs.k8s.io/cluster-api/exp/runtime/hooks/api/v1alpha1"
@@ -97,6 +98,11 @@ func (h *nutanixControlPlaneEndpoint) Mutate(
 				fmt.Sprintf("sed -i 's/control_plane_endpoint_port/%d/g' /etc/kubernetes/manifests/kube-vip.yaml",
 					controlPlaneEndpointVar.Port),
 			}
+			if netutils.IsIPv6String(controlPlaneEndpointVar.Host) {
+				// The kube-vip manifest defaults to a /32 VIP CIDR, which is only valid for IPv4 addresses.
+				commands = append(commands,
+					`sed -i '/name: vip_cidr/{n;s/"32"/"128"/}' /etc/kubernetes/manifests/kube-vip.yaml`)
+			}
 			log.WithValues(
 				"patchedObjectKind", obj.GetObjectKind().GroupVersionKind().String(),
 				"patchedObjectName", client.ObjectKeyFromObject(obj),
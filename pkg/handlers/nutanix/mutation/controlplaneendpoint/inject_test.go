@@ -59,6 +59,31 @@ var _ = Describe("Generate Nutanix ControlPlane endpoint patches", func() {
 				},
 			},
 		},
+		{
+			Name: "ControlPlaneEndpoint set to IPv6 host sets kube-vip VIP CIDR",
+			Vars: []runtimehooksv1.Variable{
+				capitest.VariableWithValue(
+					clusterconfig.MetaVariableName,
+					clusterv1.APIEndpoint{
+						Host: "fd00:20:100::10",
+						Port: 6443,
+					},
+					nutanixclusterconfig.NutanixVariableName,
+					VariableName,
+				),
+			},
+			RequestItem: request.NewKubeadmControlPlaneTemplateRequestItem(""),
+			ExpectedPatchMatchers: []capitest.JSONPatchMatcher{
+				{
+					Operation: "add",
+					Path:      "/spec/template/spec/kubeadmConfigSpec/preKubeadmCommands",
+					ValueMatcher: gomega.ContainElements(
+						"sed -i 's/control_plane_endpoint_ip/fd00:20:100::10/g' /etc/kubernetes/manifests/kube-vip.yaml",
+						`sed -i '/name: vip_cidr/{n;s/"32"/"128"/}' /etc/kubernetes/manifests/kube-vip.yaml`,
+					),
+				},
+			},
+		},
 	}
 
 	// create test node for each case
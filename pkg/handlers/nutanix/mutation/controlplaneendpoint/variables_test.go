@@ -44,6 +44,43 @@ func TestVariableValidation(t *testing.T) {
 				},
 			},
 		},
+		capitest.VariableTestDef{
+			Name: "valid IPv6 host",
+			Vals: v1alpha1.ClusterConfigSpec{
+				Nutanix: &v1alpha1.NutanixSpec{
+					ControlPlaneEndpoint: clusterv1.APIEndpoint{
+						Host: "fd00:20:100::10",
+						Port: 6443,
+					},
+					// PrismCentralEndpoint is a required field and must always be set
+					PrismCentralEndpoint: v1alpha1.NutanixPrismCentralEndpointSpec{
+						URL: testPrismCentralURL,
+						Credentials: corev1.LocalObjectReference{
+							Name: "credentials",
+						},
+					},
+				},
+			},
+		},
+		capitest.VariableTestDef{
+			Name: "invalid host",
+			Vals: v1alpha1.ClusterConfigSpec{
+				Nutanix: &v1alpha1.NutanixSpec{
+					ControlPlaneEndpoint: clusterv1.APIEndpoint{
+						Host: "invalid:host",
+						Port: 6443,
+					},
+					// PrismCentralEndpoint is a required field and must always be set
+					PrismCentralEndpoint: v1alpha1.NutanixPrismCentralEndpointSpec{
+						URL: testPrismCentralURL,
+						Credentials: corev1.LocalObjectReference{
+							Name: "credentials",
+						},
+					},
+				},
+			},
+			ExpectError: true,
+		},
 		capitest.VariableTestDef{
 			Name: "empty host",
 			Vals: v1alpha1.ClusterConfigSpec{